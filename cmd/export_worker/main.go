@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"event-registration/internal/common"
+	"event-registration/internal/config"
+	"event-registration/internal/core/domain"
+	"event-registration/internal/core/service"
+	"event-registration/internal/infrastructure/database"
+	"event-registration/internal/infrastructure/mailer"
+	"event-registration/internal/infrastructure/queue"
+	"event-registration/internal/repository/gorm"
+	"event-registration/internal/repository/redis"
+	"log"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	// go build -o export_worker.exe cmd/export_worker/main.go
+)
+
+// The export worker runs the jobs the exporter API publishes to RabbitMQ.
+// On SIGTERM it stops taking jobs and gives the running ones up to
+// EXPORT_WORKER_STOP_TIMEOUT to finish their current file part.
+func main() {
+	cfg, err := common.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the worker always consumes, whatever the exporter API is set to
+	cfg.ExportQueueEnabled = true
+
+	app := fx.New(
+		fx.StopTimeout(cfg.ExportWorkerStopTimeout),
+
+		fx.Supply(cfg),
+
+		fx.Provide(
+			config.NewLogLevel,
+			config.NewZapLogger,
+			config.NewZapGormLogger,
+			fx.Annotate(database.NewGormDwhDB, fx.ResultTags(`name:"DwhDB"`)),
+			fx.Annotate(database.NewGormPlnMobileDB, fx.ResultTags(`name:"PlnMobileDB"`)),
+			redis.NewCacheRepo,
+			config.NewRedisCache,
+			redis.NewExportJobRepo,
+			redis.NewExportCheckpointRepo,
+			redis.NewExportAdmissionRepo,
+			queue.NewExportQueue,
+			fx.Annotate(gorm.NewExporterRepo, fx.ParamTags(`name:"DwhDB"`, `name:"PlnMobileDB"`)),
+			fx.Annotate(gorm.NewExportAuditRepo, fx.ParamTags(`name:"DwhDB"`)),
+			fx.Annotate(gorm.NewExportNotificationRepo, fx.ParamTags(`name:"DwhDB"`)),
+			fx.Annotate(gorm.NewExportScheduleRepo, fx.ParamTags(`name:"DwhDB"`)),
+			mailer.NewSMTPMailer,
+			service.NewExportLayouts,
+			service.NewExportMasking,
+			service.NewExportNotifier,
+			service.NewExporterService,
+			service.NewExportWorkerService,
+		),
+
+		fx.Invoke(service.RequireDownloadSecret),
+
+		fx.Invoke(func(lc fx.Lifecycle, shutdowner fx.Shutdowner, worker *service.ExportWorkerService, notifier *service.ExportNotifier, exportQueue domain.ExportQueue, logger *zap.Logger) {
+			lc.Append(fx.Hook{
+				OnStart: func(ctx context.Context) error {
+					go func() {
+						if err := worker.Run(); err != nil {
+							// let the supervisor restart the worker with a fresh connection
+							shutdowner.Shutdown(fx.ExitCode(1))
+						}
+					}()
+					return nil
+				},
+				OnStop: func(ctx context.Context) error {
+					err := worker.Stop(ctx)
+					if err != nil {
+						logger.Error(
+							"error_stop_export_worker",
+							zap.Error(err),
+						)
+					}
+
+					if errWait := notifier.Wait(ctx); errWait != nil {
+						logger.Error(
+							"error_wait_export_notifications",
+							zap.Error(errWait),
+						)
+					}
+
+					if errClose := exportQueue.Close(); errClose != nil {
+						logger.Error(
+							"error_close_export_queue",
+							zap.Error(errClose),
+						)
+					}
+
+					return err
+				},
+			})
+		}),
+	)
+
+	app.Run()
+}
@@ -4,10 +4,14 @@ import (
 	"context"
 	"event-registration/internal/common"
 	"event-registration/internal/config"
+	"event-registration/internal/core/domain"
 	"event-registration/internal/core/service"
 	"event-registration/internal/handler"
 	"event-registration/internal/infrastructure/database"
+	"event-registration/internal/infrastructure/mailer"
+	"event-registration/internal/infrastructure/queue"
 	"event-registration/internal/infrastructure/validator"
+	"event-registration/internal/middleware"
 	"event-registration/internal/repository/gorm"
 	"event-registration/internal/repository/redis"
 	"log"
@@ -44,16 +48,41 @@ func main() {
 			config.NewZapLogger,
 			config.NewZapGormLogger,
 			validator.NewValidator,
+			common.NewHandler,
 			fx.Annotate(database.NewGormDwhDB, fx.ResultTags(`name:"DwhDB"`)),
 			fx.Annotate(database.NewGormPlnMobileDB, fx.ResultTags(`name:"PlnMobileDB"`)),
 			redis.NewCacheRepo,
+			config.NewRedisCache,
+			redis.NewExportJobRepo,
+			redis.NewExportCheckpointRepo,
+			redis.NewExportAdmissionRepo,
+			redis.NewUnitTreeRepo,
+			queue.NewExportQueue,
+			service.NewSessionService,
+			middleware.NewMiddleware,
 			fx.Annotate(gorm.NewExporterRepo, fx.ParamTags(`name:"DwhDB"`, `name:"PlnMobileDB"`)),
+			fx.Annotate(gorm.NewExportScheduleRepo, fx.ParamTags(`name:"DwhDB"`)),
+			fx.Annotate(gorm.NewExportAuditRepo, fx.ParamTags(`name:"DwhDB"`)),
+			fx.Annotate(gorm.NewExportNotificationRepo, fx.ParamTags(`name:"DwhDB"`)),
+			mailer.NewSMTPMailer,
+			service.NewExportLayouts,
+			service.NewExportMasking,
+			service.NewExportNotifier,
 			service.NewExporterService,
+			service.NewExportScheduleService,
+			service.NewExportRetentionService,
+			service.NewUnitTreeService,
 			handler.NewExporterHandler,
+			handler.NewExportScheduleHandler,
+			handler.NewExportRetentionHandler,
+			handler.NewExportNotificationHandler,
+			handler.NewUnitTreeHandler,
 			fiber.New,
 		),
 
-		fx.Invoke(func(app *fiber.App, exportHandler *handler.ExporterHandler) {
+		fx.Invoke(service.RequireDownloadSecret),
+
+		fx.Invoke(func(app *fiber.App, exportHandler *handler.ExporterHandler, scheduleHandler *handler.ExportScheduleHandler, retentionHandler *handler.ExportRetentionHandler, notificationHandler *handler.ExportNotificationHandler, unitTreeHandler *handler.UnitTreeHandler, m *middleware.Middleware) {
 
 			// Register Swagger route
 			app.Get("/swagger/*", swagger.New(swagger.Config{
@@ -64,14 +93,71 @@ func main() {
 			startProfilingServer()
 
 			// Routes
-			app.Post("/transaksi", exportHandler.ExportRekapTransaksi)
-			app.Post("/transaksi-all", exportHandler.ExportAllRekapTransaksi)
-			app.Post("/pelanggan", exportHandler.ExportRekapPelanggan)
+			app.Get("/exports/download", exportHandler.Download)
 			app.Get("/hello", exportHandler.HelloWorld)
 
+			auth := m.AuthMiddleware()
+			app.Post("/transaksi", auth, exportHandler.ExportRekapTransaksi)
+			app.Post("/transaksi-all", auth, exportHandler.ExportAllRekapTransaksi)
+			app.Post("/pelanggan", auth, exportHandler.ExportRekapPelanggan)
+			app.Post("/reconciliation", auth, exportHandler.ReconcileTransaksi)
+			app.Get("/exports/files", auth, exportHandler.ListExportArtifacts)
+			app.Post("/exports/retention", auth, retentionHandler.RunRetention)
+			app.Get("/exports/audit", auth, exportHandler.SearchExportAudits)
+			app.Post("/exports/estimate", auth, exportHandler.EstimateExport)
+			app.Get("/exports/notifications", auth, notificationHandler.FindSetting)
+			app.Put("/exports/notifications", auth, notificationHandler.SaveSetting)
+			app.Post("/exports/:id/archive", auth, exportHandler.ArchiveExport)
+			app.Post("/exports/:id/cancel", auth, exportHandler.CancelExportJob)
+			app.Post("/exports/:id/pin", auth, exportHandler.PinExportJob)
+			app.Delete("/exports/:id/pin", auth, exportHandler.UnpinExportJob)
+			app.Get("/exports/:id", auth, exportHandler.FindExportJob)
+			app.Post("/schedules", auth, scheduleHandler.CreateSchedule)
+			app.Get("/schedules", auth, scheduleHandler.FindSchedules)
+			app.Get("/schedules/:id/runs", auth, scheduleHandler.FindScheduleRuns)
+			app.Get("/units/tree", auth, unitTreeHandler.FindUnitTree)
+
 			// listRoutes(app)
 		}),
 
+		fx.Invoke(func(lc fx.Lifecycle, exporter *service.ExporterService, scheduler *service.ExportScheduleService, retention *service.ExportRetentionService, unitTree *service.UnitTreeService, notifier *service.ExportNotifier, exportQueue domain.ExportQueue, logger *zap.Logger) {
+			lc.Append(fx.Hook{
+				OnStart: func(ctx context.Context) error {
+					if err := exporter.ResumeExportJobs(ctx); err != nil {
+						return err
+					}
+
+					if err := retention.Start(); err != nil {
+						return err
+					}
+
+					if err := unitTree.Start(); err != nil {
+						return err
+					}
+
+					return scheduler.Start()
+				},
+				OnStop: func(ctx context.Context) error {
+					scheduler.Stop()
+					retention.Stop()
+					unitTree.Stop()
+
+					if err := notifier.Wait(ctx); err != nil {
+						logger.Error(
+							"error_wait_export_notifications",
+							zap.Error(err),
+						)
+					}
+
+					// nil unless EXPORT_QUEUE_ENABLED is set
+					if exportQueue != nil {
+						return exportQueue.Close()
+					}
+					return nil
+				},
+			})
+		}),
+
 		fx.Invoke(func(lc fx.Lifecycle, app *fiber.App, config *common.Config, logger *zap.Logger) {
 			lc.Append(fx.Hook{
 				OnStart: func(ctx context.Context) error {
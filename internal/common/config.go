@@ -57,6 +57,50 @@ type Config struct {
 	VCCDBSchema               string        `mapstructure:"POSTGRES_VCC_SCHEMA"`
 	MeilisearchHost           string        `mapstructure:"MEILISEARCH_HOST"`
 	MeilisearchAPIKey         string        `mapstructure:"MEILISEARCH_API_KEY"`
+	ExportJobTTL              time.Duration `mapstructure:"EXPORT_JOB_TTL"`
+	ExportJobLease            time.Duration `mapstructure:"EXPORT_JOB_LEASE"`
+	ExportDownloadSecret      string        `mapstructure:"EXPORT_DOWNLOAD_SECRET"`
+	ExportDownloadExpiration  time.Duration `mapstructure:"EXPORT_DOWNLOAD_EXPIRATION"`
+	ExportMemoryBudgetMB      int           `mapstructure:"EXPORT_MEMORY_BUDGET_MB"`
+	ExportWorkers             int           `mapstructure:"EXPORT_WORKERS"`
+	ExportRetryAttempts       int           `mapstructure:"EXPORT_RETRY_ATTEMPTS"`
+	ExportRetryBackoff        time.Duration `mapstructure:"EXPORT_RETRY_BACKOFF"`
+	ExportSchedulerEnabled    bool          `mapstructure:"EXPORT_SCHEDULER_ENABLED"`
+	ExportScheduleTimezone    string        `mapstructure:"EXPORT_SCHEDULE_TIMEZONE"`
+	ExportRetentionMaxAge     time.Duration `mapstructure:"EXPORT_RETENTION_MAX_AGE"`
+	ExportRetentionQuotaMB    int64         `mapstructure:"EXPORT_RETENTION_QUOTA_MB"`
+	ExportRetentionInterval   time.Duration `mapstructure:"EXPORT_RETENTION_INTERVAL"`
+	ExportRetentionDryRun     bool          `mapstructure:"EXPORT_RETENTION_DRY_RUN"`
+	ExportRetentionRoles      []string      `mapstructure:"EXPORT_RETENTION_ROLES"`
+	ExportLayoutsFile         string        `mapstructure:"EXPORT_LAYOUTS_FILE"`
+	ExportMaskingFile         string        `mapstructure:"EXPORT_MASKING_FILE"`
+	ExportMaskingSecret       string        `mapstructure:"EXPORT_MASKING_SECRET"`
+	ExportTimezone            string        `mapstructure:"EXPORT_TIMEZONE"`
+	ExportAuditRoles          []string      `mapstructure:"EXPORT_AUDIT_ROLES"`
+	ExportQueueEnabled        bool          `mapstructure:"EXPORT_QUEUE_ENABLED"`
+	ExportQueueName           string        `mapstructure:"EXPORT_QUEUE_NAME"`
+	ExportWorkerPrefetch      int           `mapstructure:"EXPORT_WORKER_PREFETCH"`
+	ExportWorkerStopTimeout   time.Duration `mapstructure:"EXPORT_WORKER_STOP_TIMEOUT"`
+	ExportPublicURL           string        `mapstructure:"EXPORT_PUBLIC_URL"`
+	ExportWebhookSecret       string        `mapstructure:"EXPORT_WEBHOOK_SECRET"`
+	ExportWebhookTimeout      time.Duration `mapstructure:"EXPORT_WEBHOOK_TIMEOUT"`
+	ExportWebhookAllowedHosts []string      `mapstructure:"EXPORT_WEBHOOK_ALLOWED_HOSTS"`
+	SmtpHost                  string        `mapstructure:"SMTP_HOST"`
+	SmtpPort                  int           `mapstructure:"SMTP_PORT"`
+	SmtpUsername              string        `mapstructure:"SMTP_USERNAME"`
+	SmtpPassword              string        `mapstructure:"SMTP_PASSWORD"`
+	SmtpFrom                  string        `mapstructure:"SMTP_FROM"`
+	ExportEstimateRowsPerSec  int           `mapstructure:"EXPORT_ESTIMATE_ROWS_PER_SEC"`
+	UnitTreeTTL               time.Duration `mapstructure:"UNIT_TREE_TTL"`
+	UnitTreeRefreshInterval   time.Duration `mapstructure:"UNIT_TREE_REFRESH_INTERVAL"`
+	ReconcileThresholdPercent float64       `mapstructure:"RECONCILE_THRESHOLD_PERCENT"`
+	ExportMaxConcurrent       int           `mapstructure:"EXPORT_MAX_CONCURRENT"`
+	ExportMaxQueued           int           `mapstructure:"EXPORT_MAX_QUEUED"`
+	ExportDailyQuota          int           `mapstructure:"EXPORT_DAILY_QUOTA"`
+	DBReadTimeout             time.Duration `mapstructure:"DB_READ_TIMEOUT"`
+	DBWriteTimeout            time.Duration `mapstructure:"DB_WRITE_TIMEOUT"`
+	ExportQueryTimeout        time.Duration `mapstructure:"EXPORT_QUERY_TIMEOUT"`
+	ExportStatementTimeout    time.Duration `mapstructure:"EXPORT_STATEMENT_TIMEOUT"`
 }
 
 func Load() (*Config, error) {
@@ -70,6 +114,41 @@ func Load() (*Config, error) {
 	viper.SetDefault("CACHE_TIMEOUT", "5m")
 	viper.SetDefault("REFRESH_JWT_EXPIRATION", 7)
 	viper.SetDefault("ACCESS_JWT_EXPIRATION", 1)
+	viper.SetDefault("EXPORT_JOB_TTL", "168h")
+	viper.SetDefault("EXPORT_JOB_LEASE", "1m")
+	viper.SetDefault("EXPORT_DOWNLOAD_EXPIRATION", "15m")
+	viper.SetDefault("EXPORT_MEMORY_BUDGET_MB", 64)
+	viper.SetDefault("EXPORT_WORKERS", 10)
+	viper.SetDefault("EXPORT_RETRY_ATTEMPTS", 3)
+	viper.SetDefault("EXPORT_RETRY_BACKOFF", "2s")
+	viper.SetDefault("EXPORT_SCHEDULER_ENABLED", true)
+	viper.SetDefault("EXPORT_SCHEDULE_TIMEZONE", "Asia/Jakarta")
+	viper.SetDefault("EXPORT_RETENTION_MAX_AGE", "720h")
+	viper.SetDefault("EXPORT_RETENTION_QUOTA_MB", 10240)
+	viper.SetDefault("EXPORT_RETENTION_INTERVAL", "1h")
+	viper.SetDefault("EXPORT_RETENTION_DRY_RUN", false)
+	viper.SetDefault("EXPORT_RETENTION_ROLES", "admin")
+	viper.SetDefault("EXPORT_TIMEZONE", "Asia/Jakarta")
+	viper.SetDefault("EXPORT_AUDIT_ROLES", "admin,auditor")
+	viper.SetDefault("EXPORT_QUEUE_ENABLED", false)
+	viper.SetDefault("EXPORT_QUEUE_NAME", "exports")
+	viper.SetDefault("EXPORT_WORKER_PREFETCH", 1)
+	viper.SetDefault("EXPORT_WORKER_STOP_TIMEOUT", "30m")
+	viper.SetDefault("EXPORT_PUBLIC_URL", "http://127.0.0.1:5050")
+	viper.SetDefault("EXPORT_WEBHOOK_TIMEOUT", "10s")
+	viper.SetDefault("SMTP_PORT", 587)
+	viper.SetDefault("SMTP_FROM", "exporter@localhost")
+	viper.SetDefault("EXPORT_ESTIMATE_ROWS_PER_SEC", 5000)
+	viper.SetDefault("UNIT_TREE_TTL", "24h")
+	viper.SetDefault("UNIT_TREE_REFRESH_INTERVAL", "5m")
+	viper.SetDefault("RECONCILE_THRESHOLD_PERCENT", 1)
+	viper.SetDefault("EXPORT_MAX_CONCURRENT", 2)
+	viper.SetDefault("EXPORT_MAX_QUEUED", 20)
+	viper.SetDefault("EXPORT_DAILY_QUOTA", 20)
+	viper.SetDefault("DB_READ_TIMEOUT", "5s")
+	viper.SetDefault("DB_WRITE_TIMEOUT", "10s")
+	viper.SetDefault("EXPORT_QUERY_TIMEOUT", "30m")
+	viper.SetDefault("EXPORT_STATEMENT_TIMEOUT", "15m")
 
 	viper.AutomaticEnv()
 
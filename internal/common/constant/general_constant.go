@@ -7,4 +7,5 @@ const (
 	ACCESS_TOKEN         = "access_token"
 	REFRESH_TOKEN        = "refresh_token"
 	SQL_ERROR            = "sql_error"
+	FILE_NOT_FOUND       = "file_not_found"
 )
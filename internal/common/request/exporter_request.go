@@ -8,6 +8,68 @@ type RekapRequest struct {
 	IsDBPlnMobile bool   `json:"is_db_plnmobile" form:"is_db_plnmobile" validate:"boolean" example:"false"`
 	DateStart     string `json:"date_start" form:"date_start" validate:"required,datetime=2006/01/02,max=100" example:"2026/02/01"`
 	DateEnd       string `json:"date_end" form:"date_end" validate:"required,datetime=2006/01/02,max=100" example:"2026/12/31"`
-	Limit         int    `json:"limit" form:"limit" validate:"" example:"1000"`
-	Offset        int    `json:"offset" form:"offset" validate:"" example:"0"`
+	Format        string `json:"format" form:"format" validate:"omitempty,oneof=xlsx csv ndjson" example:"xlsx"`
+	Layout        string `json:"layout" form:"layout" validate:"omitempty,max=50" example:"default"`
+	Language      string `json:"language" form:"language" validate:"omitempty,oneof=id en" example:"id"`
+	// Transaksi filters
+	StatusCode     []string `json:"status_code" form:"status_code" validate:"omitempty,max=20,dive,required,alphanum,max=10" example:"00,05"`
+	PaymentGateway string   `json:"payment_gateway" form:"payment_gateway" validate:"omitempty,max=50" example:"BRI"`
+	Type           string   `json:"type" form:"type" validate:"omitempty,max=50" example:"prepaid"`
+	AmountMin      float64  `json:"amount_min" form:"amount_min" validate:"omitempty,gte=0" example:"20000"`
+	AmountMax      float64  `json:"amount_max" form:"amount_max" validate:"omitempty,gte=0,gtefield=AmountMin" example:"500000"`
+	MeterNumber    string   `json:"meter_number" form:"meter_number" validate:"omitempty,numeric,max=20" example:"532100112233"`
+	// ThresholdPercent is the difference a reconciliation tolerates, it
+	// falls back to RECONCILE_THRESHOLD_PERCENT when left out
+	ThresholdPercent *float64 `json:"threshold_percent,omitempty" form:"threshold_percent" validate:"omitempty,gte=0,lte=100" example:"1"`
+	// Pelanggan filters
+	EnergyType string `json:"energy_type" form:"energy_type" validate:"omitempty,max=50" example:"prabayar"`
+	MeterType  string `json:"meter_type" form:"meter_type" validate:"omitempty,max=50" example:"1 PHASE"`
+	// MaskingPolicy is set by the exporter from the requester's roles
+	MaskingPolicy string `json:"masking_policy,omitempty" form:"-" validate:"isdefault" swaggerignore:"true"`
+}
+
+type DownloadRequest struct {
+	Path      string `json:"path" query:"path" validate:"required,max=500" example:"files/6f1d2c3e-5b7a-4c1e-9f0a-2d8e4b6c7a90/NASIONAL_20260201_20261231.xlsx"`
+	Expires   int64  `json:"expires" query:"expires" validate:"required" example:"1767225600"`
+	Signature string `json:"signature" query:"signature" validate:"required,hexadecimal,len=64" example:""`
+}
+
+type ArchiveRequest struct {
+	Format string `json:"format" form:"format" validate:"required,oneof=zip tar.gz" example:"zip"`
+}
+
+type ExportScheduleRequest struct {
+	Name          string `json:"name" validate:"required,max=100" example:"Rekap bulanan UID Jakarta"`
+	CronExpr      string `json:"cron_expr" validate:"required,max=100" example:"0 2 1 * *"`
+	Window        string `json:"window" validate:"required,oneof=previous_day last_7_days previous_month" example:"previous_month"`
+	ExportType    string `json:"export_type" validate:"required,oneof=transaksi transaksi_all pelanggan reconciliation" example:"transaksi_all"`
+	Induk         string `json:"id_induk" validate:"max=20" example:""`
+	Area          string `json:"id_area" validate:"max=20" example:""`
+	UnitCode      string `json:"unit_code" validate:"max=20" example:""`
+	IsDBPlnMobile bool   `json:"is_db_plnmobile" validate:"boolean" example:"false"`
+	Format        string `json:"format" validate:"omitempty,oneof=xlsx csv ndjson" example:"xlsx"`
+}
+
+type RetentionRequest struct {
+	// Delete removes the files, without it the run only reports them
+	Delete bool `json:"delete" query:"delete" validate:"boolean" example:"false"`
+}
+
+type ExportAuditRequest struct {
+	UserID    string `json:"user_id" query:"user_id" validate:"omitempty,max=100" example:""`
+	Unit      string `json:"unit" query:"unit" validate:"omitempty,max=20" example:"54110"`
+	DateStart string `json:"date_start" query:"date_start" validate:"omitempty,datetime=2006/01/02" example:"2026/02/01"`
+	DateEnd   string `json:"date_end" query:"date_end" validate:"omitempty,datetime=2006/01/02" example:"2026/02/28"`
+	Limit     int    `json:"limit" query:"limit" validate:"omitempty,min=1,max=500" example:"50"`
+	Offset    int    `json:"offset" query:"offset" validate:"omitempty,min=0" example:"0"`
+}
+
+type ExportNotificationRequest struct {
+	WebhookURL string `json:"webhook_url" validate:"omitempty,http_url,max=500" example:"https://hooks.pln.co.id/exports"`
+	Email      string `json:"email" validate:"omitempty,email,max=254" example:"budi@pln.co.id"`
+}
+
+type UnitTreeRequest struct {
+	Code   string `json:"code" query:"code" validate:"omitempty,max=20" example:"54100"`
+	Search string `json:"q" query:"q" validate:"omitempty,max=100" example:"menteng"`
 }
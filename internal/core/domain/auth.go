@@ -1,11 +1,14 @@
 package domain
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type AuthRepository interface {
-	IsRegistered(email string) (isRegistered bool, err error)
-	Register(user User) (err error)
-	FindByEmail(email string) (user *User, err error)
+	IsRegistered(ctx context.Context, email string) (isRegistered bool, err error)
+	Register(ctx context.Context, user User) (err error)
+	FindByEmail(ctx context.Context, email string) (user *User, err error)
 }
 
 type User struct {
@@ -20,6 +23,8 @@ type User struct {
 	EmailVerifiedAt *time.Time `json:"email_verified_at" gorm:"column:email_verified_at"`
 	CreatedAt       time.Time  `json:"created_at" gorm:"column:created_at"`
 	UpdatedAt       time.Time  `json:"updated_at" gorm:"column:updated_at"`
+	// Roles come from the access token's roles claim, when present
+	Roles []string `json:"roles,omitempty" gorm:"-"`
 }
 
 func (a *User) TableName() string {
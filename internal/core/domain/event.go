@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"context"
 	"time"
 )
 
@@ -14,14 +15,14 @@ type Event struct {
 }
 
 type EventRepository interface {
-	FindByID(id string) (*Event, error)
-	Save(event *Event) error
-	Update(event *Event) error
+	FindByID(ctx context.Context, id string) (*Event, error)
+	Save(ctx context.Context, event *Event) error
+	Update(ctx context.Context, event *Event) error
 }
 
 type EventCache interface {
-	Get(key string) (*Event, error)
-	Set(key string, event *Event, expiration time.Duration) error
+	Get(ctx context.Context, key string) (*Event, error)
+	Set(ctx context.Context, key string, event *Event, expiration time.Duration) error
 }
 
 type EventQueue interface {
@@ -0,0 +1,23 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ExportAdmissionRepository holds the admission state every exporter
+// process shares, so limits hold across replicas and workers.
+type ExportAdmissionRepository interface {
+	// Lock takes key for owner until Unlock or until ttl passes, it returns
+	// false while another owner holds it.
+	Lock(ctx context.Context, key, owner string, ttl time.Duration) (bool, error)
+	Unlock(ctx context.Context, key, owner string) error
+	// AcquireSlot takes one of limit running slots for holder, or puts it in
+	// line and returns false while every slot is taken. Holders in line are
+	// let in by priority, lower first, then by queuedAt. A slot or a place
+	// in line not renewed within lease is freed for others.
+	AcquireSlot(ctx context.Context, holder string, priority int, queuedAt time.Time, limit int, lease time.Duration) (bool, error)
+	RenewSlot(ctx context.Context, holder string, lease time.Duration) error
+	// ReleaseSlot frees the slot of holder, or its place in line.
+	ReleaseSlot(ctx context.Context, holder string) error
+}
@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"context"
+	"event-registration/internal/common/request"
+	"time"
+)
+
+// Databases an export reads from, see RekapRequest.IsDBPlnMobile.
+const (
+	EXPORT_SOURCE_DWH       = "dwh"
+	EXPORT_SOURCE_PLNMOBILE = "plnmobile"
+)
+
+// What an audit record was written for.
+const (
+	EXPORT_AUDIT_EXPORT   = "export"
+	EXPORT_AUDIT_ARCHIVE  = "archive"
+	EXPORT_AUDIT_ESTIMATE = "estimate"
+)
+
+// ExportAudit records one export run, archive or estimate: who asked for
+// which data, from which database, and what came out of it.
+type ExportAudit struct {
+	ID            string                `json:"id" gorm:"column:id;primaryKey;type:varchar(36)"`
+	Action        string                `json:"action" gorm:"column:action;default:export;index"`
+	JobID         string                `json:"job_id" gorm:"column:job_id;index"`
+	ExportType    string                `json:"export_type" gorm:"column:export_type"`
+	RequestedBy   string                `json:"requested_by" gorm:"column:requested_by;index"`
+	Request       *request.RekapRequest `json:"request" gorm:"column:request;type:jsonb;serializer:json"`
+	SourceDB      string                `json:"source_db" gorm:"column:source_db"`
+	Pusat         string                `json:"id_pusat" gorm:"column:id_pusat"`
+	Induk         string                `json:"id_induk" gorm:"column:id_induk"`
+	Area          string                `json:"id_area" gorm:"column:id_area"`
+	UnitCode      string                `json:"unit_code" gorm:"column:unit_code"`
+	MaskingPolicy string                `json:"masking_policy" gorm:"column:masking_policy"`
+	Rows          int64                 `json:"rows" gorm:"column:rows"`
+	Files         []string              `json:"files" gorm:"column:files;type:jsonb;serializer:json"`
+	Bytes         int64                 `json:"bytes" gorm:"column:bytes"`
+	DurationMs    int64                 `json:"duration_ms" gorm:"column:duration_ms"`
+	Status        string                `json:"status" gorm:"column:status"`
+	Error         string                `json:"error,omitempty" gorm:"column:error"`
+	StartedAt     time.Time             `json:"started_at" gorm:"column:started_at;index"`
+	FinishedAt    time.Time             `json:"finished_at" gorm:"column:finished_at"`
+}
+
+func (a *ExportAudit) TableName() string {
+	return "public.export_audit"
+}
+
+// ExportAuditFilter narrows a search of the audit trail. Empty fields match
+// everything, Unit matches any level of the unit hierarchy.
+type ExportAuditFilter struct {
+	RequestedBy string
+	Unit        string
+	From        *time.Time
+	To          *time.Time
+	Limit       int
+	Offset      int
+}
+
+type ExportAuditRepository interface {
+	Create(ctx context.Context, audit *ExportAudit) error
+	// Search returns a page of matching records, newest first, and the number
+	// of records matching in total.
+	Search(ctx context.Context, filter ExportAuditFilter) ([]*ExportAudit, int64, error)
+	// FindRecentDone returns the latest successful export runs of
+	// exportType, newest first.
+	FindRecentDone(ctx context.Context, exportType string, limit int) ([]*ExportAudit, error)
+}
@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"context"
+	"event-registration/internal/common/request"
+	"reflect"
+)
+
+// ExportDataset is a kind of record the exporter can write. Rows are read in
+// keyset order, so the export engine can split them into parts and resume
+// after the last row it saved.
+type ExportDataset interface {
+	// Name is the key of the dataset in the layouts file.
+	Name() string
+	// RowType is the struct of its rows, layouts pick its fields by json
+	// name.
+	RowType() reflect.Type
+	Count(ctx context.Context, req *request.RekapRequest) (int64, error)
+	// Stream hands at most limit rows after the cursor to fn, each a pointer
+	// to a RowType value along with its own cursor.
+	Stream(ctx context.Context, req *request.RekapRequest, after *ExportCursor, limit int, fn func(row interface{}, cursor ExportCursor) error) error
+}
@@ -0,0 +1,199 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"event-registration/internal/common/request"
+	"time"
+)
+
+const (
+	EXPORT_JOB_QUEUED  = "queued"
+	EXPORT_JOB_RUNNING = "running"
+	EXPORT_JOB_DONE    = "done"
+	EXPORT_JOB_FAILED  = "failed"
+	// some units of a transaksi_all export failed, the rest was written
+	EXPORT_JOB_PARTIAL   = "partial"
+	EXPORT_JOB_CANCELLED = "cancelled"
+)
+
+const (
+	EXPORT_UNIT_SUCCEEDED = "succeeded"
+	EXPORT_UNIT_FAILED    = "failed"
+)
+
+const (
+	EXPORT_TYPE_TRANSAKSI     = "transaksi"
+	EXPORT_TYPE_TRANSAKSI_ALL = "transaksi_all"
+	EXPORT_TYPE_PELANGGAN     = "pelanggan"
+	// compares the transactions of the DWH and of PLN Mobile
+	EXPORT_TYPE_RECONCILIATION = "reconciliation"
+)
+
+const (
+	EXPORT_FORMAT_XLSX   = "xlsx"
+	EXPORT_FORMAT_CSV    = "csv"
+	EXPORT_FORMAT_NDJSON = "ndjson"
+)
+
+const (
+	ARCHIVE_FORMAT_ZIP    = "zip"
+	ARCHIVE_FORMAT_TAR_GZ = "tar.gz"
+)
+
+var (
+	ErrExportJobNotFound        = errors.New("export_job_not_found")
+	ErrExportCheckpointNotFound = errors.New("export_checkpoint_not_found")
+	// ErrExportJobClaimed is returned while another run holds the job lease.
+	ErrExportJobClaimed = errors.New("export_job_claimed")
+)
+
+type ExportJob struct {
+	ID          string                `json:"id"`
+	Type        string                `json:"type"`
+	Status      string                `json:"status"`
+	Request     *request.RekapRequest `json:"request"`
+	RequestedBy string                `json:"requested_by"`
+	TotalRows   int64                 `json:"total_rows"`
+	RowsWritten int64                 `json:"rows_written"`
+	Files       []ExportFile          `json:"files"`
+	Archives    []ExportFile          `json:"archives,omitempty"`
+	Report      *ExportReport         `json:"report,omitempty"`
+	// Reconciliation summarises a reconciliation job
+	Reconciliation *ReconciliationReport `json:"reconciliation,omitempty"`
+	// pinned jobs keep their record and files until unpinned
+	Pinned     bool       `json:"pinned"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+type ExportFile struct {
+	Path          string `json:"path"`
+	Rows          int    `json:"rows"`
+	MaskingPolicy string `json:"masking_policy,omitempty"`
+}
+
+type ExportArtifact struct {
+	JobID     string    `json:"job_id"`
+	Path      string    `json:"path"`
+	Rows      int       `json:"rows"`
+	Size      int64     `json:"size"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ExportReport summarises a run over many units, see
+// ExporterService.ExportAllRekapTransaksi.
+type ExportReport struct {
+	Path       string             `json:"path"`
+	Succeeded  int                `json:"succeeded"`
+	Failed     int                `json:"failed"`
+	TotalRows  int                `json:"total_rows"`
+	StartedAt  time.Time          `json:"started_at"`
+	FinishedAt time.Time          `json:"finished_at"`
+	DurationMs int64              `json:"duration_ms"`
+	Units      []ExportUnitResult `json:"units"`
+}
+
+// ExportEstimate sizes an export before it runs. Bytes and duration are
+// extrapolated from the latest successful runs of the same type, BasedOnRuns
+// is 0 when there were none and defaults were used.
+type ExportEstimate struct {
+	ExportType  string `json:"export_type"`
+	Format      string `json:"format"`
+	Rows        int64  `json:"rows"`
+	Files       int    `json:"files"`
+	RowsPerFile int    `json:"rows_per_file"`
+	Bytes       int64  `json:"bytes"`
+	DurationMs  int64  `json:"duration_ms"`
+	BasedOnRuns int    `json:"based_on_runs"`
+}
+
+type ExportUnitResult struct {
+	Name       string   `json:"name"`
+	IDPusat    string   `json:"id_pusat,omitempty"`
+	IDInduk    string   `json:"id_induk,omitempty"`
+	IDArea     string   `json:"id_area,omitempty"`
+	UnitCode   string   `json:"unit_code,omitempty"`
+	Status     string   `json:"status"`
+	Attempts   int      `json:"attempts"`
+	Rows       int      `json:"rows"`
+	Files      []string `json:"files"`
+	DurationMs int64    `json:"duration_ms"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// RetentionReport lists what one retention pass deleted, or would have
+// deleted in dry-run mode.
+type RetentionReport struct {
+	DryRun         bool            `json:"dry_run"`
+	ScannedFiles   int             `json:"scanned_files"`
+	ScannedBytes   int64           `json:"scanned_bytes"`
+	ProtectedFiles int             `json:"protected_files"`
+	DeletedBytes   int64           `json:"deleted_bytes"`
+	RemainingBytes int64           `json:"remaining_bytes"`
+	Deleted        []RetentionFile `json:"deleted"`
+}
+
+type RetentionFile struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	// max_age or quota
+	Reason string `json:"reason"`
+}
+
+// ExportManifest is written as manifest.json inside every export archive.
+type ExportManifest struct {
+	JobID       string                `json:"job_id"`
+	Type        string                `json:"type"`
+	Filters     *request.RekapRequest `json:"filters"`
+	GeneratedAt time.Time             `json:"generated_at"`
+	ArchivedAt  time.Time             `json:"archived_at"`
+	Files       []ExportManifestFile  `json:"files"`
+}
+
+type ExportManifestFile struct {
+	Name          string `json:"name"`
+	Rows          int    `json:"rows"`
+	Size          int64  `json:"size"`
+	SHA256        string `json:"sha256"`
+	MaskingPolicy string `json:"masking_policy,omitempty"`
+}
+
+// ExportCheckpoint records the parts of an export that are already on disk,
+// keyed by Hash, which identifies the export type and request the parts were
+// written for. JobID is the job whose folder holds the parts.
+type ExportCheckpoint struct {
+	JobID      string        `json:"job_id"`
+	Hash       string        `json:"hash"`
+	TotalRows  int64         `json:"total_rows"`
+	TotalFiles int           `json:"total_files"`
+	Parts      []ExportFile  `json:"parts"`
+	Cursor     *ExportCursor `json:"cursor"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+}
+
+type ExportJobRepository interface {
+	Save(ctx context.Context, job *ExportJob) error
+	FindByID(ctx context.Context, id string) (*ExportJob, error)
+	FindByUser(ctx context.Context, userID string) ([]*ExportJob, error)
+	// FindActive returns the jobs still queued or running.
+	FindActive(ctx context.Context) ([]*ExportJob, error)
+	FindPinned(ctx context.Context) ([]*ExportJob, error)
+	// Claim takes the lease on a job for owner, one run at a time. It fails
+	// with ErrExportJobClaimed until the current owner releases the job or
+	// stops renewing its lease.
+	Claim(ctx context.Context, id, owner string, lease time.Duration) error
+	// Renew extends owner's lease, ErrExportJobClaimed means it was lost.
+	Renew(ctx context.Context, id, owner string, lease time.Duration) error
+	Release(ctx context.Context, id, owner string) error
+}
+
+type ExportCheckpointRepository interface {
+	Find(ctx context.Context, hash string) (*ExportCheckpoint, error)
+	Save(ctx context.Context, checkpoint *ExportCheckpoint) error
+	Delete(ctx context.Context, hash string) error
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrExportNotificationSettingNotFound = errors.New("export_notification_setting_not_found")
+
+// ExportNotificationSetting is where a requester wants to hear about the end
+// of their exports. Either destination may be left empty.
+type ExportNotificationSetting struct {
+	UserID     string    `json:"user_id" gorm:"column:user_id;primaryKey;type:varchar(100)"`
+	WebhookURL string    `json:"webhook_url" gorm:"column:webhook_url"`
+	Email      string    `json:"email" gorm:"column:email"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"column:updated_at"`
+}
+
+func (a *ExportNotificationSetting) TableName() string {
+	return "public.export_notification_setting"
+}
+
+type ExportNotificationRepository interface {
+	Find(ctx context.Context, userID string) (*ExportNotificationSetting, error)
+	Save(ctx context.Context, setting *ExportNotificationSetting) error
+}
+
+// ExportNotification is the webhook payload sent when a job finishes.
+type ExportNotification struct {
+	JobID       string                   `json:"job_id"`
+	ExportType  string                   `json:"export_type"`
+	Status      string                   `json:"status"`
+	Error       string                   `json:"error,omitempty"`
+	RequestedBy string                   `json:"requested_by"`
+	Rows        int64                    `json:"rows"`
+	Files       []ExportNotificationFile `json:"files"`
+	// download links stop working after ExpiresAt
+	ExpiresAt  time.Time `json:"expires_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+type ExportNotificationFile struct {
+	Path string `json:"path"`
+	Rows int    `json:"rows"`
+	URL  string `json:"url"`
+}
+
+type MailMessage struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(message *MailMessage) error
+}
@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrExportInterrupted is returned by an export that stopped after a file
+// part because its worker is shutting down. The job is left queued so another
+// worker picks it up again from its checkpoint.
+var ErrExportInterrupted = errors.New("export_interrupted")
+
+// ExportMessage asks an export worker to run a queued job.
+type ExportMessage struct {
+	JobID string `json:"job_id"`
+}
+
+type ExportQueue interface {
+	Publish(message *ExportMessage) error
+	// Consume hands messages to handle until ctx is done, then waits for the
+	// messages being handled. A message is acknowledged when handle returns
+	// nil, requeued when it returns ErrExportInterrupted or
+	// ErrExportJobClaimed and dead-lettered on any other error.
+	Consume(ctx context.Context, handle func(message *ExportMessage) error) error
+	Close() error
+}
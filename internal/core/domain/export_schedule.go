@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Date windows a schedule can export, relative to the time it runs.
+const (
+	SCHEDULE_WINDOW_PREVIOUS_DAY   = "previous_day"
+	SCHEDULE_WINDOW_LAST_7_DAYS    = "last_7_days"
+	SCHEDULE_WINDOW_PREVIOUS_MONTH = "previous_month"
+)
+
+var ErrExportScheduleNotFound = errors.New("export_schedule_not_found")
+
+type ExportSchedule struct {
+	ID            string     `json:"id" gorm:"column:id;primaryKey;type:varchar(36)"`
+	Name          string     `json:"name" gorm:"column:name"`
+	CronExpr      string     `json:"cron_expr" gorm:"column:cron_expr"`
+	Window        string     `json:"window" gorm:"column:window"`
+	ExportType    string     `json:"export_type" gorm:"column:export_type"`
+	Induk         string     `json:"id_induk" gorm:"column:id_induk"`
+	Area          string     `json:"id_area" gorm:"column:id_area"`
+	UnitCode      string     `json:"unit_code" gorm:"column:unit_code"`
+	IsDBPlnMobile bool       `json:"is_db_plnmobile" gorm:"column:is_db_plnmobile"`
+	Format        string     `json:"format" gorm:"column:format"`
+	Enabled       bool       `json:"enabled" gorm:"column:enabled"`
+	CreatedBy     string     `json:"created_by" gorm:"column:created_by"`
+	MaskingPolicy string     `json:"masking_policy" gorm:"column:masking_policy"`
+	LastRunAt     *time.Time `json:"last_run_at" gorm:"column:last_run_at"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" gorm:"column:updated_at"`
+}
+
+func (a *ExportSchedule) TableName() string {
+	return "public.export_schedule"
+}
+
+// ExportScheduleRun records one execution of a schedule and the job it
+// produced.
+type ExportScheduleRun struct {
+	ID         string     `json:"id" gorm:"column:id;primaryKey;type:varchar(36)"`
+	ScheduleID string     `json:"schedule_id" gorm:"column:schedule_id;index"`
+	JobID      string     `json:"job_id" gorm:"column:job_id;index"`
+	DateStart  string     `json:"date_start" gorm:"column:date_start"`
+	DateEnd    string     `json:"date_end" gorm:"column:date_end"`
+	Status     string     `json:"status" gorm:"column:status"`
+	Rows       int64      `json:"rows" gorm:"column:rows"`
+	Error      string     `json:"error,omitempty" gorm:"column:error"`
+	StartedAt  time.Time  `json:"started_at" gorm:"column:started_at"`
+	FinishedAt *time.Time `json:"finished_at" gorm:"column:finished_at"`
+}
+
+func (a *ExportScheduleRun) TableName() string {
+	return "public.export_schedule_run"
+}
+
+type ExportScheduleRepository interface {
+	Create(ctx context.Context, schedule *ExportSchedule) error
+	FindAll(ctx context.Context) ([]*ExportSchedule, error)
+	FindEnabled(ctx context.Context) ([]*ExportSchedule, error)
+	FindByID(ctx context.Context, id string) (*ExportSchedule, error)
+	// ClaimRun moves last_run_at from previous to at. It reports false when
+	// another exporter instance claimed the run first.
+	ClaimRun(ctx context.Context, id string, previous *time.Time, at time.Time) (bool, error)
+	SaveRun(ctx context.Context, run *ExportScheduleRun) error
+	// FinishJobRun records a finished job's outcome on the run that created
+	// it. Jobs no schedule created match no run.
+	FinishJobRun(ctx context.Context, job *ExportJob) error
+	FindRuns(ctx context.Context, scheduleID string, limit int) ([]*ExportScheduleRun, error)
+}
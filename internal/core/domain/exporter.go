@@ -1,6 +1,9 @@
 package domain
 
-import "event-registration/internal/common/request"
+import (
+	"context"
+	"event-registration/internal/common/request"
+)
 
 type Transaksi struct {
 	ID             string `json:"id" gorm:"column:id"`
@@ -28,6 +31,32 @@ func (a *Transaksi) TableName() string {
 	return "plnmobile.vw_transaksi"
 }
 
+// Dimensions of a TransaksiSummary row, one per grouping set.
+const (
+	SUMMARY_DIMENSION_TOTAL           = "total"
+	SUMMARY_DIMENSION_UNIT            = "unit"
+	SUMMARY_DIMENSION_PAYMENT_GATEWAY = "payment_gateway"
+	SUMMARY_DIMENSION_TYPE            = "type"
+	SUMMARY_DIMENSION_STATUS_CODE     = "status_code"
+)
+
+// TransaksiSummary is one aggregate row of SummarizeTransaksi. Only the
+// columns of its Dimension are set.
+type TransaksiSummary struct {
+	Dimension      string  `json:"dimension" gorm:"column:dimension"`
+	UnitUPI        string  `json:"unit_upi" gorm:"column:unit_upi"`
+	NameUnitUpi    string  `json:"nama_unit_upi" gorm:"column:nama_unit_upi"`
+	UnitAP         string  `json:"unit_ap" gorm:"column:unit_ap"`
+	NameUnitAP     string  `json:"nama_unit_ap" gorm:"column:nama_unit_ap"`
+	UnitUP         string  `json:"unit_up" gorm:"column:unit_up"`
+	NameUnitUP     string  `json:"nama_unit_up" gorm:"column:nama_unit_up"`
+	PaymentGateway string  `json:"payment_gateway" gorm:"column:payment_gateway"`
+	Type           string  `json:"type" gorm:"column:type"`
+	StatusCode     string  `json:"status_code" gorm:"column:status_code"`
+	Count          int64   `json:"count" gorm:"column:count"`
+	TotalAmount    float64 `json:"total_amount" gorm:"column:total_amount"`
+}
+
 type Pelanggan struct {
 	ID           string `json:"id" gorm:"column:id"`
 	IDPel        string `json:"idpel" gorm:"column:idpel"`
@@ -113,10 +142,26 @@ func (a *Unit) TableName() string {
 	return "public.pln_unit_up"
 }
 
+// ExportCursor points at the last row of a keyset page, rows are ordered by
+// (created_at, id).
+type ExportCursor struct {
+	CreatedAt string `json:"created_at"`
+	ID        string `json:"id"`
+}
+
 type ExporterRepository interface {
-	GetAllUnit() (result []*Regional, err error)
-	FindTransaksi(req *request.RekapRequest) ([]*Transaksi, error)
-	CountTransaksi(req *request.RekapRequest) (result int64, err error)
-	FindPelanggan(req *request.RekapRequest) ([]*Pelanggan, error)
-	CountPelanggan(req *request.RekapRequest) (result int64, err error)
+	GetAllUnit(ctx context.Context) (result []*Regional, err error)
+	// UnitsVersion fingerprints the rows of every pln_unit_* table, it
+	// changes whenever a unit does.
+	UnitsVersion(ctx context.Context) (string, error)
+	StreamTransaksi(ctx context.Context, req *request.RekapRequest, after *ExportCursor, limit int, fn func(*Transaksi) error) error
+	CountTransaksi(ctx context.Context, req *request.RekapRequest) (result int64, err error)
+	SummarizeTransaksi(ctx context.Context, req *request.RekapRequest) ([]*TransaksiSummary, error)
+	StreamPelanggan(ctx context.Context, req *request.RekapRequest, after *ExportCursor, limit int, fn func(*Pelanggan) error) error
+	CountPelanggan(ctx context.Context, req *request.RekapRequest) (result int64, err error)
+	// DailyTotalsTransaksi counts and sums the transactions of req per unit
+	// and per day, ordered by unit and day.
+	DailyTotalsTransaksi(ctx context.Context, req *request.RekapRequest) ([]*TransaksiDailyTotal, error)
+	// TransaksiIDs returns the ids of the transactions of req, in order.
+	TransaksiIDs(ctx context.Context, req *request.RekapRequest) ([]string, error)
 }
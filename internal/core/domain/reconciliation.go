@@ -0,0 +1,45 @@
+package domain
+
+const (
+	RECONCILIATION_MATCHED    = "matched"
+	RECONCILIATION_MISMATCHED = "mismatched"
+)
+
+// TransaksiDailyTotal counts the transactions of one unit on one day.
+type TransaksiDailyTotal struct {
+	UnitUP      string  `json:"unit_up" gorm:"column:unit_up"`
+	NameUnitUP  string  `json:"nama_unit_up" gorm:"column:nama_unit_up"`
+	Day         string  `json:"day" gorm:"column:day"`
+	Count       int64   `json:"count" gorm:"column:count"`
+	TotalAmount float64 `json:"total_amount" gorm:"column:total_amount"`
+}
+
+// ReconciliationRow compares one unit on one day between the DWH and PLN
+// Mobile. The sample ids are only looked up for mismatched rows.
+type ReconciliationRow struct {
+	UnitUP          string   `json:"unit_up"`
+	NameUnitUP      string   `json:"nama_unit_up"`
+	Day             string   `json:"day"`
+	DwhCount        int64    `json:"dwh_count"`
+	PlnMobileCount  int64    `json:"plnmobile_count"`
+	DwhAmount       float64  `json:"dwh_amount"`
+	PlnMobileAmount float64  `json:"plnmobile_amount"`
+	Status          string   `json:"status"`
+	OnlyInDwh       []string `json:"only_in_dwh,omitempty"`
+	OnlyInPlnMobile []string `json:"only_in_plnmobile,omitempty"`
+}
+
+// ReconciliationReport summarises a reconciliation, every row is in the xlsx
+// file at Path.
+type ReconciliationReport struct {
+	Path             string  `json:"path"`
+	ThresholdPercent float64 `json:"threshold_percent"`
+	Rows             int     `json:"rows"`
+	Mismatched       int     `json:"mismatched"`
+	DwhCount         int64   `json:"dwh_count"`
+	PlnMobileCount   int64   `json:"plnmobile_count"`
+	DwhAmount        float64 `json:"dwh_amount"`
+	PlnMobileAmount  float64 `json:"plnmobile_amount"`
+	// Mismatches lists the first mismatched rows
+	Mismatches []ReconciliationRow `json:"mismatches"`
+}
@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrUnitNotFound      = errors.New("unit_not_found")
+	ErrUnitTreeNotCached = errors.New("unit_tree_not_cached")
+)
+
+// UnitTree is the Regional→Induk→Area→Unit hierarchy as cached, with the
+// UnitsVersion it was built from.
+type UnitTree struct {
+	Version  string      `json:"version"`
+	Regional []*Regional `json:"regional"`
+	BuiltAt  time.Time   `json:"built_at"`
+}
+
+type UnitTreeCache interface {
+	Get(ctx context.Context) (*UnitTree, error)
+	Set(ctx context.Context, tree *UnitTree, expiration time.Duration) error
+}
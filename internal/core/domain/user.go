@@ -1,15 +1,17 @@
 package domain
 
 import (
+	"context"
 	"time"
 )
 
 type UserRepository interface {
-	Search(key string) (user []*UserVCC, err error)
-	Roles() (user []*Role, err error)
-	Unit(level string) (units []*UnitName, err error)
-	Update(user *UserVCC) (err error)
-	FindAll() (user []*UserVCC, err error)
+	Search(ctx context.Context, key string) (user []*UserVCC, err error)
+	Roles(ctx context.Context) (user []*Role, err error)
+	Unit(ctx context.Context, level string) (units []*UnitName, err error)
+	Update(ctx context.Context, user *UserVCC) (err error)
+	FindAll(ctx context.Context) (user []*UserVCC, err error)
+	RoleNames(ctx context.Context, email string) (roles []string, err error)
 }
 
 type UserVCC struct {
@@ -30,15 +30,17 @@ var GoogleUserinfoURL = "https://www.googleapis.com/oauth2/v2/userinfo"
 
 type AuthService struct {
 	repo              domain.AuthRepository
+	users             domain.UserRepository
 	logger            *zap.Logger
 	GoogleOauthConfig *oauth2.Config
 	config            *common.Config
 	sessionService    *SessionService
 }
 
-func NewAuthService(repo domain.AuthRepository, logger *zap.Logger, googleConfig *oauth2.Config, config *common.Config, sessionService *SessionService) *AuthService {
+func NewAuthService(repo domain.AuthRepository, users domain.UserRepository, logger *zap.Logger, googleConfig *oauth2.Config, config *common.Config, sessionService *SessionService) *AuthService {
 	return &AuthService{
 		repo:              repo,
+		users:             users,
 		GoogleOauthConfig: googleConfig,
 		logger:            logger,
 		config:            config,
@@ -126,7 +128,7 @@ func (s *AuthService) GoogleHandleCallback(ctx context.Context, req *request.Goo
 		return accessToken, refreshToken, err
 	}
 
-	exists, err := s.repo.IsRegistered(user.Email)
+	exists, err := s.repo.IsRegistered(ctx, user.Email)
 	if err != nil {
 		s.logger.Error(
 			"error_check_is_registered",
@@ -139,7 +141,7 @@ func (s *AuthService) GoogleHandleCallback(ctx context.Context, req *request.Goo
 
 	if !exists {
 		user.ID = helper.GenerateUUID()
-		err = s.Register(*user)
+		err = s.Register(ctx, *user)
 		if err != nil {
 			s.logger.Error(
 				"error_registered",
@@ -148,7 +150,7 @@ func (s *AuthService) GoogleHandleCallback(ctx context.Context, req *request.Goo
 			return accessToken, refreshToken, err
 		}
 	} else {
-		user, err = s.repo.FindByEmail(user.Email)
+		user, err = s.repo.FindByEmail(ctx, user.Email)
 		if err != nil {
 			s.logger.Error(
 				"error_get_user_by_email",
@@ -158,7 +160,7 @@ func (s *AuthService) GoogleHandleCallback(ctx context.Context, req *request.Goo
 		}
 	}
 
-	accessToken, refreshToken, err = s.GenerateToken(user)
+	accessToken, refreshToken, err = s.GenerateToken(ctx, user)
 	if err != nil {
 		s.logger.Error("error_create_token", zap.Error(err))
 		return accessToken, refreshToken, errors.New("invalid_credentials")
@@ -167,7 +169,7 @@ func (s *AuthService) GoogleHandleCallback(ctx context.Context, req *request.Goo
 	return accessToken, refreshToken, err
 }
 
-func (s *AuthService) Register(user domain.User) (err error) {
+func (s *AuthService) Register(ctx context.Context, user domain.User) (err error) {
 	var now time.Time = time.Now()
 
 	if user.VerifiedEmail {
@@ -186,7 +188,7 @@ func (s *AuthService) Register(user domain.User) (err error) {
 
 	user.Password = password
 
-	return s.repo.Register(user)
+	return s.repo.Register(ctx, user)
 }
 
 func (s *AuthService) GenerateSafePassword(length int) (string, error) {
@@ -212,7 +214,7 @@ func (s *AuthService) GenerateSafePassword(length int) (string, error) {
 }
 
 func (s *AuthService) Login(ctx context.Context, req *request.LoginRequest) (accessToken, refreshToken string, err error) {
-	user, err := s.repo.FindByEmail(req.Email)
+	user, err := s.repo.FindByEmail(ctx, req.Email)
 	if err != nil {
 		s.logger.Error("error_get_user_by_email", zap.Error(err))
 		return accessToken, refreshToken, errors.New("invalid_credentials")
@@ -224,7 +226,7 @@ func (s *AuthService) Login(ctx context.Context, req *request.LoginRequest) (acc
 		return accessToken, refreshToken, errors.New("invalid_credentials")
 	}
 
-	accessToken, refreshToken, err = s.GenerateToken(user)
+	accessToken, refreshToken, err = s.GenerateToken(ctx, user)
 	if err != nil {
 		s.logger.Error("error_create_token", zap.Error(err))
 		return accessToken, refreshToken, errors.New("invalid_credentials")
@@ -233,8 +235,8 @@ func (s *AuthService) Login(ctx context.Context, req *request.LoginRequest) (acc
 	return accessToken, refreshToken, nil
 }
 
-func (s *AuthService) GenerateToken(user *domain.User) (accessToken, refreshToken string, err error) {
-	accessToken, err = s.GenerateAccessTokenJWT(user)
+func (s *AuthService) GenerateToken(ctx context.Context, user *domain.User) (accessToken, refreshToken string, err error) {
+	accessToken, err = s.GenerateAccessTokenJWT(ctx, user)
 	if err != nil {
 		s.logger.Error("error_generate_access_token_jwt", zap.Error(err))
 		return accessToken, refreshToken, err
@@ -247,7 +249,7 @@ func (s *AuthService) GenerateToken(user *domain.User) (accessToken, refreshToke
 	}
 
 	expiration := time.Duration(s.config.RefreshTokenExpiration) * 24 * time.Hour
-	err = s.sessionService.CreateSession(context.Background(), user.ID, user.Email, refreshToken, expiration)
+	err = s.sessionService.CreateSession(ctx, user.ID, user.Email, refreshToken, expiration)
 	if err != nil {
 		s.logger.Error("error_create_session", zap.Error(err))
 		return accessToken, refreshToken, err
@@ -256,10 +258,20 @@ func (s *AuthService) GenerateToken(user *domain.User) (accessToken, refreshToke
 	return accessToken, refreshToken, nil
 }
 
-func (s *AuthService) GenerateAccessTokenJWT(user *domain.User) (string, error) {
+// GenerateAccessTokenJWT issues an access token carrying the user's current
+// roles, they are read on every issue so a refresh picks up role changes.
+func (s *AuthService) GenerateAccessTokenJWT(ctx context.Context, user *domain.User) (string, error) {
+	// a token without roles still logs the user in, only role gated data is masked
+	roles, err := s.users.RoleNames(ctx, user.Email)
+	if err != nil {
+		s.logger.Error("error_get_user_roles", zap.Error(err))
+		roles = nil
+	}
+
 	claims := map[string]any{
 		"sub":   user.ID,
 		"email": user.Email,
+		"roles": roles,
 		"exp":   time.Now().Add(time.Duration(s.config.AccessJwtExpiration) * time.Minute).Unix(),
 		"type":  constant.ACCESS_TOKEN,
 	}
@@ -16,6 +16,7 @@ import (
 	gormrepo "event-registration/internal/repository/gorm"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
@@ -80,16 +81,16 @@ func (s *AuthServiceIntegrationSuite) SetupTest() {
 	s.mock = mock
 	s.cleanup = cleanup
 	s.logger = zap.NewNop()
-	s.repo = gormrepo.NewAuthRepo(db, s.logger)
-	s.google = &oauth2.Config{ClientID: "test", ClientSecret: "test", RedirectURL: "http://localhost"}
 	s.config = &common.Config{JwtSecret: "secret", AccessJwtExpiration: 10, RefreshTokenExpiration: 7}
+	s.repo = gormrepo.NewAuthRepo(db, s.logger, s.config)
+	s.google = &oauth2.Config{ClientID: "test", ClientSecret: "test", RedirectURL: "http://localhost"}
 	s.sessionService = &MockSessionService{}
 
 	// Create real SessionService for constructor compatibility
 	redisClient := &redis.Client{} // Mock redis client
 	realSessionService := service.NewSessionService(redisClient, s.logger)
 
-	s.service = service.NewAuthService(s.repo, s.logger, s.google, s.config, realSessionService)
+	s.service = service.NewAuthService(s.repo, gormrepo.NewUserRepo(db, s.logger, s.config), s.logger, s.google, s.config, realSessionService)
 }
 
 func (s *AuthServiceIntegrationSuite) TearDownTest() {
@@ -157,6 +158,9 @@ func (s *AuthServiceIntegrationSuite) TestGoogleHandleCallbackSuccess() {
 	s.mock.ExpectBegin()
 	s.mock.ExpectExec("INSERT").WillReturnResult(sqlmock.NewResult(1, 1))
 	s.mock.ExpectCommit()
+	s.mock.ExpectQuery(`SELECT "roles"."name" FROM "dashboard"."roles"`).
+		WithArgs(user.Email).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}))
 
 	cbReq := &request.GoogleCallbackRequest{Code: "code", State: "state", StateCookie: "state"}
 	access, refresh, err := s.service.GoogleHandleCallback(context.Background(), cbReq)
@@ -330,12 +334,32 @@ func (s *AuthServiceIntegrationSuite) TestLoginInvalidCredentials() {
 
 func (s *AuthServiceIntegrationSuite) TestGenerateTokenJWT() {
 	user := &domain.User{ID: "1", Email: "jwt@example.com"}
-	access, refresh, err := s.service.GenerateToken(user)
+	s.mock.ExpectQuery(`SELECT "roles"."name" FROM "dashboard"."roles"`).
+		WithArgs("jwt@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("admin"))
+
+	access, refresh, err := s.service.GenerateToken(context.Background(), user)
 	require.NoError(s.T(), err)
 	require.NotEmpty(s.T(), access)
 	require.NotEmpty(s.T(), refresh)
 }
 
+func (s *AuthServiceIntegrationSuite) TestGenerateAccessTokenJWTWithoutRoles() {
+	user := &domain.User{ID: "1", Email: "jwt@example.com"}
+	s.mock.ExpectQuery(`SELECT "roles"."name" FROM "dashboard"."roles"`).
+		WithArgs("jwt@example.com").
+		WillReturnError(errors.New("mock roles error"))
+
+	access, err := s.service.GenerateAccessTokenJWT(context.Background(), user)
+	require.NoError(s.T(), err)
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(access, claims, func(*jwt.Token) (any, error) { return []byte("secret"), nil })
+	require.NoError(s.T(), err)
+	require.Nil(s.T(), claims["roles"])
+	require.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
+
 func (s *AuthServiceIntegrationSuite) TestGoogleHandleCallbackErrorReadBody() {
 	// Simulate a server that returns a response with a broken body (read error)
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
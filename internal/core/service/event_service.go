@@ -2,6 +2,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"event-registration/internal/core/domain"
 	"time"
@@ -26,30 +27,30 @@ func NewEventService(repo domain.EventRepository, cache domain.EventCache, logge
 }
 
 // RegisterEvent registers a user for an event
-func (s *EventService) RegisterEvent(eventID, userID string) error {
+func (s *EventService) RegisterEvent(ctx context.Context, eventID, userID string) error {
 	// Check if the event exists in the cache
-	cachedEvent, err := s.cache.Get(eventID)
+	cachedEvent, err := s.cache.Get(ctx, eventID)
 	if err == nil && cachedEvent != nil {
 		// Use the cached event
-		return s.processRegistration(cachedEvent, userID)
+		return s.processRegistration(ctx, cachedEvent, userID)
 	}
 
 	// If not in cache, fetch from the repository
-	event, err := s.repo.FindByID(eventID)
+	event, err := s.repo.FindByID(ctx, eventID)
 	if err != nil {
 		return errors.New("event not found")
 	}
 
 	// Cache the event for future use
-	s.cache.Set(eventID, event, time.Minute)
+	s.cache.Set(ctx, eventID, event, time.Minute)
 
 	// Process the registration
-	return s.processRegistration(event, userID)
+	return s.processRegistration(ctx, event, userID)
 }
 
 // processRegistration handles the actual registration logic
 // TODO: FIX THIS
-func (s *EventService) processRegistration(event *domain.Event, _ string) error {
+func (s *EventService) processRegistration(ctx context.Context, event *domain.Event, _ string) error {
 	// Check if there are available slots
 	if event.BookedSlots >= event.TotalSlots {
 		return errors.New("no available slots")
@@ -59,7 +60,7 @@ func (s *EventService) processRegistration(event *domain.Event, _ string) error
 	event.BookedSlots++
 
 	// Save the updated event to the repository
-	err := s.repo.Update(event)
+	err := s.repo.Update(ctx, event)
 	if err != nil {
 		return errors.New("failed to update event")
 	}
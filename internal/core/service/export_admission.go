@@ -0,0 +1,434 @@
+package service
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"event-registration/internal/common/helper"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"reflect"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrExportQuotaExceeded = errors.New("export_daily_quota_exceeded")
+	ErrExportQueueFull     = errors.New("export_queue_full")
+	ErrExportAlreadyQueued = errors.New("export_already_queued")
+)
+
+const (
+	// defaultExportRunDuration is the expected run time of an export type no
+	// past run has measured yet.
+	defaultExportRunDuration = 5 * time.Minute
+
+	// exportAdmissionKey locks admission across every exporter process.
+	exportAdmissionKey = "admission"
+	// exportAdmissionTTL frees the admission lock of a process that died
+	// holding it.
+	exportAdmissionTTL  = 10 * time.Second
+	exportAdmissionPoll = 50 * time.Millisecond
+
+	// exportSlotPoll is how often an export waiting for a running slot
+	// shared with other processes tries again, and keeps its place in line.
+	exportSlotPoll = 2 * time.Second
+)
+
+// ExportRejection is returned by EnqueueExport when admission control turns
+// a request away, RetryAfter is how long the requester should wait.
+type ExportRejection struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *ExportRejection) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExportRejection) Unwrap() error {
+	return e.Err
+}
+
+// exportPriority ranks a job by how much of the DWH it reads, smaller jobs
+// run first.
+func exportPriority(job *domain.ExportJob) int {
+	if job.Type == domain.EXPORT_TYPE_TRANSAKSI_ALL {
+		return 5
+	}
+
+	req := job.Request
+	switch {
+	case req == nil:
+		return 4
+	case len(req.UnitCode) > 0:
+		return 0
+	case len(req.Area) > 0:
+		return 1
+	case len(req.Induk) > 0:
+		return 2
+	case len(req.Pusat) > 0:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// lockAdmission makes admitExport and the creation of the admitted job
+// atomic, across every exporter process when they share an admission
+// repository. Otherwise two requests could both pass the checks before
+// either job is saved.
+func (s *ExporterService) lockAdmission(ctx context.Context) (unlock func(), err error) {
+	if s.admission == nil {
+		s.admitMu.Lock()
+		return s.admitMu.Unlock, nil
+	}
+
+	owner := helper.GenerateUUID()
+	for {
+		locked, err := s.admission.Lock(ctx, exportAdmissionKey, owner, exportAdmissionTTL)
+		if err != nil {
+			s.logger.Error(
+				"error_lock_export_admission",
+				zap.Error(err),
+			)
+			return nil, err
+		}
+
+		if locked {
+			break
+		}
+
+		select {
+		case <-time.After(exportAdmissionPoll):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return func() {
+		if err := s.admission.Unlock(context.WithoutCancel(ctx), exportAdmissionKey, owner); err != nil {
+			s.logger.Error(
+				"error_unlock_export_admission",
+				zap.Error(err),
+			)
+		}
+	}, nil
+}
+
+// admitExport turns a request away when its requester used up the daily
+// quota, already has the same export queued, or too many exports are
+// waiting. Scheduled runs are not subject to it. Callers hold lockAdmission.
+func (s *ExporterService) admitExport(ctx context.Context, exportType string, req *request.RekapRequest, requestedBy string) error {
+	active, err := s.jobs.FindActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	if quota := s.config.ExportDailyQuota; quota > 0 && requestedBy != "" {
+		jobs, err := s.jobs.FindByUser(ctx, requestedBy)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().In(s.exportLocation())
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+		var count int
+		for _, job := range jobs {
+			if !job.CreatedAt.Before(today) {
+				count++
+			}
+		}
+
+		if count >= quota {
+			return &ExportRejection{Err: ErrExportQuotaExceeded, RetryAfter: today.AddDate(0, 0, 1).Sub(now)}
+		}
+	}
+
+	for _, job := range active {
+		if job.RequestedBy == requestedBy && job.Type == exportType && reflect.DeepEqual(job.Request, req) {
+			return &ExportRejection{Err: ErrExportAlreadyQueued, RetryAfter: s.expectedWait(ctx, exportType, jobsAhead(active, job))}
+		}
+	}
+
+	if limit := s.config.ExportMaxQueued; limit > 0 && len(active) >= limit {
+		return &ExportRejection{Err: ErrExportQueueFull, RetryAfter: s.expectedWait(ctx, exportType, len(active))}
+	}
+
+	return nil
+}
+
+// jobsAhead counts the active jobs created before job.
+func jobsAhead(active []*domain.ExportJob, job *domain.ExportJob) int {
+	var ahead int
+	for _, other := range active {
+		if other.CreatedAt.Before(job.CreatedAt) {
+			ahead++
+		}
+	}
+
+	return ahead
+}
+
+// expectedWait is how long ahead jobs take to clear EXPORT_MAX_CONCURRENT
+// slots, from the average duration of the last runs of exportType.
+func (s *ExporterService) expectedWait(ctx context.Context, exportType string, ahead int) time.Duration {
+	slots := max(s.config.ExportMaxConcurrent, 1)
+
+	return s.averageRunDuration(ctx, exportType) * time.Duration(ahead/slots+1)
+}
+
+func (s *ExporterService) averageRunDuration(ctx context.Context, exportType string) time.Duration {
+	if s.audits == nil {
+		return defaultExportRunDuration
+	}
+
+	audits, err := s.audits.FindRecentDone(ctx, exportType, estimateHistoryRuns)
+	if err != nil {
+		s.logger.Error(
+			"error_find_recent_export_audits",
+			zap.String("type", exportType),
+			zap.Error(err),
+		)
+		return defaultExportRunDuration
+	}
+
+	if len(audits) == 0 {
+		return defaultExportRunDuration
+	}
+
+	var durationMs int64
+	for _, audit := range audits {
+		durationMs += audit.DurationMs
+	}
+
+	return time.Duration(durationMs/int64(len(audits))) * time.Millisecond
+}
+
+// acquireRun waits for one of the running slots shared with the other
+// exporter processes, or for a slot of this process's gate when there is no
+// shared admission.
+func (s *ExporterService) acquireRun(ctx context.Context, job *domain.ExportJob) (release func(), err error) {
+	if s.admission != nil && s.config.ExportMaxConcurrent > 0 {
+		return s.acquireExportSlot(ctx, job)
+	}
+
+	return s.gate.acquire(ctx, exportPriority(job), func(waiters int) {
+		s.logger.Info(
+			"export_job_waiting",
+			zap.String("job_id", job.ID),
+			zap.Int("priority", exportPriority(job)),
+			zap.Int("waiters", waiters),
+		)
+	})
+}
+
+// acquireExportSlot takes one of the EXPORT_MAX_CONCURRENT running slots
+// shared by every exporter process, waiting in a line shared by them too
+// until its turn comes, ctx is done or the export worker drains. Like the
+// gate, the line lets smaller exports in first. The slot is renewed until
+// release, the slot or place in line of a process that died frees up once
+// its lease runs out.
+func (s *ExporterService) acquireExportSlot(ctx context.Context, job *domain.ExportJob) (release func(), err error) {
+	limit := s.config.ExportMaxConcurrent
+	priority := exportPriority(job)
+	lease := s.exportJobLease()
+	// the place in line is renewed by polling, well within its lease
+	poll := min(exportSlotPoll, lease/3)
+
+	queuedAt := job.CreatedAt
+	if queuedAt.IsZero() {
+		queuedAt = time.Now()
+	}
+
+	for waiting := false; ; waiting = true {
+		acquired, err := s.admission.AcquireSlot(ctx, job.ID, priority, queuedAt, limit, lease)
+		if err != nil {
+			s.logger.Error(
+				"error_acquire_export_slot",
+				zap.String("job_id", job.ID),
+				zap.Error(err),
+			)
+			return nil, err
+		}
+
+		if acquired {
+			break
+		}
+
+		if !waiting {
+			s.logger.Info(
+				"export_job_waiting_for_slot",
+				zap.String("job_id", job.ID),
+				zap.Int("priority", priority),
+				zap.Int("limit", limit),
+			)
+		}
+
+		select {
+		case <-time.After(poll):
+			continue
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-drainSignal(ctx):
+			err = domain.ErrExportInterrupted
+		}
+
+		// leave the line
+		if errRelease := s.admission.ReleaseSlot(context.WithoutCancel(ctx), job.ID); errRelease != nil {
+			s.logger.Error(
+				"error_release_export_slot",
+				zap.String("job_id", job.ID),
+				zap.Error(errRelease),
+			)
+		}
+		return nil, err
+	}
+
+	stop := heartbeat(lease, func() bool {
+		if err := s.admission.RenewSlot(context.WithoutCancel(ctx), job.ID, lease); err != nil {
+			s.logger.Error(
+				"error_renew_export_slot",
+				zap.String("job_id", job.ID),
+				zap.Error(err),
+			)
+		}
+		return true
+	})
+
+	return func() {
+		stop()
+
+		if err := s.admission.ReleaseSlot(context.WithoutCancel(ctx), job.ID); err != nil {
+			s.logger.Error(
+				"error_release_export_slot",
+				zap.String("job_id", job.ID),
+				zap.Error(err),
+			)
+		}
+	}, nil
+}
+
+// exportGate lets at most limit exports read the databases at once. Waiting
+// exports are let in by priority, then in arrival order. A limit of 0 lets
+// every export in.
+type exportGate struct {
+	mu      sync.Mutex
+	limit   int
+	running int
+	seq     uint64
+	waiting exportWaiters
+}
+
+func newExportGate(limit int) *exportGate {
+	return &exportGate{limit: limit}
+}
+
+// acquire waits for a slot until ctx is done, or until the export worker
+// drains. waiting is told how many exports wait when it has to wait too.
+// release hands the slot to the next waiting export.
+func (g *exportGate) acquire(ctx context.Context, priority int, waiting func(waiters int)) (release func(), err error) {
+	if g.limit <= 0 {
+		return func() {}, nil
+	}
+
+	g.mu.Lock()
+	if g.running < g.limit && len(g.waiting) == 0 {
+		g.running++
+		g.mu.Unlock()
+		return g.releaser(), nil
+	}
+
+	g.seq++
+	waiter := &exportWaiter{priority: priority, seq: g.seq, ready: make(chan struct{})}
+	heap.Push(&g.waiting, waiter)
+	waiters := len(g.waiting)
+	g.mu.Unlock()
+
+	waiting(waiters)
+
+	select {
+	case <-waiter.ready:
+		return g.releaser(), nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-drainSignal(ctx):
+		err = domain.ErrExportInterrupted
+	}
+
+	g.mu.Lock()
+	granted := waiter.index < 0
+	if !granted {
+		heap.Remove(&g.waiting, waiter.index)
+	}
+	g.mu.Unlock()
+
+	// the slot was handed over meanwhile, pass it on
+	if granted {
+		g.releaser()()
+	}
+
+	return nil, err
+}
+
+func (g *exportGate) releaser() func() {
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			g.mu.Lock()
+			defer g.mu.Unlock()
+
+			if len(g.waiting) == 0 {
+				g.running--
+				return
+			}
+
+			next := heap.Pop(&g.waiting).(*exportWaiter)
+			close(next.ready)
+		})
+	}
+}
+
+type exportWaiter struct {
+	priority int
+	seq      uint64
+	ready    chan struct{}
+	// index in the heap, -1 once let in
+	index int
+}
+
+type exportWaiters []*exportWaiter
+
+func (w exportWaiters) Len() int { return len(w) }
+
+func (w exportWaiters) Less(i, j int) bool {
+	if w[i].priority != w[j].priority {
+		return w[i].priority < w[j].priority
+	}
+	return w[i].seq < w[j].seq
+}
+
+func (w exportWaiters) Swap(i, j int) {
+	w[i], w[j] = w[j], w[i]
+	w[i].index = i
+	w[j].index = j
+}
+
+func (w *exportWaiters) Push(x any) {
+	waiter := x.(*exportWaiter)
+	waiter.index = len(*w)
+	*w = append(*w, waiter)
+}
+
+func (w *exportWaiters) Pop() any {
+	old := *w
+	waiter := old[len(old)-1]
+	old[len(old)-1] = nil
+	waiter.index = -1
+	*w = old[:len(old)-1]
+	return waiter
+}
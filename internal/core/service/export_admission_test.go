@@ -0,0 +1,321 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"event-registration/internal/common"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"event-registration/internal/core/service"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// gatedExporterRepo reports the unit of every export that starts reading
+// and holds it until proceed is closed.
+type gatedExporterRepo struct {
+	flakyExporterRepo
+	started chan string
+	proceed chan struct{}
+}
+
+func (r *gatedExporterRepo) StreamTransaksi(ctx context.Context, req *request.RekapRequest, after *domain.ExportCursor, limit int, fn func(*domain.Transaksi) error) error {
+	r.started <- req.UnitCode
+	<-r.proceed
+	return r.flakyExporterRepo.StreamTransaksi(ctx, req, after, limit, fn)
+}
+
+// memoryAdmissionRepo is the admission state shared by several services in
+// a test, leases never expire.
+type memoryAdmissionRepo struct {
+	mu      sync.Mutex
+	locks   map[string]string
+	slots   map[string]bool
+	waiters map[string]slotWaiter
+}
+
+type slotWaiter struct {
+	priority int
+	queuedAt time.Time
+}
+
+func newMemoryAdmissionRepo() *memoryAdmissionRepo {
+	return &memoryAdmissionRepo{locks: map[string]string{}, slots: map[string]bool{}, waiters: map[string]slotWaiter{}}
+}
+
+func (r *memoryAdmissionRepo) Lock(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.locks[key]; ok {
+		return false, nil
+	}
+	r.locks[key] = owner
+	return true, nil
+}
+
+func (r *memoryAdmissionRepo) Unlock(ctx context.Context, key, owner string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.locks[key] == owner {
+		delete(r.locks, key)
+	}
+	return nil
+}
+
+func (r *memoryAdmissionRepo) AcquireSlot(ctx context.Context, holder string, priority int, queuedAt time.Time, limit int, lease time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.slots[holder] {
+		return true, nil
+	}
+
+	waiter := slotWaiter{priority: priority, queuedAt: queuedAt}
+	r.waiters[holder] = waiter
+
+	ahead := 0
+	for other, w := range r.waiters {
+		if w.priority < waiter.priority || (w.priority == waiter.priority && (w.queuedAt.Before(waiter.queuedAt) || (w.queuedAt.Equal(waiter.queuedAt) && other < holder))) {
+			ahead++
+		}
+	}
+
+	if ahead >= limit-len(r.slots) {
+		return false, nil
+	}
+
+	delete(r.waiters, holder)
+	r.slots[holder] = true
+	return true, nil
+}
+
+func (r *memoryAdmissionRepo) RenewSlot(ctx context.Context, holder string, lease time.Duration) error {
+	return nil
+}
+
+func (r *memoryAdmissionRepo) ReleaseSlot(ctx context.Context, holder string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.slots, holder)
+	delete(r.waiters, holder)
+	return nil
+}
+
+func TestEnqueueExportAdmission(t *testing.T) {
+	queue := newMemoryExportQueue()
+	cfg := &common.Config{ExportMaxConcurrent: 1, ExportMaxQueued: 3, ExportDailyQuota: 2}
+	s := newExporterService(service.ExporterParams{Repo: &flakyExporterRepo{}, Jobs: &memoryExportJobRepo{jobs: map[string]*domain.ExportJob{}}, Queue: queue, Config: cfg})
+
+	enqueue := func(unitCode, requestedBy string) error {
+		req := &request.RekapRequest{UnitCode: unitCode, DateStart: "2026/02/01", DateEnd: "2026/02/28"}
+		_, err := s.EnqueueExport(context.Background(), domain.EXPORT_TYPE_TRANSAKSI, req, requestedBy, nil)
+		return err
+	}
+
+	require.NoError(t, enqueue("54110", "user-1"))
+
+	var rejection *service.ExportRejection
+
+	err := enqueue("54110", "user-1")
+	require.ErrorIs(t, err, service.ErrExportAlreadyQueued)
+	require.ErrorAs(t, err, &rejection)
+	require.Equal(t, 5*time.Minute, rejection.RetryAfter)
+
+	require.NoError(t, enqueue("54120", "user-1"))
+
+	err = enqueue("54130", "user-1")
+	require.ErrorIs(t, err, service.ErrExportQuotaExceeded)
+	require.ErrorAs(t, err, &rejection)
+	require.LessOrEqual(t, rejection.RetryAfter, 24*time.Hour)
+
+	require.NoError(t, enqueue("54110", "user-2"))
+
+	// three exports wait for the single slot
+	err = enqueue("54110", "user-3")
+	require.ErrorIs(t, err, service.ErrExportQueueFull)
+	require.ErrorAs(t, err, &rejection)
+	require.Equal(t, 20*time.Minute, rejection.RetryAfter)
+}
+
+func TestExportGatePrefersSmallExports(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("files", 0o750))
+
+	// tells the test when an export starts waiting for the gate
+	waiting := make(chan struct{})
+	core, _ := observer.New(zap.InfoLevel)
+	logger := zap.New(core, zap.Hooks(func(entry zapcore.Entry) error {
+		if entry.Message == "export_job_waiting" {
+			waiting <- struct{}{}
+		}
+		return nil
+	}))
+
+	repo := &gatedExporterRepo{started: make(chan string), proceed: make(chan struct{})}
+	jobs := &memoryExportJobRepo{jobs: map[string]*domain.ExportJob{}}
+	s := newExporterService(service.ExporterParams{Repo: repo, Jobs: jobs, Config: &common.Config{ExportMaxConcurrent: 1}, Logger: logger})
+
+	run := func(id string, req *request.RekapRequest) {
+		req.DateStart, req.DateEnd, req.Format = "2026/02/01", "2026/02/28", domain.EXPORT_FORMAT_CSV
+		job := &domain.ExportJob{ID: id, Type: domain.EXPORT_TYPE_TRANSAKSI, Status: domain.EXPORT_JOB_QUEUED, Request: req}
+		go s.RunExportJob(context.Background(), job)
+	}
+
+	run("first", &request.RekapRequest{})
+	require.Equal(t, "", <-repo.started)
+
+	// a national export queues first, then a single unit
+	run("national", &request.RekapRequest{})
+	<-waiting
+	run("unit", &request.RekapRequest{UnitCode: "54110"})
+	<-waiting
+
+	close(repo.proceed)
+	require.Equal(t, "54110", <-repo.started)
+	require.Equal(t, "", <-repo.started)
+}
+
+func TestEnqueueExportAdmissionIsAtomic(t *testing.T) {
+	for name, admission := range map[string]domain.ExportAdmissionRepository{
+		"in process": nil,
+		"shared":     newMemoryAdmissionRepo(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := &common.Config{ExportDailyQuota: 1}
+			s := newExporterService(service.ExporterParams{Repo: &flakyExporterRepo{}, Jobs: &memoryExportJobRepo{jobs: map[string]*domain.ExportJob{}}, Queue: newMemoryExportQueue(), Admission: admission, Config: cfg})
+
+			var wg sync.WaitGroup
+			errs := make(chan error, 10)
+			for i := range 10 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					req := &request.RekapRequest{UnitCode: fmt.Sprintf("5411%d", i), DateStart: "2026/02/01", DateEnd: "2026/02/28"}
+					_, err := s.EnqueueExport(context.Background(), domain.EXPORT_TYPE_TRANSAKSI, req, "user-1", nil)
+					errs <- err
+				}()
+			}
+			wg.Wait()
+			close(errs)
+
+			var admitted int
+			for err := range errs {
+				if err == nil {
+					admitted++
+					continue
+				}
+				require.ErrorIs(t, err, service.ErrExportQuotaExceeded)
+			}
+			require.Equal(t, 1, admitted)
+		})
+	}
+}
+
+func TestExportSlotsAreShared(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("files", 0o750))
+
+	// tells the test when an export waits for a slot held elsewhere
+	waiting := make(chan struct{}, 1)
+	core, _ := observer.New(zap.InfoLevel)
+	logger := zap.New(core, zap.Hooks(func(entry zapcore.Entry) error {
+		if entry.Message == "export_job_waiting_for_slot" {
+			waiting <- struct{}{}
+		}
+		return nil
+	}))
+
+	repo := &gatedExporterRepo{started: make(chan string), proceed: make(chan struct{})}
+	jobs := &memoryExportJobRepo{jobs: map[string]*domain.ExportJob{}}
+	admission := newMemoryAdmissionRepo()
+	cfg := &common.Config{ExportMaxConcurrent: 1}
+
+	// two exporter processes sharing one running slot
+	first := newExporterService(service.ExporterParams{Repo: repo, Jobs: jobs, Admission: admission, Config: cfg, Logger: logger})
+	second := newExporterService(service.ExporterParams{Repo: repo, Jobs: jobs, Admission: admission, Config: cfg, Logger: logger})
+
+	newJob := func(id string) *domain.ExportJob {
+		req := &request.RekapRequest{UnitCode: "54110", DateStart: "2026/02/01", DateEnd: "2026/02/28", Format: domain.EXPORT_FORMAT_CSV}
+		return &domain.ExportJob{ID: id, Type: domain.EXPORT_TYPE_TRANSAKSI, Status: domain.EXPORT_JOB_QUEUED, Request: req}
+	}
+
+	running := newJob("running")
+	done := make(chan struct{})
+	go func() {
+		first.RunExportJob(context.Background(), running)
+		close(done)
+	}()
+	<-repo.started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	blocked := newJob("blocked")
+	go func() {
+		<-waiting
+		cancel()
+	}()
+	second.RunExportJob(ctx, blocked)
+
+	// the second process never got to read
+	require.Equal(t, domain.EXPORT_JOB_CANCELLED, blocked.Status)
+	require.Nil(t, blocked.StartedAt)
+
+	close(repo.proceed)
+	<-done
+	require.Equal(t, domain.EXPORT_JOB_DONE, running.Status)
+	require.Empty(t, admission.slots)
+	require.Empty(t, admission.waiters)
+}
+
+func TestExportSlotsPreferSmallExports(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("files", 0o750))
+
+	// tells the test when an export waits in the shared line
+	waiting := make(chan struct{})
+	core, _ := observer.New(zap.InfoLevel)
+	logger := zap.New(core, zap.Hooks(func(entry zapcore.Entry) error {
+		if entry.Message == "export_job_waiting_for_slot" {
+			waiting <- struct{}{}
+		}
+		return nil
+	}))
+
+	repo := &gatedExporterRepo{started: make(chan string), proceed: make(chan struct{})}
+	jobs := &memoryExportJobRepo{jobs: map[string]*domain.ExportJob{}}
+	admission := newMemoryAdmissionRepo()
+	cfg := &common.Config{ExportMaxConcurrent: 1, ExportJobLease: 30 * time.Millisecond}
+
+	first := newExporterService(service.ExporterParams{Repo: repo, Jobs: jobs, Admission: admission, Config: cfg, Logger: logger})
+	second := newExporterService(service.ExporterParams{Repo: repo, Jobs: jobs, Admission: admission, Config: cfg, Logger: logger})
+
+	run := func(s *service.ExporterService, id string, req *request.RekapRequest) {
+		req.DateStart, req.DateEnd, req.Format = "2026/02/01", "2026/02/28", domain.EXPORT_FORMAT_CSV
+		job := &domain.ExportJob{ID: id, Type: domain.EXPORT_TYPE_TRANSAKSI, Status: domain.EXPORT_JOB_QUEUED, Request: req, CreatedAt: time.Now()}
+		go s.RunExportJob(context.Background(), job)
+	}
+
+	run(first, "first", &request.RekapRequest{})
+	require.Equal(t, "", <-repo.started)
+
+	// a national export queues on the second process first, then a single
+	// unit on the first
+	run(second, "national", &request.RekapRequest{})
+	<-waiting
+	run(first, "unit", &request.RekapRequest{UnitCode: "54110"})
+	<-waiting
+
+	close(repo.proceed)
+	require.Equal(t, "54110", <-repo.started)
+	require.Equal(t, "", <-repo.started)
+}
@@ -0,0 +1,178 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"event-registration/internal/common"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"event-registration/internal/core/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+// flakyExporterRepo serves two transaksi rows per unit. Areas listed in
+// failures fail that many StreamTransaksi calls before succeeding.
+type flakyExporterRepo struct {
+	mu       sync.Mutex
+	failures map[string]int
+}
+
+func (r *flakyExporterRepo) GetAllUnit(ctx context.Context) ([]*domain.Regional, error) {
+	return []*domain.Regional{{
+		Induk: []domain.Induk{{
+			IDUnitUPI:   "11",
+			Satuan:      "UID",
+			NamaUnitUPI: "Jakarta",
+			Area: []domain.Area{
+				{IDUnitAP: "21", Satuan: "UP3", NamaUnitAP: "Menteng"},
+				{IDUnitAP: "22", Satuan: "UP3", NamaUnitAP: "Bulungan"},
+			},
+		}},
+	}}, nil
+}
+
+func (r *flakyExporterRepo) UnitsVersion(ctx context.Context) (string, error) {
+	return "v1", nil
+}
+
+func (r *flakyExporterRepo) StreamTransaksi(ctx context.Context, req *request.RekapRequest, after *domain.ExportCursor, limit int, fn func(*domain.Transaksi) error) error {
+	r.mu.Lock()
+	if r.failures[req.Area] > 0 {
+		r.failures[req.Area]--
+		r.mu.Unlock()
+		return errors.New("connection reset")
+	}
+	r.mu.Unlock()
+
+	for _, id := range []string{"1", "2"} {
+		if err := fn(&domain.Transaksi{ID: id, CreatedAt: "2026-02-01 00:00:00"}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *flakyExporterRepo) CountTransaksi(ctx context.Context, req *request.RekapRequest) (int64, error) {
+	return 2, nil
+}
+
+func (r *flakyExporterRepo) SummarizeTransaksi(ctx context.Context, req *request.RekapRequest) ([]*domain.TransaksiSummary, error) {
+	return []*domain.TransaksiSummary{
+		{Dimension: domain.SUMMARY_DIMENSION_TOTAL, Count: 2, TotalAmount: 40000},
+		{Dimension: domain.SUMMARY_DIMENSION_PAYMENT_GATEWAY, PaymentGateway: "BRI", Count: 2, TotalAmount: 40000},
+	}, nil
+}
+
+func (r *flakyExporterRepo) DailyTotalsTransaksi(ctx context.Context, req *request.RekapRequest) ([]*domain.TransaksiDailyTotal, error) {
+	return nil, nil
+}
+
+func (r *flakyExporterRepo) TransaksiIDs(ctx context.Context, req *request.RekapRequest) ([]string, error) {
+	return nil, nil
+}
+
+func (r *flakyExporterRepo) StreamPelanggan(ctx context.Context, req *request.RekapRequest, after *domain.ExportCursor, limit int, fn func(*domain.Pelanggan) error) error {
+	return nil
+}
+
+func (r *flakyExporterRepo) CountPelanggan(ctx context.Context, req *request.RekapRequest) (int64, error) {
+	return 0, nil
+}
+
+func TestExportAllRekapTransaksiReport(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("files", 0o750))
+
+	repo := &flakyExporterRepo{failures: map[string]int{"21": 1, "22": 100}}
+	cfg := &common.Config{ExportWorkers: 2, ExportRetryAttempts: 3, ExportRetryBackoff: time.Millisecond}
+	s := newExporterService(service.ExporterParams{Repo: repo, Config: cfg})
+
+	req := &request.RekapRequest{DateStart: "2026/02/01", DateEnd: "2026/02/28", Format: domain.EXPORT_FORMAT_CSV}
+	report, err := s.ExportAllRekapTransaksi(context.Background(), req, nil)
+	require.ErrorIs(t, err, service.ErrExportPartiallyFailed)
+	require.FileExists(t, report.Path)
+
+	require.Equal(t, 2, report.Succeeded)
+	require.Equal(t, 1, report.Failed)
+	require.Equal(t, 4, report.TotalRows)
+
+	units := map[string]domain.ExportUnitResult{}
+	for _, unit := range report.Units {
+		units[unit.Name] = unit
+	}
+
+	require.Equal(t, domain.EXPORT_UNIT_SUCCEEDED, units["UP3_MENTENG"].Status)
+	require.Equal(t, 2, units["UP3_MENTENG"].Attempts)
+	require.Equal(t, 2, units["UP3_MENTENG"].Rows)
+
+	require.Equal(t, domain.EXPORT_UNIT_FAILED, units["UP3_BULUNGAN"].Status)
+	require.Equal(t, 3, units["UP3_BULUNGAN"].Attempts)
+	require.NoDirExists(t, "files/UP3_BULUNGAN")
+}
+
+// regionalExporterRepo serves a full regional, induk, area and unit tree.
+type regionalExporterRepo struct {
+	flakyExporterRepo
+}
+
+func (r *regionalExporterRepo) GetAllUnit(ctx context.Context) ([]*domain.Regional, error) {
+	return []*domain.Regional{{
+		IDRegAPKT:    "REG2",
+		NamaRegional: "Jawa Madura Bali",
+		Induk: []domain.Induk{{
+			IDUnitUPI:   "11",
+			Satuan:      "UID",
+			NamaUnitUPI: "Jakarta",
+			Area: []domain.Area{{
+				IDUnitAP:   "21",
+				Satuan:     "UP3",
+				NamaUnitAP: "Menteng",
+				Unit:       []domain.Unit{{IDUnitUP: "31", Satuan: "ULP", NamaUnitUP: "Cikini"}},
+			}},
+		}},
+	}}, nil
+}
+
+func TestExportAllRekapTransaksiRegional(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("files", 0o750))
+
+	cfg := &common.Config{ExportWorkers: 2, ExportRetryAttempts: 1}
+	s := newExporterService(service.ExporterParams{Repo: &regionalExporterRepo{}, Config: cfg})
+
+	req := &request.RekapRequest{DateStart: "2026/02/01", DateEnd: "2026/02/28", Format: domain.EXPORT_FORMAT_CSV}
+	report, err := s.ExportAllRekapTransaksi(context.Background(), req, nil)
+	require.NoError(t, err)
+	require.Equal(t, 4, report.Succeeded)
+
+	units := map[string]domain.ExportUnitResult{}
+	for _, unit := range report.Units {
+		units[unit.Name] = unit
+	}
+
+	require.Equal(t, "REG2", units["REGIONAL_JAWA_MADURA_BALI"].IDPusat)
+	require.Equal(t, "31", units["ULP_CIKINI"].UnitCode)
+	require.FileExists(t, "files/REGIONAL_JAWA_MADURA_BALI/REKAP_TRANSAKSI_EXPORT_REGIONAL_JAWA_MADURA_BALI_PART_1.csv")
+}
+
+func TestExportAllRekapTransaksiCancelled(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("files", 0o750))
+
+	cfg := &common.Config{ExportWorkers: 1, ExportRetryAttempts: 1}
+	s := newExporterService(service.ExporterParams{Repo: &flakyExporterRepo{}, Config: cfg})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := &request.RekapRequest{DateStart: "2026/02/01", DateEnd: "2026/02/28", Format: domain.EXPORT_FORMAT_CSV}
+	report, err := s.ExportAllRekapTransaksi(ctx, req, nil)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, 3, report.Failed)
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"event-registration/internal/core/domain"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const manifestName = "manifest.json"
+
+var ErrExportJobNotFinished = errors.New("export_job_not_finished")
+
+// ArchiveExport packages every file of a finished job, plus a manifest, into
+// a single zip or tar.gz in the job folder. Every attempt on an owned job is
+// audited.
+func (s *ExporterService) ArchiveExport(ctx context.Context, jobID, requestedBy, format string) (artifact *domain.ExportArtifact, err error) {
+	job, err := s.FindExportJob(ctx, jobID, requestedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	startedAt := time.Now()
+	defer func() {
+		s.recordArchiveAudit(context.WithoutCancel(ctx), job, artifact, err, startedAt)
+	}()
+
+	if job.Status != domain.EXPORT_JOB_DONE && job.Status != domain.EXPORT_JOB_PARTIAL {
+		return nil, ErrExportJobNotFinished
+	}
+
+	manifest := &domain.ExportManifest{
+		JobID:      job.ID,
+		Type:       job.Type,
+		Filters:    job.Request,
+		ArchivedAt: time.Now(),
+	}
+	if job.FinishedAt != nil {
+		manifest.GeneratedAt = *job.FinishedAt
+	}
+
+	outputFile := fmt.Sprintf("%sEXPORT_%s.%s", exportJobDir(job.ID), job.ID, format)
+
+	if err := os.MkdirAll(exportJobDir(job.ID), 0o750); err != nil {
+		return nil, err
+	}
+
+	// a half-written archive must not be left for download
+	defer func() {
+		if err != nil {
+			os.Remove(outputFile)
+		}
+	}()
+
+	if err := s.compressFiles(job.Files, outputFile, format, manifest); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(outputFile)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := domain.ExportFile{Path: outputFile, Rows: 0}
+	for _, file := range job.Files {
+		archive.Rows += file.Rows
+	}
+
+	if err := s.saveArchive(ctx, job, archive); err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(s.config.ExportDownloadExpiration)
+
+	return &domain.ExportArtifact{
+		JobID:     job.ID,
+		Path:      outputFile,
+		Rows:      archive.Rows,
+		Size:      info.Size(),
+		URL:       s.SignDownloadURL(outputFile, expiresAt),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func (s *ExporterService) saveArchive(ctx context.Context, job *domain.ExportJob, archive domain.ExportFile) error {
+	for _, existing := range job.Archives {
+		if existing.Path == archive.Path {
+			return nil
+		}
+	}
+
+	job.Archives = append(job.Archives, archive)
+
+	if err := s.jobs.Save(ctx, job); err != nil {
+		s.logger.Error(
+			"error_save_export_job",
+			zap.String("job_id", job.ID),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	return nil
+}
+
+// archiveWriter adds entries to a zip or tar.gz archive.
+type archiveWriter interface {
+	Add(name string, size int64, modTime time.Time, r io.Reader) error
+	Close() error
+}
+
+type tarGzArchive struct {
+	gzip *gzip.Writer
+	tar  *tar.Writer
+}
+
+func newTarGzArchive(w io.Writer) (*tarGzArchive, error) {
+	gzipWriter, err := gzip.NewWriterLevel(w, gzip.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tarGzArchive{gzip: gzipWriter, tar: tar.NewWriter(gzipWriter)}, nil
+}
+
+func (a *tarGzArchive) Add(name string, size int64, modTime time.Time, r io.Reader) error {
+	header := &tar.Header{
+		Name:    name,    // File name
+		Mode:    0o640,   // File mode
+		Size:    size,    // File size
+		ModTime: modTime, // Modification time
+	}
+
+	if err := a.tar.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err := io.Copy(a.tar, r)
+	return err
+}
+
+func (a *tarGzArchive) Close() error {
+	if err := a.tar.Close(); err != nil {
+		return err
+	}
+
+	return a.gzip.Close()
+}
+
+type zipArchive struct {
+	zip *zip.Writer
+}
+
+func (a *zipArchive) Add(name string, size int64, modTime time.Time, r io.Reader) error {
+	entry, err := a.zip.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modTime,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(entry, r)
+	return err
+}
+
+func (a *zipArchive) Close() error {
+	return a.zip.Close()
+}
+
+// compressFiles writes files into outputFile and appends manifest.json, which
+// lists every file with its row count and SHA-256.
+func (s *ExporterService) compressFiles(files []domain.ExportFile, outputFile, format string, manifest *domain.ExportManifest) error {
+	s.logger.Info(
+		"compress_started",
+		zap.Int("file_count", len(files)),
+		zap.String("format", format),
+		zap.String("output", outputFile),
+	)
+
+	if err := validateFilesPath(outputFile); err != nil {
+		s.logger.Error("invalid_output_file_path", zap.String("outputFile", outputFile), zap.Error(err))
+		return err
+	}
+
+	outFile, err := os.Create(outputFile)
+	if err != nil {
+		s.logger.Error(
+			"error_create_output_file",
+			zap.Error(err),
+		)
+		return err
+	}
+	defer outFile.Close()
+
+	var archive archiveWriter
+	switch format {
+	case domain.ARCHIVE_FORMAT_ZIP:
+		archive = &zipArchive{zip: zip.NewWriter(outFile)}
+	case domain.ARCHIVE_FORMAT_TAR_GZ:
+		archive, err = newTarGzArchive(outFile)
+		if err != nil {
+			s.logger.Error(
+				"error_set_compression_level",
+				zap.Error(err),
+			)
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown archive format: %s", format)
+	}
+
+	for _, file := range files {
+		entry, err := s.addArchiveFile(archive, file, exportJobDir(manifest.JobID))
+		if err != nil {
+			archive.Close()
+			return err
+		}
+
+		manifest.Files = append(manifest.Files, entry)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		archive.Close()
+		return err
+	}
+
+	if err := archive.Add(manifestName, int64(len(data)), manifest.ArchivedAt, strings.NewReader(string(data))); err != nil {
+		archive.Close()
+		s.logger.Error(
+			"error_write_manifest",
+			zap.Error(err),
+		)
+		return err
+	}
+
+	if err := archive.Close(); err != nil {
+		s.logger.Error(
+			"error_close_archive",
+			zap.Error(err),
+		)
+		return err
+	}
+
+	s.logger.Info(
+		"compress_finished",
+		zap.String("output", outputFile),
+	)
+
+	return outFile.Sync()
+}
+
+func (s *ExporterService) addArchiveFile(archive archiveWriter, file domain.ExportFile, dir string) (domain.ExportManifestFile, error) {
+	entry := domain.ExportManifestFile{
+		Name:          strings.TrimPrefix(strings.TrimPrefix(file.Path, dir), filesDir),
+		Rows:          file.Rows,
+		MaskingPolicy: file.MaskingPolicy,
+	}
+
+	if err := validateFilesPath(file.Path); err != nil {
+		s.logger.Error("invalid_archive_file_path", zap.String("path", file.Path), zap.Error(err))
+		return entry, err
+	}
+
+	// Open the input file
+	inFile, err := os.Open(file.Path)
+	if err != nil {
+		s.logger.Error(
+			"error_open_file",
+			zap.Error(err),
+		)
+		return entry, err
+	}
+	defer inFile.Close()
+
+	fileInfo, err := inFile.Stat()
+	if err != nil {
+		s.logger.Error(
+			"error_get_file_info",
+			zap.Error(err),
+		)
+		return entry, err
+	}
+
+	hash := sha256.New()
+	if err := archive.Add(entry.Name, fileInfo.Size(), fileInfo.ModTime(), io.TeeReader(inFile, hash)); err != nil {
+		s.logger.Error(
+			"error_copy_file",
+			zap.Error(err),
+		)
+		return entry, err
+	}
+
+	entry.Size = fileInfo.Size()
+	entry.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	return entry, nil
+}
@@ -0,0 +1,195 @@
+package service_test
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"event-registration/internal/common"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"event-registration/internal/core/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+type memoryExportJobRepo struct {
+	jobs map[string]*domain.ExportJob
+
+	leaseMu sync.Mutex
+	// leases maps a claimed job to its owner, leases never expire
+	leases map[string]string
+}
+
+func (r *memoryExportJobRepo) Save(ctx context.Context, job *domain.ExportJob) error {
+	r.jobs[job.ID] = job
+	return nil
+}
+
+func (r *memoryExportJobRepo) FindByID(ctx context.Context, id string) (*domain.ExportJob, error) {
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, domain.ErrExportJobNotFound
+	}
+	return job, nil
+}
+
+func (r *memoryExportJobRepo) FindByUser(ctx context.Context, userID string) ([]*domain.ExportJob, error) {
+	jobs := []*domain.ExportJob{}
+	for _, job := range r.jobs {
+		if job.RequestedBy == userID {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+func (r *memoryExportJobRepo) FindActive(ctx context.Context) ([]*domain.ExportJob, error) {
+	jobs := []*domain.ExportJob{}
+	for _, job := range r.jobs {
+		if job.Status == domain.EXPORT_JOB_QUEUED || job.Status == domain.EXPORT_JOB_RUNNING {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+func (r *memoryExportJobRepo) FindPinned(ctx context.Context) ([]*domain.ExportJob, error) {
+	jobs := []*domain.ExportJob{}
+	for _, job := range r.jobs {
+		if job.Pinned {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+func (r *memoryExportJobRepo) Claim(ctx context.Context, id, owner string, lease time.Duration) error {
+	r.leaseMu.Lock()
+	defer r.leaseMu.Unlock()
+
+	if _, ok := r.leases[id]; ok {
+		return domain.ErrExportJobClaimed
+	}
+
+	if r.leases == nil {
+		r.leases = map[string]string{}
+	}
+	r.leases[id] = owner
+	return nil
+}
+
+func (r *memoryExportJobRepo) Renew(ctx context.Context, id, owner string, lease time.Duration) error {
+	r.leaseMu.Lock()
+	defer r.leaseMu.Unlock()
+
+	if r.leases[id] != owner {
+		return domain.ErrExportJobClaimed
+	}
+	return nil
+}
+
+func (r *memoryExportJobRepo) Release(ctx context.Context, id, owner string) error {
+	r.leaseMu.Lock()
+	defer r.leaseMu.Unlock()
+
+	if r.leases[id] == owner {
+		delete(r.leases, id)
+	}
+	return nil
+}
+
+func TestArchiveExport(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("files", 0o750))
+	require.NoError(t, os.WriteFile("files/REPORT_PART_1.csv", []byte("a,b\n1,2\n"), 0o600))
+	require.NoError(t, os.WriteFile("files/REPORT_PART_2.csv", []byte("a,b\n3,4\n"), 0o600))
+
+	finishedAt := time.Now()
+	repo := &memoryExportJobRepo{jobs: map[string]*domain.ExportJob{
+		"done": {
+			ID:          "done",
+			Type:        domain.EXPORT_TYPE_TRANSAKSI,
+			Status:      domain.EXPORT_JOB_DONE,
+			Request:     &request.RekapRequest{DateStart: "2026/02/01", DateEnd: "2026/02/28"},
+			RequestedBy: "user-1",
+			Files:       []domain.ExportFile{{Path: "files/REPORT_PART_1.csv", Rows: 1}, {Path: "files/REPORT_PART_2.csv", Rows: 1}},
+			FinishedAt:  &finishedAt,
+		},
+		"running": {ID: "running", Status: domain.EXPORT_JOB_RUNNING, RequestedBy: "user-1"},
+		"missing": {
+			ID:          "missing",
+			Status:      domain.EXPORT_JOB_DONE,
+			RequestedBy: "user-1",
+			Files:       []domain.ExportFile{{Path: "files/REPORT_PART_1.csv", Rows: 1}, {Path: "files/GONE.csv", Rows: 1}},
+		},
+	}}
+
+	cfg := &common.Config{ExportDownloadSecret: "secret", ExportDownloadExpiration: time.Minute}
+	s := newExporterService(service.ExporterParams{Jobs: repo, Config: cfg})
+
+	t.Run("zip with manifest", func(t *testing.T) {
+		artifact, err := s.ArchiveExport(context.Background(), "done", "user-1", domain.ARCHIVE_FORMAT_ZIP)
+		require.NoError(t, err)
+		require.Equal(t, "files/done/EXPORT_done.zip", artifact.Path)
+		require.Equal(t, 2, artifact.Rows)
+		require.Len(t, repo.jobs["done"].Archives, 1)
+
+		reader, err := zip.OpenReader(artifact.Path)
+		require.NoError(t, err)
+		defer reader.Close()
+
+		entries := map[string][]byte{}
+		for _, f := range reader.File {
+			rc, err := f.Open()
+			require.NoError(t, err)
+			data, err := io.ReadAll(rc)
+			rc.Close()
+			require.NoError(t, err)
+			entries[f.Name] = data
+		}
+
+		var manifest domain.ExportManifest
+		require.NoError(t, json.Unmarshal(entries["manifest.json"], &manifest))
+		require.Equal(t, "2026/02/01", manifest.Filters.DateStart)
+		require.Len(t, manifest.Files, 2)
+
+		for _, file := range manifest.Files {
+			sum := sha256.Sum256(entries[file.Name])
+			require.Equal(t, hex.EncodeToString(sum[:]), file.SHA256)
+			require.Equal(t, 1, file.Rows)
+		}
+	})
+
+	t.Run("tar.gz", func(t *testing.T) {
+		artifact, err := s.ArchiveExport(context.Background(), "done", "user-1", domain.ARCHIVE_FORMAT_TAR_GZ)
+		require.NoError(t, err)
+		require.FileExists(t, artifact.Path)
+	})
+
+	t.Run("failed archive is removed", func(t *testing.T) {
+		for _, format := range []string{domain.ARCHIVE_FORMAT_ZIP, domain.ARCHIVE_FORMAT_TAR_GZ} {
+			_, err := s.ArchiveExport(context.Background(), "missing", "user-1", format)
+			require.Error(t, err)
+			require.NoFileExists(t, "files/missing/EXPORT_missing."+format)
+		}
+		require.Empty(t, repo.jobs["missing"].Archives)
+	})
+
+	t.Run("job not finished", func(t *testing.T) {
+		_, err := s.ArchiveExport(context.Background(), "running", "user-1", domain.ARCHIVE_FORMAT_ZIP)
+		require.ErrorIs(t, err, service.ErrExportJobNotFinished)
+	})
+
+	t.Run("other user", func(t *testing.T) {
+		_, err := s.ArchiveExport(context.Background(), "done", "user-2", domain.ARCHIVE_FORMAT_ZIP)
+		require.ErrorIs(t, err, domain.ErrExportJobNotFound)
+	})
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"event-registration/internal/common/helper"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const defaultAuditLimit = 50
+
+var ErrExportAuditForbidden = errors.New("export_audit_forbidden")
+
+// recordAudit writes the audit record of a finished job run. A failed write
+// is logged, it does not fail the export.
+func (s *ExporterService) recordAudit(ctx context.Context, job *domain.ExportJob, startedAt, finishedAt time.Time) {
+	audit := newExportAudit(domain.EXPORT_AUDIT_EXPORT, job.Type, job.RequestedBy, job.Request, startedAt, finishedAt)
+	audit.JobID = job.ID
+	audit.Rows = job.RowsWritten
+	audit.Status = job.Status
+	audit.Error = job.Error
+
+	for _, file := range job.Files {
+		audit.Files = append(audit.Files, file.Path)
+
+		if info, err := os.Stat(file.Path); err == nil {
+			audit.Bytes += info.Size()
+		}
+	}
+
+	s.createAudit(ctx, audit)
+}
+
+// recordArchiveAudit writes the audit record of an archive of job, the
+// artifact is nil when archiving failed with err.
+func (s *ExporterService) recordArchiveAudit(ctx context.Context, job *domain.ExportJob, artifact *domain.ExportArtifact, err error, startedAt time.Time) {
+	audit := newExportAudit(domain.EXPORT_AUDIT_ARCHIVE, job.Type, job.RequestedBy, job.Request, startedAt, time.Now())
+	audit.JobID = job.ID
+	audit.Status = domain.EXPORT_JOB_DONE
+
+	if err != nil {
+		audit.Status = domain.EXPORT_JOB_FAILED
+		audit.Error = err.Error()
+	} else {
+		audit.Rows = int64(artifact.Rows)
+		audit.Files = append(audit.Files, artifact.Path)
+		audit.Bytes = artifact.Size
+	}
+
+	s.createAudit(ctx, audit)
+}
+
+// recordEstimateAudit writes the audit record of an estimate, nothing is
+// written to files so only the counted rows are kept.
+func (s *ExporterService) recordEstimateAudit(ctx context.Context, exportType, requestedBy string, req *request.RekapRequest, estimate *domain.ExportEstimate, err error, startedAt time.Time) {
+	audit := newExportAudit(domain.EXPORT_AUDIT_ESTIMATE, exportType, requestedBy, req, startedAt, time.Now())
+	audit.Status = domain.EXPORT_JOB_DONE
+
+	if err != nil {
+		audit.Status = domain.EXPORT_JOB_FAILED
+		audit.Error = err.Error()
+	} else {
+		audit.Rows = estimate.Rows
+	}
+
+	s.createAudit(ctx, audit)
+}
+
+// newExportAudit fills in who asked for which data, shared by every action.
+func newExportAudit(action, exportType, requestedBy string, req *request.RekapRequest, startedAt, finishedAt time.Time) *domain.ExportAudit {
+	audit := &domain.ExportAudit{
+		ID:          helper.GenerateUUID(),
+		Action:      action,
+		ExportType:  exportType,
+		RequestedBy: requestedBy,
+		Request:     req,
+		SourceDB:    domain.EXPORT_SOURCE_DWH,
+		Files:       []string{},
+		DurationMs:  finishedAt.Sub(startedAt).Milliseconds(),
+		StartedAt:   startedAt,
+		FinishedAt:  finishedAt,
+	}
+
+	if req != nil {
+		if req.IsDBPlnMobile {
+			audit.SourceDB = domain.EXPORT_SOURCE_PLNMOBILE
+		}
+		audit.Pusat = req.Pusat
+		audit.Induk = req.Induk
+		audit.Area = req.Area
+		audit.UnitCode = req.UnitCode
+		audit.MaskingPolicy = req.MaskingPolicy
+	}
+
+	return audit
+}
+
+func (s *ExporterService) createAudit(ctx context.Context, audit *domain.ExportAudit) {
+	if s.audits == nil {
+		return
+	}
+
+	if err := s.audits.Create(ctx, audit); err != nil {
+		s.logger.Error(
+			"error_create_export_audit",
+			zap.String("action", audit.Action),
+			zap.String("job_id", audit.JobID),
+			zap.Error(err),
+		)
+	}
+}
+
+// SearchExportAudits pages through the audit trail. Only requesters with one
+// of EXPORT_AUDIT_ROLES may read it.
+func (s *ExporterService) SearchExportAudits(ctx context.Context, req *request.ExportAuditRequest, roles []string) ([]*domain.ExportAudit, int64, error) {
+	if !s.canReadAudit(roles) {
+		return nil, 0, ErrExportAuditForbidden
+	}
+
+	if s.audits == nil {
+		return []*domain.ExportAudit{}, 0, nil
+	}
+
+	filter := domain.ExportAuditFilter{
+		RequestedBy: req.UserID,
+		Unit:        req.Unit,
+		Limit:       req.Limit,
+		Offset:      req.Offset,
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditLimit
+	}
+
+	var err error
+	if len(req.DateStart) > 0 {
+		if filter.From, err = helper.StartDateParser(req.DateStart); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if len(req.DateEnd) > 0 {
+		if filter.To, err = helper.EndDateParser(req.DateEnd); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	audits, total, err := s.audits.Search(ctx, filter)
+	if err != nil {
+		s.logger.Error(
+			"error_search_export_audits",
+			zap.Error(err),
+		)
+		return nil, 0, err
+	}
+
+	return audits, total, nil
+}
+
+func (s *ExporterService) canReadAudit(roles []string) bool {
+	for _, role := range roles {
+		for _, allowed := range s.config.ExportAuditRoles {
+			if strings.EqualFold(role, allowed) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,124 @@
+package service_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"event-registration/internal/common"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"event-registration/internal/core/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+type memoryAuditRepo struct {
+	audits  []*domain.ExportAudit
+	filters []domain.ExportAuditFilter
+}
+
+func (r *memoryAuditRepo) Create(ctx context.Context, audit *domain.ExportAudit) error {
+	r.audits = append(r.audits, audit)
+	return nil
+}
+
+func (r *memoryAuditRepo) Search(ctx context.Context, filter domain.ExportAuditFilter) ([]*domain.ExportAudit, int64, error) {
+	r.filters = append(r.filters, filter)
+	return r.audits, int64(len(r.audits)), nil
+}
+
+func (r *memoryAuditRepo) FindRecentDone(ctx context.Context, exportType string, limit int) ([]*domain.ExportAudit, error) {
+	var result []*domain.ExportAudit
+	for i := len(r.audits) - 1; i >= 0 && len(result) < limit; i-- {
+		audit := r.audits[i]
+		if audit.Action != "" && audit.Action != domain.EXPORT_AUDIT_EXPORT {
+			continue
+		}
+		if audit.ExportType == exportType && audit.Status == domain.EXPORT_JOB_DONE {
+			result = append(result, audit)
+		}
+	}
+	return result, nil
+}
+
+func TestExportAudit(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("files", 0o750))
+
+	jobs := &memoryExportJobRepo{jobs: map[string]*domain.ExportJob{}}
+	audits := &memoryAuditRepo{}
+	cfg := &common.Config{ExportAuditRoles: []string{"admin", "auditor"}}
+
+	s := newExporterService(service.ExporterParams{Repo: &flakyExporterRepo{}, Jobs: jobs, Audits: audits, Config: cfg})
+
+	job := &domain.ExportJob{
+		ID:          "job-1",
+		Type:        domain.EXPORT_TYPE_PELANGGAN,
+		RequestedBy: "user-1",
+		Request:     &request.RekapRequest{Area: "54100", IsDBPlnMobile: true, DateStart: "2026/02/01", DateEnd: "2026/02/28"},
+	}
+	s.RunExportJob(t.Context(), job)
+
+	require.Len(t, audits.audits, 1)
+	audit := audits.audits[0]
+	require.Equal(t, domain.EXPORT_AUDIT_EXPORT, audit.Action)
+	require.Equal(t, "job-1", audit.JobID)
+	require.Equal(t, "user-1", audit.RequestedBy)
+	require.Equal(t, domain.EXPORT_SOURCE_PLNMOBILE, audit.SourceDB)
+	require.Equal(t, "54100", audit.Area)
+	require.Equal(t, domain.EXPORT_JOB_DONE, audit.Status)
+	require.Same(t, job.Request, audit.Request)
+
+	t.Run("search requires an audit role", func(t *testing.T) {
+		_, _, err := s.SearchExportAudits(context.Background(), &request.ExportAuditRequest{}, []string{"operator"})
+		require.ErrorIs(t, err, service.ErrExportAuditForbidden)
+	})
+
+	t.Run("search", func(t *testing.T) {
+		req := &request.ExportAuditRequest{UserID: "user-1", Unit: "54100", DateStart: "2026/02/01", DateEnd: "2026/02/28"}
+		result, total, err := s.SearchExportAudits(context.Background(), req, []string{"Auditor"})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), total)
+		require.Len(t, result, 1)
+
+		filter := audits.filters[len(audits.filters)-1]
+		require.Equal(t, "user-1", filter.RequestedBy)
+		require.Equal(t, "54100", filter.Unit)
+		require.Equal(t, 50, filter.Limit)
+		require.Equal(t, "2026-02-28 23:59:59", filter.To.Format("2006-01-02 15:04:05"))
+	})
+
+	t.Run("archive and estimate", func(t *testing.T) {
+		audits.audits = audits.audits[:1]
+
+		artifact, err := s.ArchiveExport(context.Background(), "job-1", "user-1", domain.ARCHIVE_FORMAT_ZIP)
+		require.NoError(t, err)
+
+		_, err = s.ArchiveExport(context.Background(), "job-1", "user-1", "rar")
+		require.Error(t, err)
+
+		estimate, err := s.EstimateExport(context.Background(), domain.EXPORT_TYPE_PELANGGAN, job.Request, "user-2")
+		require.NoError(t, err)
+
+		require.Len(t, audits.audits, 4)
+
+		archived := audits.audits[1]
+		require.Equal(t, domain.EXPORT_AUDIT_ARCHIVE, archived.Action)
+		require.Equal(t, "job-1", archived.JobID)
+		require.Equal(t, "user-1", archived.RequestedBy)
+		require.Equal(t, domain.EXPORT_JOB_DONE, archived.Status)
+		require.Equal(t, []string{artifact.Path}, archived.Files)
+		require.Equal(t, artifact.Size, archived.Bytes)
+
+		require.Equal(t, domain.EXPORT_AUDIT_ARCHIVE, audits.audits[2].Action)
+		require.Equal(t, domain.EXPORT_JOB_FAILED, audits.audits[2].Status)
+		require.NotEmpty(t, audits.audits[2].Error)
+
+		estimated := audits.audits[3]
+		require.Equal(t, domain.EXPORT_AUDIT_ESTIMATE, estimated.Action)
+		require.Equal(t, "user-2", estimated.RequestedBy)
+		require.Equal(t, "54100", estimated.Area)
+		require.Equal(t, estimate.Rows, estimated.Rows)
+	})
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"go.uber.org/zap"
+)
+
+// cellTimeLayouts are the timestamp formats the databases hand back as text.
+// Values without a zone are already local time.
+var cellTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// Excel number formats of the typed cells.
+var cellNumFmts = map[string]string{
+	cellTypeNumber:   "#,##0.##",
+	cellTypeRupiah:   `"Rp"\ #,##0`,
+	cellTypeDatetime: "dd/mm/yyyy hh:mm:ss",
+}
+
+// exportLocation is the time zone dates are written in, EXPORT_TIMEZONE or
+// WIB when it cannot be loaded.
+func (s *ExporterService) exportLocation() *time.Location {
+	s.locationOnce.Do(func() {
+		location, err := time.LoadLocation(s.config.ExportTimezone)
+		if err != nil || s.config.ExportTimezone == "" {
+			s.logger.Warn(
+				"error_load_export_timezone",
+				zap.String("timezone", s.config.ExportTimezone),
+				zap.Error(err),
+			)
+			location = time.FixedZone("WIB", 7*60*60)
+		}
+		s.location = location
+	})
+
+	return s.location
+}
+
+// cellStyles creates one style per typed column of f, 0 for text columns.
+func cellStyles(f *excelize.File, types []string) ([]int, error) {
+	styles := make([]int, len(types))
+	created := map[string]int{}
+
+	for i, cellType := range types {
+		numFmt, ok := cellNumFmts[cellType]
+		if !ok {
+			continue
+		}
+
+		if _, ok := created[cellType]; !ok {
+			style, err := f.NewStyle(&excelize.Style{CustomNumFmt: &numFmt})
+			if err != nil {
+				return nil, err
+			}
+			created[cellType] = style
+		}
+
+		styles[i] = created[cellType]
+	}
+
+	return styles, nil
+}
+
+// typedCell parses value for a column of cellType. ok is false when value
+// does not parse, the caller then keeps the text.
+func typedCell(cellType, value string, location *time.Location) (cell interface{}, ok bool) {
+	value = strings.TrimSpace(value)
+
+	switch cellType {
+	case cellTypeNumber, cellTypeRupiah:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, false
+		}
+		return number, true
+	case cellTypeDatetime:
+		for _, layout := range cellTimeLayouts {
+			if t, err := time.ParseInLocation(layout, value, location); err == nil {
+				return t.In(location), true
+			}
+		}
+		return nil, false
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,74 @@
+package service_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"event-registration/internal/common"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"event-registration/internal/core/service"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// amountExporterRepo streams one transaksi with a parseable amount and date,
+// and one with neither.
+type amountExporterRepo struct {
+	flakyExporterRepo
+}
+
+func (r *amountExporterRepo) StreamTransaksi(ctx context.Context, req *request.RekapRequest, after *domain.ExportCursor, limit int, fn func(*domain.Transaksi) error) error {
+	if err := fn(&domain.Transaksi{ID: "1", Amount: "1500000", CreatedAt: "2026-02-01T03:04:05Z"}); err != nil {
+		return err
+	}
+	return fn(&domain.Transaksi{ID: "2", Amount: "n/a", CreatedAt: "kemarin"})
+}
+
+func TestExportTypedCells(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("files", 0o750))
+
+	require.NoError(t, os.WriteFile("layouts.yaml", []byte(`
+transaksi:
+  typed:
+    columns:
+      - { field: amount, header: { id: "Nominal" }, type: rupiah }
+      - { field: created_at, header: { id: "Tanggal" }, type: datetime }
+`), 0o600))
+
+	cfg := &common.Config{ExportLayoutsFile: "layouts.yaml", ExportTimezone: "Asia/Jakarta"}
+
+	layouts, err := service.NewExportLayouts(cfg)
+	require.NoError(t, err)
+
+	s := newExporterService(service.ExporterParams{Repo: &amountExporterRepo{}, Layouts: layouts, Config: cfg})
+
+	req := &request.RekapRequest{UnitCode: "54110", DateStart: "2026/02/01", DateEnd: "2026/02/28", Layout: "typed"}
+	require.NoError(t, s.ExportRekapTransaksi(t.Context(), req, nil))
+
+	f, err := excelize.OpenFile("files/UNIT_54110_20260201_20260228.xlsx")
+	require.NoError(t, err)
+	defer f.Close()
+
+	rows, err := f.GetRows("Sheet1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"Rp 1,500,000", "01/02/2026 10:04:05"}, rows[1])
+
+	// values that do not parse stay text
+	require.Equal(t, []string{"n/a", "kemarin"}, rows[2])
+
+	t.Run("unknown type", func(t *testing.T) {
+		require.NoError(t, os.WriteFile("broken.yaml", []byte(`
+transaksi:
+  typed:
+    columns:
+      - { field: amount, header: { id: "Nominal" }, type: currency }
+`), 0o600))
+
+		_, err := service.NewExportLayouts(&common.Config{ExportLayoutsFile: "broken.yaml"})
+		require.ErrorContains(t, err, "unknown type currency")
+	})
+}
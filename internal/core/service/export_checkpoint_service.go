@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// exportRequestHash identifies identical export requests.
+func exportRequestHash(exportType string, req *request.RekapRequest) string {
+	data, _ := json.Marshal(struct {
+		Type    string                `json:"type"`
+		Request *request.RekapRequest `json:"request"`
+	}{exportType, req})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// resumeCheckpoint returns the checkpoint to continue from. Checkpoints are
+// kept per request, so a job picks up the parts left by an earlier,
+// interrupted or failed run of the same request, whether that was this job
+// or an identical one submitted before. Parts another job wrote are moved
+// into this job's folder and replayed into progress. A checkpoint whose row
+// count or layout no longer matches, whose files are gone, or whose job is
+// still running elsewhere is left alone and the export starts over with an
+// empty one. Exports outside a job are not checkpointed.
+func (s *ExporterService) resumeCheckpoint(ctx context.Context, exportType string, req *request.RekapRequest, totalRows int64, totalFiles int, progress *ExportProgress) *domain.ExportCheckpoint {
+	fresh := &domain.ExportCheckpoint{
+		JobID:      progress.jobID(),
+		Hash:       exportRequestHash(exportType, req),
+		TotalRows:  totalRows,
+		TotalFiles: totalFiles,
+		Parts:      []domain.ExportFile{},
+	}
+
+	if s.checkpoints == nil || fresh.JobID == "" {
+		return fresh
+	}
+
+	checkpoint, err := s.checkpoints.Find(ctx, fresh.Hash)
+	if err != nil {
+		if !errors.Is(err, domain.ErrExportCheckpointNotFound) {
+			s.logger.Error(
+				"error_find_checkpoint",
+				zap.String("job_id", fresh.JobID),
+				zap.Error(err),
+			)
+		}
+		return fresh
+	}
+
+	if checkpoint.TotalRows != totalRows || checkpoint.TotalFiles != totalFiles {
+		s.logger.Info(
+			"checkpoint_outdated",
+			zap.String("job_id", fresh.JobID),
+			zap.Int64("checkpoint_rows", checkpoint.TotalRows),
+			zap.Int64("total_rows", totalRows),
+		)
+		return fresh
+	}
+
+	for _, part := range checkpoint.Parts {
+		if info, err := os.Stat(part.Path); err != nil || info.Size() == 0 {
+			s.logger.Info(
+				"checkpoint_part_missing",
+				zap.String("job_id", fresh.JobID),
+				zap.String("path", part.Path),
+			)
+			return fresh
+		}
+	}
+
+	if checkpoint.JobID != fresh.JobID {
+		if !s.adoptCheckpoint(ctx, checkpoint, fresh.JobID, progress.dir()) {
+			return fresh
+		}
+	}
+
+	for _, part := range checkpoint.Parts {
+		progress.AddFile(part.Path, part.Rows)
+		progress.AddRows(part.Rows)
+	}
+
+	s.logger.Info(
+		"export_resumed",
+		zap.String("job_id", fresh.JobID),
+		zap.Int("completed_parts", len(checkpoint.Parts)),
+		zap.Int("total_files", totalFiles),
+	)
+
+	return checkpoint
+}
+
+// adoptCheckpoint hands the checkpoint of an earlier job with the same
+// request to jobID, moving its parts into dir. It refuses while the earlier
+// job is still queued or running.
+func (s *ExporterService) adoptCheckpoint(ctx context.Context, checkpoint *domain.ExportCheckpoint, jobID, dir string) bool {
+	previous, err := s.jobs.FindByID(ctx, checkpoint.JobID)
+	if err == nil && (previous.Status == domain.EXPORT_JOB_QUEUED || previous.Status == domain.EXPORT_JOB_RUNNING) {
+		s.logger.Info(
+			"checkpoint_in_use",
+			zap.String("job_id", jobID),
+			zap.String("checkpoint_job_id", checkpoint.JobID),
+		)
+		return false
+	}
+
+	previousDir := exportJobDir(checkpoint.JobID)
+	for i, part := range checkpoint.Parts {
+		path := dir + strings.TrimPrefix(part.Path, previousDir)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			return false
+		}
+
+		if err := os.Rename(part.Path, path); err != nil {
+			s.logger.Error(
+				"error_move_checkpoint_part",
+				zap.String("job_id", jobID),
+				zap.String("path", part.Path),
+				zap.Error(err),
+			)
+			return false
+		}
+
+		checkpoint.Parts[i].Path = path
+	}
+
+	s.logger.Info(
+		"checkpoint_adopted",
+		zap.String("job_id", jobID),
+		zap.String("checkpoint_job_id", checkpoint.JobID),
+	)
+
+	checkpoint.JobID = jobID
+	s.saveCheckpoint(ctx, checkpoint)
+
+	return true
+}
+
+// saveCheckpoint records a finished part, even while the export is being
+// cancelled.
+func (s *ExporterService) saveCheckpoint(ctx context.Context, checkpoint *domain.ExportCheckpoint) {
+	if s.checkpoints == nil || checkpoint.JobID == "" {
+		return
+	}
+
+	checkpoint.UpdatedAt = time.Now()
+
+	if err := s.checkpoints.Save(context.WithoutCancel(ctx), checkpoint); err != nil {
+		s.logger.Error(
+			"error_save_checkpoint",
+			zap.String("job_id", checkpoint.JobID),
+			zap.Error(err),
+		)
+	}
+}
+
+// deleteCheckpoint drops the checkpoint of a job that completed, its request
+// has nothing left to resume. Failed and cancelled jobs keep theirs for the
+// next identical request until it expires.
+func (s *ExporterService) deleteCheckpoint(ctx context.Context, job *domain.ExportJob) {
+	if s.checkpoints == nil {
+		return
+	}
+
+	if err := s.checkpoints.Delete(ctx, exportRequestHash(job.Type, job.Request)); err != nil {
+		s.logger.Error(
+			"error_delete_checkpoint",
+			zap.String("job_id", job.ID),
+			zap.Error(err),
+		)
+	}
+}
+
+// ResumeExportJobs restarts the jobs a previous exporter process left queued
+// or running. Each job is claimed first, jobs another replica is running keep
+// their lease and are skipped. Exports with a checkpoint continue after their
+// last saved part. With an export queue the workers resume them from their
+// unacknowledged messages instead.
+func (s *ExporterService) ResumeExportJobs(ctx context.Context) error {
+	if s.queue != nil {
+		return nil
+	}
+
+	jobs, err := s.jobs.FindActive(ctx)
+	if err != nil {
+		s.logger.Error(
+			"error_find_active_export_jobs",
+			zap.Error(err),
+		)
+		return err
+	}
+
+	for _, active := range jobs {
+		runCtx, release, err := s.claimExportJob(context.WithoutCancel(ctx), active.ID)
+		if errors.Is(err, domain.ErrExportJobClaimed) {
+			continue
+		}
+		if err != nil {
+			s.logger.Error(
+				"error_claim_export_job",
+				zap.String("job_id", active.ID),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		// the job may have finished between listing and claiming it
+		job, err := s.findExportJob(runCtx, active.ID)
+		if err != nil || (job.Status != domain.EXPORT_JOB_QUEUED && job.Status != domain.EXPORT_JOB_RUNNING) {
+			release()
+			continue
+		}
+
+		resetExportJob(job)
+
+		s.logger.Info(
+			"export_job_resumed",
+			zap.String("job_id", job.ID),
+			zap.String("type", job.Type),
+		)
+
+		go func() {
+			defer release()
+			s.runClaimed(runCtx, job)
+		}()
+	}
+
+	return nil
+}
+
+// resetExportJob clears the progress of an interrupted run, the next run
+// replays the parts its checkpoint holds.
+func resetExportJob(job *domain.ExportJob) {
+	job.Status = domain.EXPORT_JOB_QUEUED
+	job.TotalRows = 0
+	job.RowsWritten = 0
+	job.Files = []domain.ExportFile{}
+	job.Report = nil
+	job.Reconciliation = nil
+	job.Error = ""
+}
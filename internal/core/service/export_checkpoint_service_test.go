@@ -0,0 +1,166 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"event-registration/internal/common"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"event-registration/internal/core/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+type memoryCheckpointRepo struct {
+	checkpoints map[string]*domain.ExportCheckpoint
+}
+
+func (r *memoryCheckpointRepo) Find(ctx context.Context, hash string) (*domain.ExportCheckpoint, error) {
+	checkpoint, ok := r.checkpoints[hash]
+	if !ok {
+		return nil, domain.ErrExportCheckpointNotFound
+	}
+	return checkpoint, nil
+}
+
+func (r *memoryCheckpointRepo) Save(ctx context.Context, checkpoint *domain.ExportCheckpoint) error {
+	r.checkpoints[checkpoint.Hash] = checkpoint
+	return nil
+}
+
+func (r *memoryCheckpointRepo) Delete(ctx context.Context, hash string) error {
+	delete(r.checkpoints, hash)
+	return nil
+}
+
+// byJob returns the checkpoint whose parts are in the folder of job id.
+func (r *memoryCheckpointRepo) byJob(id string) *domain.ExportCheckpoint {
+	for _, checkpoint := range r.checkpoints {
+		if checkpoint.JobID == id {
+			return checkpoint
+		}
+	}
+	return nil
+}
+
+// cursorRecordingRepo counts 150000 transaksi rows but only streams a few,
+// recording the cursor of every StreamTransaksi call.
+type cursorRecordingRepo struct {
+	flakyExporterRepo
+	cursors []*domain.ExportCursor
+}
+
+func (r *cursorRecordingRepo) CountTransaksi(ctx context.Context, req *request.RekapRequest) (int64, error) {
+	return 150000, nil
+}
+
+func (r *cursorRecordingRepo) StreamTransaksi(ctx context.Context, req *request.RekapRequest, after *domain.ExportCursor, limit int, fn func(*domain.Transaksi) error) error {
+	r.cursors = append(r.cursors, after)
+	return fn(&domain.Transaksi{ID: "150000", CreatedAt: "2026-02-28 23:59:59"})
+}
+
+// failingPartRepo fails the export when asked for the second part.
+type failingPartRepo struct {
+	cursorRecordingRepo
+	fail bool
+}
+
+func (r *failingPartRepo) StreamTransaksi(ctx context.Context, req *request.RekapRequest, after *domain.ExportCursor, limit int, fn func(*domain.Transaksi) error) error {
+	if r.fail && after != nil {
+		return errors.New("connection reset by peer")
+	}
+	return r.cursorRecordingRepo.StreamTransaksi(ctx, req, after, limit, fn)
+}
+
+func TestExportCheckpointResumesIdenticalRequest(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("files", 0o750))
+
+	repo := &failingPartRepo{fail: true}
+	jobs := &memoryExportJobRepo{jobs: map[string]*domain.ExportJob{}}
+	checkpoints := &memoryCheckpointRepo{checkpoints: map[string]*domain.ExportCheckpoint{}}
+
+	s := newExporterService(service.ExporterParams{Repo: repo, Jobs: jobs, Checkpoints: checkpoints, Config: &common.Config{ExportRetryAttempts: 1}})
+
+	run := func(id string, req *request.RekapRequest) *domain.ExportJob {
+		job := &domain.ExportJob{ID: id, Type: domain.EXPORT_TYPE_TRANSAKSI, Status: domain.EXPORT_JOB_QUEUED, Request: req, RequestedBy: "user-1"}
+		jobs.jobs[job.ID] = job
+		s.RunExportJob(t.Context(), job)
+		return job
+	}
+
+	newRequest := func(unit string) *request.RekapRequest {
+		return &request.RekapRequest{UnitCode: unit, DateStart: "2026/02/01", DateEnd: "2026/02/28"}
+	}
+
+	// the first job fails after part 1 of 2 and keeps its checkpoint
+	failed := run("job-1", newRequest("54110"))
+	require.Equal(t, domain.EXPORT_JOB_FAILED, failed.Status)
+	require.FileExists(t, "files/job-1/UNIT_54110_20260201_20260228_PART_1.xlsx")
+	checkpoint := checkpoints.byJob("job-1")
+	require.NotNil(t, checkpoint)
+	require.Len(t, checkpoint.Parts, 1)
+	cursor := checkpoint.Cursor
+
+	t.Run("a different request starts over", func(t *testing.T) {
+		streamed := len(repo.cursors)
+		run("job-2", newRequest("54111"))
+
+		require.Nil(t, repo.cursors[streamed])
+		require.Len(t, checkpoints.checkpoints, 2)
+	})
+
+	t.Run("resubmitted request resumes", func(t *testing.T) {
+		repo.fail = false
+		streamed := len(repo.cursors)
+
+		job := run("job-3", newRequest("54110"))
+
+		// only part 2 is read, part 1 moves to the new job's folder
+		require.Equal(t, domain.EXPORT_JOB_DONE, job.Status)
+		require.Equal(t, []*domain.ExportCursor{cursor}, repo.cursors[streamed:])
+		require.Equal(t, "files/job-3/UNIT_54110_20260201_20260228_PART_1.xlsx", job.Files[0].Path)
+		require.FileExists(t, job.Files[0].Path)
+		require.NoFileExists(t, "files/job-1/UNIT_54110_20260201_20260228_PART_1.xlsx")
+		require.Nil(t, checkpoints.byJob("job-1"))
+		require.Nil(t, checkpoints.byJob("job-3"))
+	})
+
+	t.Run("exports outside a job are not checkpointed", func(t *testing.T) {
+		before := len(checkpoints.checkpoints)
+		require.NoError(t, s.ExportRekapTransaksi(t.Context(), newRequest("54112"), nil))
+		require.Len(t, checkpoints.checkpoints, before)
+	})
+}
+
+func TestResumeExportJobsClaimsJobs(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("files", 0o750))
+
+	req := &request.RekapRequest{UnitCode: "54110", DateStart: "2026/02/01", DateEnd: "2026/02/28"}
+	jobs := &memoryExportJobRepo{jobs: map[string]*domain.ExportJob{
+		"left":  {ID: "left", Type: domain.EXPORT_TYPE_TRANSAKSI, Status: domain.EXPORT_JOB_RUNNING, Request: req, RowsWritten: 10},
+		"owned": {ID: "owned", Type: domain.EXPORT_TYPE_TRANSAKSI, Status: domain.EXPORT_JOB_RUNNING, Request: req, RowsWritten: 10},
+	}}
+	require.NoError(t, jobs.Claim(context.Background(), "owned", "other-replica", time.Minute))
+
+	s := newExporterService(service.ExporterParams{Repo: &cursorRecordingRepo{}, Jobs: jobs})
+	require.NoError(t, s.ResumeExportJobs(context.Background()))
+
+	require.Eventually(t, func() bool {
+		jobs.leaseMu.Lock()
+		defer jobs.leaseMu.Unlock()
+		_, running := jobs.leases["left"]
+		return !running
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.Equal(t, domain.EXPORT_JOB_DONE, jobs.jobs["left"].Status)
+
+	// the job another replica runs is not reset
+	require.Equal(t, domain.EXPORT_JOB_RUNNING, jobs.jobs["owned"].Status)
+	require.Equal(t, int64(10), jobs.jobs["owned"].RowsWritten)
+}
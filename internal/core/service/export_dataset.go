@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+var ErrUnknownExportType = errors.New("unknown_export_type")
+
+type transaksiDataset struct {
+	repo domain.ExporterRepository
+}
+
+func (d *transaksiDataset) Name() string {
+	return layoutDatasetTransaksi
+}
+
+func (d *transaksiDataset) RowType() reflect.Type {
+	return reflect.TypeOf(domain.Transaksi{})
+}
+
+func (d *transaksiDataset) Count(ctx context.Context, req *request.RekapRequest) (int64, error) {
+	return d.repo.CountTransaksi(ctx, req)
+}
+
+func (d *transaksiDataset) Stream(ctx context.Context, req *request.RekapRequest, after *domain.ExportCursor, limit int, fn func(row interface{}, cursor domain.ExportCursor) error) error {
+	return d.repo.StreamTransaksi(ctx, req, after, limit, func(row *domain.Transaksi) error {
+		return fn(row, domain.ExportCursor{CreatedAt: row.CreatedAt, ID: row.ID})
+	})
+}
+
+func (d *transaksiDataset) Summarize(ctx context.Context, req *request.RekapRequest) ([]*domain.TransaksiSummary, error) {
+	return d.repo.SummarizeTransaksi(ctx, req)
+}
+
+type pelangganDataset struct {
+	repo domain.ExporterRepository
+}
+
+func (d *pelangganDataset) Name() string {
+	return layoutDatasetPelanggan
+}
+
+func (d *pelangganDataset) RowType() reflect.Type {
+	return reflect.TypeOf(domain.Pelanggan{})
+}
+
+func (d *pelangganDataset) Count(ctx context.Context, req *request.RekapRequest) (int64, error) {
+	return d.repo.CountPelanggan(ctx, req)
+}
+
+func (d *pelangganDataset) Stream(ctx context.Context, req *request.RekapRequest, after *domain.ExportCursor, limit int, fn func(row interface{}, cursor domain.ExportCursor) error) error {
+	return d.repo.StreamPelanggan(ctx, req, after, limit, func(row *domain.Pelanggan) error {
+		return fn(row, domain.ExportCursor{CreatedAt: row.CreatedAt, ID: row.ID})
+	})
+}
+
+// summarizedDataset is implemented by datasets whose xlsx files get a
+// "Ringkasan" sheet.
+type summarizedDataset interface {
+	Summarize(ctx context.Context, req *request.RekapRequest) ([]*domain.TransaksiSummary, error)
+}
+
+// RegisterDataset makes dataset exportable as exportType through
+// ExportDataset and EnqueueExport. Its layouts are checked right away, a
+// dataset without any layout gets every field of its row type. Datasets must
+// be registered before exports run.
+func (s *ExporterService) RegisterDataset(exportType string, dataset domain.ExportDataset) error {
+	if err := s.exportLayouts().validate(dataset.Name(), dataset.RowType()); err != nil {
+		return err
+	}
+
+	s.datasets[exportType] = dataset
+
+	return nil
+}
+
+func (s *ExporterService) dataset(exportType string) (domain.ExportDataset, error) {
+	dataset, ok := s.datasets[exportType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownExportType, exportType)
+	}
+
+	return dataset, nil
+}
+
+func (s *ExporterService) countDataset(ctx context.Context, dataset domain.ExportDataset, req *request.RekapRequest) (int64, error) {
+	count, err := dataset.Count(ctx, req)
+	if err != nil {
+		s.logger.Error(
+			"error_count_dataset",
+			zap.String("dataset", dataset.Name()),
+			zap.Error(err),
+		)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// ExportDataset writes every row of a registered export type matching req to
+// the job folder, one file per part for xlsx and a single file otherwise.
+func (s *ExporterService) ExportDataset(ctx context.Context, exportType string, req *request.RekapRequest, progress *ExportProgress) error {
+	dataset, err := s.dataset(exportType)
+	if err != nil {
+		return err
+	}
+
+	dir := progress.dir()
+	name := strings.ToUpper(dataset.Name())
+	base := exportBaseFilename(req)
+	format := exportFormat(req.Format)
+
+	return s.exportDataset(ctx, &datasetExport{
+		exportType:  exportType,
+		req:         req,
+		rowsPerFile: datasetBatchSize,
+		path: func(part, parts int) string {
+			if splitsFiles(format) {
+				return fmt.Sprintf("%sREKAP_%s_EXPORT_%s_PART_%d.%s", dir, name, base, part, format)
+			}
+			return fmt.Sprintf("%sREKAP_%s_EXPORT_%s.%s", dir, name, base, format)
+		},
+		sheetName: "Rekap " + strings.ToUpper(name[:1]) + strings.ToLower(name[1:]),
+		resumable: true,
+	}, progress)
+}
+
+// exportBaseFilename names the files of req after the narrowest unit it
+// filters on and its period.
+func exportBaseFilename(req *request.RekapRequest) string {
+	tanggal := strings.ReplaceAll(req.DateStart+"_"+req.DateEnd, "/", "")
+
+	if len(req.Induk) > 0 {
+		return "INDUK_" + req.Induk + "_" + tanggal
+	} else if len(req.Area) > 0 {
+		return "AREA_" + req.Area + "_" + tanggal
+	} else if len(req.UnitCode) > 0 {
+		return "UNIT_" + req.UnitCode + "_" + tanggal
+	} else if len(req.Pusat) > 0 {
+		return "PUSAT_" + req.Pusat + "_" + tanggal
+	}
+
+	return "NASIONAL" + "_" + tanggal
+}
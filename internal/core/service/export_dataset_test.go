@@ -0,0 +1,92 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"event-registration/internal/core/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+type registrasi struct {
+	ID        string `json:"id"`
+	Email     string `json:"email"`
+	CreatedAt string `json:"created_at"`
+}
+
+// registrasiDataset holds its rows in memory, in keyset order.
+type registrasiDataset struct {
+	rows []registrasi
+}
+
+func (d *registrasiDataset) Name() string {
+	return "registrasi"
+}
+
+func (d *registrasiDataset) RowType() reflect.Type {
+	return reflect.TypeOf(registrasi{})
+}
+
+func (d *registrasiDataset) Count(ctx context.Context, req *request.RekapRequest) (int64, error) {
+	return int64(len(d.rows)), nil
+}
+
+func (d *registrasiDataset) Stream(ctx context.Context, req *request.RekapRequest, after *domain.ExportCursor, limit int, fn func(row interface{}, cursor domain.ExportCursor) error) error {
+	for i := range d.rows {
+		row := &d.rows[i]
+		if after != nil && row.ID <= after.ID {
+			continue
+		}
+		if limit == 0 {
+			return nil
+		}
+		limit--
+
+		if err := fn(row, domain.ExportCursor{CreatedAt: row.CreatedAt, ID: row.ID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRegisterDataset(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("files", 0o750))
+
+	dataset := &registrasiDataset{}
+	for i := 1; i <= 3; i++ {
+		dataset.rows = append(dataset.rows, registrasi{
+			ID:        fmt.Sprintf("%d", i),
+			Email:     fmt.Sprintf("user%d@pln.co.id", i),
+			CreatedAt: "2026-02-01 00:00:00",
+		})
+	}
+
+	s := newExporterService(service.ExporterParams{})
+	require.NoError(t, s.RegisterDataset("registrasi", dataset))
+
+	req := &request.RekapRequest{DateStart: "2026/02/01", DateEnd: "2026/02/28", Format: "csv"}
+	require.NoError(t, s.ExportDataset(t.Context(), "registrasi", req, nil))
+
+	data, err := os.ReadFile("files/REKAP_REGISTRASI_EXPORT_NASIONAL_20260201_20260228.csv")
+	require.NoError(t, err)
+	require.Equal(t, "id,email,created_at\n"+
+		"1,user1@pln.co.id,2026-02-01 00:00:00\n"+
+		"2,user2@pln.co.id,2026-02-01 00:00:00\n"+
+		"3,user3@pln.co.id,2026-02-01 00:00:00\n", string(data))
+
+	t.Run("unknown export type", func(t *testing.T) {
+		require.ErrorIs(t, s.ExportDataset(t.Context(), "pengguna", req, nil), service.ErrUnknownExportType)
+	})
+
+	t.Run("unknown layout", func(t *testing.T) {
+		req := &request.RekapRequest{DateStart: "2026/02/01", DateEnd: "2026/02/28", Layout: "compact"}
+		require.ErrorIs(t, s.ExportDataset(t.Context(), "registrasi", req, nil), service.ErrUnknownLayout)
+	})
+}
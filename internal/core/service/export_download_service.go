@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"event-registration/internal/common"
+	"event-registration/internal/core/domain"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrDownloadExpired          = errors.New("download_link_expired")
+	ErrDownloadInvalidSignature = errors.New("invalid_download_signature")
+	ErrDownloadSecretNotSet     = errors.New("EXPORT_DOWNLOAD_SECRET is not set")
+)
+
+// ListExportArtifacts returns every file generated by the user's jobs that is
+// still on disk, each with a signed download URL.
+func (s *ExporterService) ListExportArtifacts(ctx context.Context, userID string) ([]*domain.ExportArtifact, error) {
+	jobs, err := s.jobs.FindByUser(ctx, userID)
+	if err != nil {
+		s.logger.Error(
+			"error_find_user_export_jobs",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(s.config.ExportDownloadExpiration)
+	artifacts := []*domain.ExportArtifact{}
+
+	for _, job := range jobs {
+		files := append(append([]domain.ExportFile{}, job.Files...), job.Archives...)
+		for _, file := range files {
+			info, err := os.Stat(file.Path)
+			if err != nil {
+				// file removed from disk, nothing left to serve
+				continue
+			}
+
+			artifacts = append(artifacts, &domain.ExportArtifact{
+				JobID:     job.ID,
+				Path:      file.Path,
+				Rows:      file.Rows,
+				Size:      info.Size(),
+				URL:       s.SignDownloadURL(file.Path, expiresAt),
+				ExpiresAt: expiresAt,
+			})
+		}
+	}
+
+	return artifacts, nil
+}
+
+// RequireDownloadSecret stops a process that signs or serves download links
+// from starting without its own EXPORT_DOWNLOAD_SECRET.
+func RequireDownloadSecret(config *common.Config) error {
+	if len(config.ExportDownloadSecret) == 0 {
+		return ErrDownloadSecretNotSet
+	}
+
+	return nil
+}
+
+// SignDownloadURL builds a relative URL to the download endpoint that is only
+// valid until expiresAt.
+func (s *ExporterService) SignDownloadURL(path string, expiresAt time.Time) string {
+	expires := expiresAt.Unix()
+
+	query := url.Values{}
+	query.Set("path", path)
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", s.downloadSignature(path, expires))
+
+	return "/exports/download?" + query.Encode()
+}
+
+// ResolveDownload verifies a signed download link and returns the file path
+// to serve.
+func (s *ExporterService) ResolveDownload(path string, expires int64, signature string) (string, error) {
+	if time.Now().Unix() > expires {
+		return "", ErrDownloadExpired
+	}
+
+	// a link signed with an empty key proves nothing
+	if len(s.config.ExportDownloadSecret) == 0 {
+		return "", ErrDownloadSecretNotSet
+	}
+
+	expected := s.downloadSignature(path, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		s.logger.Warn(
+			"invalid_download_signature",
+			zap.String("path", path),
+		)
+		return "", ErrDownloadInvalidSignature
+	}
+
+	if err := validateFilesPath(path); err != nil {
+		s.logger.Error("invalid_download_path", zap.String("path", path), zap.Error(err))
+		return "", err
+	}
+
+	info, err := os.Stat(filepath.Clean(path))
+	if err != nil {
+		s.logger.Error("error_stat_download_file", zap.String("path", path), zap.Error(err))
+		return "", err
+	}
+
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("not a regular file: %s", path)
+	}
+
+	return filepath.Clean(path), nil
+}
+
+func (s *ExporterService) downloadSignature(path string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(s.config.ExportDownloadSecret))
+	mac.Write([]byte(path + "|" + strconv.FormatInt(expires, 10)))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,83 @@
+package service_test
+
+import (
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"event-registration/internal/common"
+	"event-registration/internal/core/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newDownloadTestService(t *testing.T) *service.ExporterService {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("files", 0o750))
+	require.NoError(t, os.WriteFile("files/REPORT.xlsx", []byte("xlsx"), 0o600))
+
+	cfg := &common.Config{ExportDownloadSecret: "secret", ExportDownloadExpiration: time.Minute}
+	return newExporterService(service.ExporterParams{Config: cfg})
+}
+
+func parseSignedURL(t *testing.T, signed string) (path string, expires int64, signature string) {
+	u, err := url.Parse(signed)
+	require.NoError(t, err)
+	require.Equal(t, "/exports/download", u.Path)
+
+	expires, err = strconv.ParseInt(u.Query().Get("expires"), 10, 64)
+	require.NoError(t, err)
+
+	return u.Query().Get("path"), expires, u.Query().Get("signature")
+}
+
+func TestResolveDownload(t *testing.T) {
+	s := newDownloadTestService(t)
+
+	t.Run("valid signature", func(t *testing.T) {
+		path, expires, signature := parseSignedURL(t, s.SignDownloadURL("files/REPORT.xlsx", time.Now().Add(time.Minute)))
+
+		resolved, err := s.ResolveDownload(path, expires, signature)
+		require.NoError(t, err)
+		require.Equal(t, "files/REPORT.xlsx", resolved)
+	})
+
+	t.Run("expired link", func(t *testing.T) {
+		path, expires, signature := parseSignedURL(t, s.SignDownloadURL("files/REPORT.xlsx", time.Now().Add(-time.Minute)))
+
+		_, err := s.ResolveDownload(path, expires, signature)
+		require.ErrorIs(t, err, service.ErrDownloadExpired)
+	})
+
+	t.Run("tampered path", func(t *testing.T) {
+		_, expires, signature := parseSignedURL(t, s.SignDownloadURL("files/REPORT.xlsx", time.Now().Add(time.Minute)))
+
+		_, err := s.ResolveDownload("files/OTHER.xlsx", expires, signature)
+		require.ErrorIs(t, err, service.ErrDownloadInvalidSignature)
+	})
+
+	t.Run("no download secret", func(t *testing.T) {
+		cfg := &common.Config{JwtSecret: "jwt-secret", ExportDownloadExpiration: time.Minute}
+		require.ErrorIs(t, service.RequireDownloadSecret(cfg), service.ErrDownloadSecretNotSet)
+
+		// links are not signed with the JWT secret instead
+		unset := newExporterService(service.ExporterParams{Config: cfg})
+		path, expires, signature := parseSignedURL(t, unset.SignDownloadURL("files/REPORT.xlsx", time.Now().Add(time.Minute)))
+		_, err := unset.ResolveDownload(path, expires, signature)
+		require.ErrorIs(t, err, service.ErrDownloadSecretNotSet)
+
+		jwtSigned := newExporterService(service.ExporterParams{Config: &common.Config{ExportDownloadSecret: "jwt-secret"}})
+		path, expires, signature = parseSignedURL(t, jwtSigned.SignDownloadURL("files/REPORT.xlsx", time.Now().Add(time.Minute)))
+		_, err = s.ResolveDownload(path, expires, signature)
+		require.ErrorIs(t, err, service.ErrDownloadInvalidSignature)
+	})
+
+	t.Run("signed path outside files dir", func(t *testing.T) {
+		path, expires, signature := parseSignedURL(t, s.SignDownloadURL("files/../go.mod", time.Now().Add(time.Minute)))
+
+		_, err := s.ResolveDownload(path, expires, signature)
+		require.Error(t, err)
+	})
+}
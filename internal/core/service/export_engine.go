@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+
+	"go.uber.org/zap"
+)
+
+// datasetBatchSize is how many rows a single database read streams.
+const datasetBatchSize = 25_000 * 6
+
+// datasetExport describes the files writeDataset produces for one request.
+type datasetExport struct {
+	exportType string
+	req        *request.RekapRequest
+	// total is the row count the parts are planned from
+	total int64
+	// rowsPerFile caps every xlsx part, other formats get a single file
+	rowsPerFile int
+	// path returns the file of part, counted from 1, out of parts
+	path      func(part, parts int) string
+	sheetName string
+	// resumable exports save a checkpoint after every part
+	resumable bool
+}
+
+func (e *datasetExport) parts() int {
+	if e.total <= 0 {
+		return 0
+	}
+
+	if !splitsFiles(e.req.Format) {
+		return 1
+	}
+
+	return (int(e.total) + e.rowsPerFile - 1) / e.rowsPerFile
+}
+
+// exportDataset counts the rows of e, records them as the job total and
+// writes them.
+func (s *ExporterService) exportDataset(ctx context.Context, e *datasetExport, progress *ExportProgress) error {
+	dataset, err := s.dataset(e.exportType)
+	if err != nil {
+		return err
+	}
+
+	e.total, err = s.countDataset(ctx, dataset, e.req)
+	if err != nil {
+		return err
+	}
+
+	progress.SetTotal(e.total)
+
+	s.logger.Info(
+		"total_rows_to_export",
+		zap.String("type", e.exportType),
+		zap.Int64("total_rows", e.total),
+		zap.Int("total_files", e.parts()),
+	)
+
+	if e.total == 0 {
+		s.logger.Info("no_data_to_export")
+		return nil
+	}
+
+	files, _, err := s.writeDataset(ctx, e, progress)
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info(
+		"export_completed",
+		zap.String("type", e.exportType),
+		zap.Int64("total_rows", e.total),
+		zap.Strings("generated_files", files),
+	)
+
+	return nil
+}
+
+// writeDataset is the export engine shared by every dataset. It streams the
+// rows of e into its parts, keyset page by keyset page, and returns the files
+// it saved and every row it wrote, including those of a part that failed
+// halfway.
+func (s *ExporterService) writeDataset(ctx context.Context, e *datasetExport, progress *ExportProgress) (files []string, rowsWritten int, err error) {
+	dataset, err := s.dataset(e.exportType)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	layout, err := s.exportLayout(e.exportType, e.req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	parts := e.parts()
+	if parts == 0 {
+		return nil, 0, nil
+	}
+
+	summary, err := s.datasetSummary(ctx, dataset, e.req, parts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Pick up after the parts an earlier run of the same request saved
+	checkpoint := &domain.ExportCheckpoint{}
+	if e.resumable {
+		checkpoint = s.resumeCheckpoint(ctx, e.exportType, e.req, e.total, parts, progress)
+	}
+
+	for _, part := range checkpoint.Parts {
+		files = append(files, part.Path)
+	}
+
+	// Keyset position of the last written row, carried across parts
+	cursor := checkpoint.Cursor
+
+	opts := writerOptions{
+		sheetName:     e.sheetName,
+		colWidths:     layout.widths,
+		colTypes:      layout.types,
+		maskingPolicy: layout.maskingPolicy,
+		summary:       summary,
+	}
+
+	for part := len(checkpoint.Parts); part < parts; part++ {
+		limit := int(e.total) - part*e.rowsPerFile
+		if parts > 1 && limit > e.rowsPerFile {
+			limit = e.rowsPerFile
+		}
+
+		path := e.path(part+1, parts)
+
+		s.logger.Info(
+			"creating_file",
+			zap.Int("file_number", part+1),
+			zap.Int("total_files", parts),
+			zap.Int("rows_for_this_file", limit),
+			zap.Any("cursor", cursor),
+		)
+
+		rows, last, err := s.writeDatasetPart(ctx, dataset, e.req, layout, path, opts, cursor, limit, progress)
+		rowsWritten += rows
+		if err != nil {
+			return files, rowsWritten, err
+		}
+
+		cursor = last
+		files = append(files, path)
+		progress.AddFile(path, rows)
+
+		if e.resumable {
+			checkpoint.Parts = append(checkpoint.Parts, domain.ExportFile{Path: path, Rows: rows})
+			checkpoint.Cursor = cursor
+			s.saveCheckpoint(ctx, checkpoint)
+		}
+
+		s.logger.Info(
+			"file_saved",
+			zap.Int("file_number", part+1),
+			zap.String("filepath", path),
+			zap.Int("rows_in_file", rows),
+		)
+
+		if part+1 < parts && draining(ctx) {
+			return files, rowsWritten, domain.ErrExportInterrupted
+		}
+	}
+
+	return files, rowsWritten, nil
+}
+
+// writeDatasetPart writes up to limit rows after cursor into the file at
+// path and returns the cursor of its last row.
+func (s *ExporterService) writeDatasetPart(ctx context.Context, dataset domain.ExportDataset, req *request.RekapRequest, layout *exportLayout, path string, opts writerOptions, cursor *domain.ExportCursor, limit int, progress *ExportProgress) (rows int, last *domain.ExportCursor, err error) {
+	w, err := s.newRowWriter(req.Format, path, opts)
+	if err != nil {
+		s.logger.Error("error_create_writer", zap.Error(err))
+		return 0, cursor, err
+	}
+
+	if err := w.WriteHeader(layout.headers); err != nil {
+		w.Close()
+		s.logger.Error("error_set_headers", zap.Error(err))
+		return 0, cursor, err
+	}
+
+	last = cursor
+	rowIndex := 0
+	for rows < limit {
+		batch := min(datasetBatchSize, limit-rows)
+
+		// Stream this batch straight from the database into the writer
+		written, err := s.streamRows(ctx, path, w, progress, func(emit rowEmitter) error {
+			return dataset.Stream(ctx, req, last, batch, func(row interface{}, position domain.ExportCursor) error {
+				last = &position
+				rowIndex++
+				return emit(layout.row(rowIndex, row))
+			})
+		})
+		rows += written
+		if err != nil {
+			w.Close()
+			s.logger.Error(
+				"error_stream_file",
+				zap.String("filepath", path),
+				zap.Error(err),
+			)
+			return rows, last, err
+		}
+
+		// the dataset ran out before its count, rows were removed meanwhile
+		if written < batch {
+			break
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		s.logger.Error(
+			"error_save_file",
+			zap.String("filepath", path),
+			zap.Error(err),
+		)
+		return rows, last, err
+	}
+
+	return rows, last, nil
+}
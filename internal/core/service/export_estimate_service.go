@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// estimateHistoryRuns is how many past runs an estimate learns from.
+const estimateHistoryRuns = 20
+
+// estimateBytesPerRow is used for formats no past run has measured yet.
+var estimateBytesPerRow = map[string]float64{
+	domain.EXPORT_FORMAT_XLSX:   110,
+	domain.EXPORT_FORMAT_CSV:    180,
+	domain.EXPORT_FORMAT_NDJSON: 420,
+}
+
+// EstimateExport counts the rows req would export and sizes the files they
+// would make, without writing anything. Units of a transaksi_all export are
+// split on their own, its file count is a lower bound. Estimates are audited
+// like exports, they reveal how much data matches the filters.
+func (s *ExporterService) EstimateExport(ctx context.Context, exportType string, req *request.RekapRequest, requestedBy string) (estimate *domain.ExportEstimate, err error) {
+	startedAt := time.Now()
+	defer func() {
+		s.recordEstimateAudit(context.WithoutCancel(ctx), exportType, requestedBy, req, estimate, err, startedAt)
+	}()
+
+	if _, err := s.exportLayout(exportType, req); err != nil {
+		return nil, err
+	}
+
+	dataset, err := s.dataset(exportType)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := s.countDataset(ctx, dataset, req)
+	if err != nil {
+		return nil, err
+	}
+
+	format := exportFormat(req.Format)
+	plan := &datasetExport{req: req, total: total, rowsPerFile: exportRowsPerFile(exportType)}
+
+	estimate = &domain.ExportEstimate{
+		ExportType: exportType,
+		Format:     format,
+		Rows:       total,
+		Files:      plan.parts(),
+	}
+
+	if splitsFiles(format) {
+		estimate.RowsPerFile = plan.rowsPerFile
+	}
+
+	rowsPerSec, bytesPerRow, runs := s.exportThroughput(ctx, exportType, format)
+	estimate.Bytes = int64(float64(total) * bytesPerRow)
+	estimate.DurationMs = int64(float64(total) / rowsPerSec * 1000)
+	estimate.BasedOnRuns = runs
+
+	s.logger.Info(
+		"export_estimated",
+		zap.String("type", exportType),
+		zap.Int64("total_rows", total),
+		zap.Int("total_files", estimate.Files),
+		zap.Int("based_on_runs", runs),
+	)
+
+	return estimate, nil
+}
+
+// exportRowsPerFile is the xlsx part size exports of exportType are split by.
+func exportRowsPerFile(exportType string) int {
+	if exportType == domain.EXPORT_TYPE_TRANSAKSI {
+		return MAX_ROWS_PER_FILE
+	}
+
+	return datasetBatchSize
+}
+
+// exportThroughput averages the rows per second and the bytes per row of the
+// latest successful runs of exportType. Bytes per row only come from runs in
+// the same format, EXPORT_ESTIMATE_ROWS_PER_SEC and estimateBytesPerRow fill
+// in without history.
+func (s *ExporterService) exportThroughput(ctx context.Context, exportType, format string) (rowsPerSec, bytesPerRow float64, runs int) {
+	rowsPerSec = float64(max(s.config.ExportEstimateRowsPerSec, 1))
+	bytesPerRow = estimateBytesPerRow[format]
+
+	if s.audits == nil {
+		return rowsPerSec, bytesPerRow, 0
+	}
+
+	audits, err := s.audits.FindRecentDone(ctx, exportType, estimateHistoryRuns)
+	if err != nil {
+		s.logger.Error(
+			"error_find_recent_export_audits",
+			zap.String("type", exportType),
+			zap.Error(err),
+		)
+		return rowsPerSec, bytesPerRow, 0
+	}
+
+	var rows, durationMs, formatRows, formatBytes int64
+	for _, audit := range audits {
+		// empty runs say nothing about throughput
+		if audit.Rows == 0 || audit.DurationMs == 0 {
+			continue
+		}
+
+		runs++
+		rows += audit.Rows
+		durationMs += audit.DurationMs
+
+		if audit.Request != nil && exportFormat(audit.Request.Format) == format && audit.Bytes > 0 {
+			formatRows += audit.Rows
+			formatBytes += audit.Bytes
+		}
+	}
+
+	if rows > 0 {
+		rowsPerSec = float64(rows) / (float64(durationMs) / 1000)
+	}
+
+	if formatRows > 0 {
+		bytesPerRow = float64(formatBytes) / float64(formatRows)
+	}
+
+	return rowsPerSec, bytesPerRow, runs
+}
@@ -0,0 +1,72 @@
+package service_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"event-registration/internal/common"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"event-registration/internal/core/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateExport(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("files", 0o750))
+
+	audits := &memoryAuditRepo{}
+	cfg := &common.Config{ExportEstimateRowsPerSec: 5000}
+	s := newExporterService(service.ExporterParams{Repo: &cursorRecordingRepo{}, Audits: audits, Config: cfg})
+
+	req := &request.RekapRequest{DateStart: "2026/01/01", DateEnd: "2026/12/31"}
+
+	t.Run("without history", func(t *testing.T) {
+		estimate, err := s.EstimateExport(context.Background(), domain.EXPORT_TYPE_TRANSAKSI, req, "user-1")
+		require.NoError(t, err)
+		require.Equal(t, &domain.ExportEstimate{
+			ExportType:  domain.EXPORT_TYPE_TRANSAKSI,
+			Format:      domain.EXPORT_FORMAT_XLSX,
+			Rows:        150000,
+			Files:       2,
+			RowsPerFile: 100000,
+			Bytes:       150000 * 110,
+			DurationMs:  30000,
+		}, estimate)
+	})
+
+	t.Run("from past runs", func(t *testing.T) {
+		audits.audits = []*domain.ExportAudit{
+			// 20000 rows per second, 100 bytes per xlsx row
+			{ExportType: domain.EXPORT_TYPE_TRANSAKSI, Status: domain.EXPORT_JOB_DONE, Request: &request.RekapRequest{}, Rows: 200000, DurationMs: 10000, Bytes: 20_000_000},
+			{ExportType: domain.EXPORT_TYPE_TRANSAKSI, Status: domain.EXPORT_JOB_DONE, Request: &request.RekapRequest{Format: "csv"}, Rows: 100000, DurationMs: 5000, Bytes: 50_000_000},
+			{ExportType: domain.EXPORT_TYPE_TRANSAKSI, Status: domain.EXPORT_JOB_FAILED, Rows: 10, DurationMs: 60000},
+			{ExportType: domain.EXPORT_TYPE_PELANGGAN, Status: domain.EXPORT_JOB_DONE, Rows: 10, DurationMs: 60000},
+		}
+
+		estimate, err := s.EstimateExport(context.Background(), domain.EXPORT_TYPE_TRANSAKSI, req, "user-1")
+		require.NoError(t, err)
+		require.Equal(t, int64(150000*100), estimate.Bytes)
+		require.Equal(t, int64(7500), estimate.DurationMs)
+		require.Equal(t, 2, estimate.BasedOnRuns)
+	})
+
+	t.Run("single file formats", func(t *testing.T) {
+		estimate, err := s.EstimateExport(context.Background(), domain.EXPORT_TYPE_TRANSAKSI, &request.RekapRequest{DateStart: "2026/01/01", DateEnd: "2026/12/31", Format: "csv"}, "user-1")
+		require.NoError(t, err)
+		require.Equal(t, 1, estimate.Files)
+		require.Zero(t, estimate.RowsPerFile)
+		require.Equal(t, int64(150000*500), estimate.Bytes)
+	})
+
+	t.Run("unknown export type", func(t *testing.T) {
+		_, err := s.EstimateExport(context.Background(), "pengguna", req, "user-1")
+		require.ErrorIs(t, err, service.ErrUnknownExportType)
+	})
+
+	entries, err := os.ReadDir("files")
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
@@ -0,0 +1,503 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"event-registration/internal/common/helper"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrExportPartiallyFailed = errors.New("export_partially_failed")
+	ErrExportJobNotRunning   = errors.New("export_job_not_running")
+)
+
+// ExportProgress keeps the job record in sync with what the exporter has
+// written so far. A nil *ExportProgress is valid and records nothing.
+type ExportProgress struct {
+	mu sync.Mutex
+	// ctx is only used to save the job, it outlives a cancelled export
+	ctx    context.Context
+	job    *domain.ExportJob
+	repo   domain.ExportJobRepository
+	logger *zap.Logger
+}
+
+func (p *ExportProgress) SetTotal(total int64) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.job.TotalRows = total
+	p.save()
+}
+
+func (p *ExportProgress) AddTotal(total int64) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.job.TotalRows += total
+	p.save()
+}
+
+func (p *ExportProgress) AddRows(rows int) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.job.RowsWritten += int64(rows)
+	p.save()
+}
+
+func (p *ExportProgress) AddFile(path string, rows int) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.job.Files = append(p.job.Files, domain.ExportFile{Path: path, Rows: rows, MaskingPolicy: p.job.Request.MaskingPolicy})
+	p.save()
+}
+
+// Discard takes a failed attempt back out of the job so it can be retried
+// without counting its rows and files twice.
+func (p *ExportProgress) Discard(files []string, rows int, total int64) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	discarded := map[string]bool{}
+	for _, file := range files {
+		discarded[file] = true
+	}
+
+	kept := p.job.Files[:0]
+	for _, file := range p.job.Files {
+		if !discarded[file.Path] {
+			kept = append(kept, file)
+		}
+	}
+
+	p.job.Files = kept
+	p.job.RowsWritten -= int64(rows)
+	p.job.TotalRows -= total
+	p.save()
+}
+
+func (p *ExportProgress) SetReport(report *domain.ExportReport) {
+	if p == nil || report == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.job.Report = report
+	p.save()
+}
+
+func (p *ExportProgress) SetReconciliation(report *domain.ReconciliationReport) {
+	if p == nil || report == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.job.Reconciliation = report
+	p.save()
+}
+
+// dir is the folder the job writes its files to, files/<job id>/ so exports
+// of the same filters do not overwrite each other.
+func (p *ExportProgress) dir() string {
+	if p == nil || p.job == nil {
+		return filesDir
+	}
+
+	return exportJobDir(p.job.ID)
+}
+
+func (p *ExportProgress) jobID() string {
+	if p == nil || p.job == nil {
+		return ""
+	}
+
+	return p.job.ID
+}
+
+func exportJobDir(jobID string) string {
+	return filesDir + jobID + "/"
+}
+
+func (p *ExportProgress) save() {
+	if err := p.repo.Save(p.ctx, p.job); err != nil {
+		p.logger.Error(
+			"error_save_export_job",
+			zap.String("job_id", p.job.ID),
+			zap.Error(err),
+		)
+	}
+}
+
+// EnqueueExport stores a queued job and hands it to startExportJob, so the
+// caller can poll FindExportJob instead of waiting for every file part.
+func (s *ExporterService) EnqueueExport(ctx context.Context, exportType string, req *request.RekapRequest, requestedBy string, roles []string) (*domain.ExportJob, error) {
+	req.MaskingPolicy = s.MaskingPolicyFor(roles)
+
+	// a reconciliation writes its own columns, not a dataset layout
+	if exportType != domain.EXPORT_TYPE_RECONCILIATION {
+		if _, err := s.exportLayout(exportType, req); err != nil {
+			return nil, err
+		}
+	}
+
+	unlock, err := s.lockAdmission(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.admitExport(ctx, exportType, req, requestedBy); err != nil {
+		unlock()
+		return nil, err
+	}
+
+	job, err := s.createExportJob(ctx, exportType, req, requestedBy)
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.startExportJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// startExportJob publishes job to the export workers, or runs it in the
+// background of this process when no queue is configured. A job that cannot
+// be published is marked failed.
+func (s *ExporterService) startExportJob(ctx context.Context, job *domain.ExportJob) error {
+	if s.queue == nil {
+		go s.runTracked(job)
+		return nil
+	}
+
+	if err := s.queue.Publish(&domain.ExportMessage{JobID: job.ID}); err != nil {
+		s.logger.Error(
+			"error_publish_export_job",
+			zap.String("job_id", job.ID),
+			zap.Error(err),
+		)
+
+		job.Status = domain.EXPORT_JOB_FAILED
+		job.Error = err.Error()
+		if errSave := s.jobs.Save(ctx, job); errSave != nil {
+			s.logger.Error(
+				"error_save_export_job",
+				zap.String("job_id", job.ID),
+				zap.Error(errSave),
+			)
+		}
+		return err
+	}
+
+	s.logger.Info(
+		"export_job_published",
+		zap.String("job_id", job.ID),
+	)
+
+	return nil
+}
+
+// MaskingPolicyFor returns the masking policy for a requester with roles.
+func (s *ExporterService) MaskingPolicyFor(roles []string) string {
+	return s.maskingPolicies().PolicyFor(roles)
+}
+
+func (s *ExporterService) createExportJob(ctx context.Context, exportType string, req *request.RekapRequest, requestedBy string) (*domain.ExportJob, error) {
+	job := &domain.ExportJob{
+		ID:          helper.GenerateUUID(),
+		Type:        exportType,
+		Status:      domain.EXPORT_JOB_QUEUED,
+		Request:     req,
+		RequestedBy: requestedBy,
+		Files:       []domain.ExportFile{},
+		CreatedAt:   time.Now(),
+	}
+
+	if err := s.jobs.Save(ctx, job); err != nil {
+		s.logger.Error(
+			"error_save_export_job",
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	s.logger.Info(
+		"export_job_queued",
+		zap.String("job_id", job.ID),
+		zap.String("type", job.Type),
+	)
+
+	return job, nil
+}
+
+// runTracked claims the job and runs it in this process, a job another
+// replica claimed first is left to it.
+func (s *ExporterService) runTracked(job *domain.ExportJob) {
+	ctx, release, err := s.claimExportJob(context.Background(), job.ID)
+	if err != nil {
+		s.logger.Error(
+			"error_claim_export_job",
+			zap.String("job_id", job.ID),
+			zap.Error(err),
+		)
+		return
+	}
+	defer release()
+
+	s.runClaimed(ctx, job)
+}
+
+// runClaimed runs a job this process holds the lease on and keeps it
+// cancellable through CancelExportJob while it runs.
+func (s *ExporterService) runClaimed(ctx context.Context, job *domain.ExportJob) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	s.runningMu.Lock()
+	s.running[job.ID] = cancel
+	s.runningMu.Unlock()
+
+	defer func() {
+		s.runningMu.Lock()
+		delete(s.running, job.ID)
+		s.runningMu.Unlock()
+		cancel()
+	}()
+
+	s.RunExportJob(ctx, job)
+}
+
+// CancelExportJob stops a job running in this process. The job ends up
+// cancelled once the exporter notices, files written so far are kept.
+func (s *ExporterService) CancelExportJob(ctx context.Context, id, requestedBy string) (*domain.ExportJob, error) {
+	job, err := s.FindExportJob(ctx, id, requestedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	s.runningMu.Lock()
+	cancel, ok := s.running[id]
+	s.runningMu.Unlock()
+
+	if !ok {
+		return nil, ErrExportJobNotRunning
+	}
+
+	cancel()
+
+	s.logger.Info(
+		"export_job_cancel_requested",
+		zap.String("job_id", id),
+	)
+
+	return job, nil
+}
+
+// PinExportJob protects a job's files from the retention policy, or
+// releases them again.
+func (s *ExporterService) PinExportJob(ctx context.Context, id, requestedBy string, pinned bool) (*domain.ExportJob, error) {
+	job, err := s.FindExportJob(ctx, id, requestedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	job.Pinned = pinned
+	if err := s.jobs.Save(ctx, job); err != nil {
+		s.logger.Error(
+			"error_save_export_job",
+			zap.String("job_id", job.ID),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	s.logger.Info(
+		"export_job_pinned",
+		zap.String("job_id", job.ID),
+		zap.Bool("pinned", pinned),
+	)
+
+	return job, nil
+}
+
+// FindExportJob returns a job of requestedBy, other users' jobs are reported
+// as not found.
+func (s *ExporterService) FindExportJob(ctx context.Context, id, requestedBy string) (*domain.ExportJob, error) {
+	job, err := s.findExportJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.RequestedBy != requestedBy {
+		return nil, domain.ErrExportJobNotFound
+	}
+
+	return job, nil
+}
+
+func (s *ExporterService) findExportJob(ctx context.Context, id string) (*domain.ExportJob, error) {
+	job, err := s.jobs.FindByID(ctx, id)
+	if err != nil {
+		if !errors.Is(err, domain.ErrExportJobNotFound) {
+			s.logger.Error(
+				"error_find_export_job",
+				zap.String("job_id", id),
+				zap.Error(err),
+			)
+		}
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// RunExportJob executes a job synchronously and records its final state.
+// The job stays queued until it gets a running slot.
+func (s *ExporterService) RunExportJob(ctx context.Context, job *domain.ExportJob) {
+	progress := &ExportProgress{ctx: context.WithoutCancel(ctx), job: job, repo: s.jobs, logger: s.logger}
+
+	startedAt := time.Now()
+	release, err := s.acquireRun(ctx, job)
+	if err == nil {
+		defer release()
+
+		startedAt = time.Now()
+		progress.mu.Lock()
+		job.Status = domain.EXPORT_JOB_RUNNING
+		job.StartedAt = &startedAt
+		progress.save()
+		progress.mu.Unlock()
+
+		err = s.runExport(ctx, job, progress)
+	}
+
+	finishedAt := time.Now()
+	progress.mu.Lock()
+	defer progress.mu.Unlock()
+
+	// another worker may own the job now, its state is theirs to record
+	if errors.Is(context.Cause(ctx), ErrExportJobLeaseLost) {
+		s.logger.Error(
+			"export_job_abandoned",
+			zap.String("job_id", job.ID),
+		)
+		return
+	}
+
+	// the worker is shutting down, the next one resumes from the checkpoint
+	if errors.Is(err, domain.ErrExportInterrupted) {
+		job.Status = domain.EXPORT_JOB_QUEUED
+		progress.save()
+
+		s.logger.Info(
+			"export_job_interrupted",
+			zap.String("job_id", job.ID),
+			zap.Int64("rows_written", job.RowsWritten),
+		)
+		return
+	}
+
+	job.FinishedAt = &finishedAt
+	switch {
+	case err == nil:
+		job.Status = domain.EXPORT_JOB_DONE
+	case errors.Is(err, context.Canceled):
+		job.Status = domain.EXPORT_JOB_CANCELLED
+		job.Error = err.Error()
+	case errors.Is(err, ErrExportPartiallyFailed):
+		job.Status = domain.EXPORT_JOB_PARTIAL
+		job.Error = err.Error()
+	default:
+		job.Status = domain.EXPORT_JOB_FAILED
+		job.Error = err.Error()
+	}
+	progress.save()
+	if job.Status == domain.EXPORT_JOB_DONE || job.Status == domain.EXPORT_JOB_PARTIAL {
+		s.deleteCheckpoint(progress.ctx, job)
+	}
+	s.finishScheduleRun(progress.ctx, job)
+
+	s.logger.Info(
+		"export_job_finished",
+		zap.String("job_id", job.ID),
+		zap.String("status", job.Status),
+		zap.Duration("duration", finishedAt.Sub(startedAt)),
+	)
+
+	s.recordAudit(progress.ctx, job, startedAt, finishedAt)
+	s.notifyExportJob(progress.ctx, job)
+}
+
+func (s *ExporterService) runExport(ctx context.Context, job *domain.ExportJob, progress *ExportProgress) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error(
+				"export_job_panic",
+				zap.String("job_id", job.ID),
+				zap.Any("panic", r),
+			)
+			err = fmt.Errorf("export panicked: %v", r)
+		}
+	}()
+
+	if err := os.MkdirAll(progress.dir(), 0o750); err != nil {
+		s.logger.Error(
+			"error_make_dir",
+			zap.String("job_id", job.ID),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	switch job.Type {
+	case domain.EXPORT_TYPE_TRANSAKSI:
+		return s.ExportRekapTransaksi(ctx, job.Request, progress)
+	case domain.EXPORT_TYPE_TRANSAKSI_ALL:
+		report, err := s.ExportAllRekapTransaksi(ctx, job.Request, progress)
+		progress.SetReport(report)
+		return err
+	case domain.EXPORT_TYPE_PELANGGAN:
+		return s.ExportRekapPelanggan(ctx, job.Request, progress)
+	case domain.EXPORT_TYPE_RECONCILIATION:
+		report, err := s.ReconcileTransaksi(ctx, job.Request, progress)
+		progress.SetReconciliation(report)
+		return err
+	default:
+		return s.ExportDataset(ctx, job.Type, job.Request, progress)
+	}
+}
@@ -0,0 +1,324 @@
+package service
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"event-registration/internal/common"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	layoutDatasetTransaksi = "transaksi"
+	layoutDatasetPelanggan = "pelanggan"
+
+	layoutDefault   = "default"
+	layoutRekapUnit = "rekap_unit"
+
+	layoutLanguageDefault = "id"
+	layoutRowNumber       = "row_number"
+)
+
+// Cell types a layout column can declare. xlsx writes them as numbers and
+// dates, other formats keep the source text.
+const (
+	cellTypeText     = "text"
+	cellTypeNumber   = "number"
+	cellTypeRupiah   = "rupiah"
+	cellTypeDatetime = "datetime"
+)
+
+var ErrUnknownLayout = errors.New("unknown_export_layout")
+
+//go:embed layouts/export_layouts.yaml
+var builtinLayouts []byte
+
+// layoutTypes maps the built-in datasets to the row type their fields are
+// read from. Layouts of registered datasets are checked by RegisterDataset.
+var layoutTypes = map[string]reflect.Type{
+	layoutDatasetTransaksi: reflect.TypeOf(domain.Transaksi{}),
+	layoutDatasetPelanggan: reflect.TypeOf(domain.Pelanggan{}),
+}
+
+type layoutColumn struct {
+	Field  string            `mapstructure:"field"`
+	Header map[string]string `mapstructure:"header"`
+	Width  float64           `mapstructure:"width"`
+	Empty  string            `mapstructure:"empty"`
+	Type   string            `mapstructure:"type"`
+}
+
+type layoutConfig struct {
+	Columns []layoutColumn `mapstructure:"columns"`
+}
+
+// ExportLayouts holds the column sets per dataset, the built-in ones merged
+// with EXPORT_LAYOUTS_FILE.
+type ExportLayouts struct {
+	datasets map[string]map[string]layoutConfig
+}
+
+func NewExportLayouts(config *common.Config) (*ExportLayouts, error) {
+	layouts, err := parseLayouts(builtinLayouts)
+	if err != nil {
+		return nil, fmt.Errorf("builtin export layouts: %w", err)
+	}
+
+	if config.ExportLayoutsFile != "" {
+		data, err := os.ReadFile(config.ExportLayoutsFile)
+		if err != nil {
+			return nil, err
+		}
+
+		custom, err := parseLayouts(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", config.ExportLayoutsFile, err)
+		}
+
+		for dataset, named := range custom.datasets {
+			if layouts.datasets[dataset] == nil {
+				layouts.datasets[dataset] = map[string]layoutConfig{}
+			}
+			for name, layout := range named {
+				layouts.datasets[dataset][name] = layout
+			}
+		}
+	}
+
+	// fail at startup rather than in the middle of an export
+	for dataset, rowType := range layoutTypes {
+		if err := layouts.validate(dataset, rowType); err != nil {
+			return nil, err
+		}
+	}
+
+	return layouts, nil
+}
+
+// validate resolves every layout of dataset against rowType.
+func (l *ExportLayouts) validate(dataset string, rowType reflect.Type) error {
+	for name := range l.datasets[dataset] {
+		if _, err := l.resolve(dataset, rowType, name, layoutLanguageDefault); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var (
+	defaultLayoutsOnce sync.Once
+	defaultLayouts     *ExportLayouts
+)
+
+// builtinExportLayouts is used when the service was built without layouts.
+func builtinExportLayouts() *ExportLayouts {
+	defaultLayoutsOnce.Do(func() {
+		layouts, err := NewExportLayouts(&common.Config{})
+		if err != nil {
+			panic(err)
+		}
+		defaultLayouts = layouts
+	})
+
+	return defaultLayouts
+}
+
+func parseLayouts(data []byte) (*ExportLayouts, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	datasets := map[string]map[string]layoutConfig{}
+	if err := v.Unmarshal(&datasets); err != nil {
+		return nil, err
+	}
+
+	for dataset := range layoutTypes {
+		if datasets[dataset] == nil {
+			datasets[dataset] = map[string]layoutConfig{}
+		}
+	}
+
+	return &ExportLayouts{datasets: datasets}, nil
+}
+
+// exportLayout is a layout resolved against its row type.
+type exportLayout struct {
+	headers []string
+	widths  []float64
+	names   []string
+	fields  []int // -1 for the row number
+	empty   []string
+	types   []string
+	masks   []func(string) string
+	// maskingPolicy is the policy applied by masks
+	maskingPolicy string
+}
+
+func (l *ExportLayouts) resolve(dataset string, rowType reflect.Type, name, language string) (*exportLayout, error) {
+	config, ok := l.datasets[dataset][strings.ToLower(name)]
+	if !ok && len(l.datasets[dataset]) == 0 && strings.EqualFold(name, layoutDefault) {
+		// datasets without layouts export every field under its json name
+		config, ok = allFieldsLayout(rowType), true
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s/%s", ErrUnknownLayout, dataset, name)
+	}
+
+	if len(config.Columns) == 0 {
+		return nil, fmt.Errorf("layout %s/%s has no columns", dataset, name)
+	}
+
+	fieldIndex := jsonFieldIndex(rowType)
+
+	layout := &exportLayout{}
+	for _, column := range config.Columns {
+		index := -1
+		if column.Field != layoutRowNumber {
+			i, ok := fieldIndex[column.Field]
+			if !ok {
+				return nil, fmt.Errorf("layout %s/%s: unknown field %s", dataset, name, column.Field)
+			}
+			index = i
+		}
+
+		cellType := strings.ToLower(column.Type)
+		switch cellType {
+		case "":
+			cellType = cellTypeText
+		case cellTypeText, cellTypeNumber, cellTypeRupiah, cellTypeDatetime:
+		default:
+			return nil, fmt.Errorf("layout %s/%s: unknown type %s for %s", dataset, name, column.Type, column.Field)
+		}
+
+		header := column.Header[language]
+		if header == "" {
+			header = column.Header[layoutLanguageDefault]
+		}
+		if header == "" {
+			header = column.Field
+		}
+
+		layout.names = append(layout.names, column.Field)
+		layout.headers = append(layout.headers, header)
+		layout.widths = append(layout.widths, column.Width)
+		layout.fields = append(layout.fields, index)
+		layout.empty = append(layout.empty, column.Empty)
+		layout.types = append(layout.types, cellType)
+	}
+
+	return layout, nil
+}
+
+func allFieldsLayout(rowType reflect.Type) layoutConfig {
+	var config layoutConfig
+	for i := 0; i < rowType.NumField(); i++ {
+		name := strings.Split(rowType.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			config.Columns = append(config.Columns, layoutColumn{Field: name})
+		}
+	}
+	return config
+}
+
+// jsonFieldIndex maps the json names of t's fields to their index.
+func jsonFieldIndex(t reflect.Type) map[string]int {
+	index := map[string]int{}
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			index[name] = i
+		}
+	}
+	return index
+}
+
+// row returns the cells of one record, row must be a pointer to the
+// dataset's struct.
+func (l *exportLayout) row(rowIndex int, row interface{}) []interface{} {
+	value := reflect.ValueOf(row).Elem()
+
+	cells := make([]interface{}, len(l.fields))
+	for i, field := range l.fields {
+		if field < 0 {
+			cells[i] = rowIndex
+			continue
+		}
+
+		cell := value.Field(field).Interface()
+		if str, ok := cell.(string); ok && l.masks != nil && l.masks[i] != nil {
+			cell = l.masks[i](str)
+		}
+		if l.empty[i] != "" && cell == "" {
+			cell = l.empty[i]
+		}
+		cells[i] = cell
+	}
+
+	return cells
+}
+
+// exportLayout resolves the layout picked by req for exportType, with the
+// request's masking policy applied.
+func (s *ExporterService) exportLayout(exportType string, req *request.RekapRequest) (*exportLayout, error) {
+	dataset, err := s.dataset(exportType)
+	if err != nil {
+		return nil, err
+	}
+
+	// rekap transaksi all writes one folder per unit with its own columns
+	name := layoutDefault
+	if exportType == domain.EXPORT_TYPE_TRANSAKSI_ALL {
+		name = layoutRekapUnit
+	}
+	if req.Layout != "" {
+		name = req.Layout
+	}
+
+	language := req.Language
+	if language == "" {
+		language = layoutLanguageDefault
+	}
+
+	layout, err := s.exportLayouts().resolve(dataset.Name(), dataset.RowType(), name, language)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, maskers := s.maskingPolicies().maskers(req.MaskingPolicy)
+
+	layout.maskingPolicy = policy
+	layout.masks = make([]func(string) string, len(layout.names))
+	for i, name := range layout.names {
+		layout.masks[i] = maskers[name]
+	}
+
+	return layout, nil
+}
+
+func (s *ExporterService) exportLayouts() *ExportLayouts {
+	if s.layouts == nil {
+		return builtinExportLayouts()
+	}
+
+	return s.layouts
+}
+
+func (s *ExporterService) maskingPolicies() *ExportMasking {
+	if s.masking == nil {
+		return builtinExportMasking()
+	}
+
+	return s.masking
+}
@@ -0,0 +1,57 @@
+package service_test
+
+import (
+	"os"
+	"testing"
+
+	"event-registration/internal/common"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportLayouts(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("files", 0o750))
+
+	require.NoError(t, os.WriteFile("layouts.yaml", []byte(`
+transaksi:
+  compact:
+    columns:
+      - { field: row_number, header: { id: "No.", en: "No." } }
+      - { field: meter_number, header: { id: "ID Meteran", en: "Meter ID" } }
+      - { field: type, header: { id: "Jenis", en: "Type" }, empty: "-" }
+`), 0o600))
+
+	layouts, err := service.NewExportLayouts(&common.Config{ExportLayoutsFile: "layouts.yaml"})
+	require.NoError(t, err)
+
+	s := newExporterService(service.ExporterParams{Repo: &flakyExporterRepo{}, Layouts: layouts})
+
+	t.Run("named layout in english", func(t *testing.T) {
+		req := &request.RekapRequest{UnitCode: "54110", DateStart: "2026/02/01", DateEnd: "2026/02/28", Format: "csv", Layout: "compact", Language: "en"}
+		require.NoError(t, s.ExportRekapTransaksi(t.Context(), req, nil))
+
+		data, err := os.ReadFile("files/UNIT_54110_20260201_20260228.csv")
+		require.NoError(t, err)
+		require.Equal(t, "No.,Meter ID,Type\n1,,-\n2,,-\n", string(data))
+	})
+
+	t.Run("unknown layout", func(t *testing.T) {
+		req := &request.RekapRequest{DateStart: "2026/02/01", DateEnd: "2026/02/28", Layout: "missing"}
+		require.ErrorIs(t, s.ExportRekapTransaksi(t.Context(), req, nil), service.ErrUnknownLayout)
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		require.NoError(t, os.WriteFile("broken.yaml", []byte(`
+pelanggan:
+  default:
+    columns:
+      - { field: saldo, header: { id: "Saldo" } }
+`), 0o600))
+
+		_, err := service.NewExportLayouts(&common.Config{ExportLayoutsFile: "broken.yaml"})
+		require.ErrorContains(t, err, "unknown field saldo")
+	})
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"event-registration/internal/common/helper"
+	"event-registration/internal/core/domain"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// defaultExportJobLease is used when EXPORT_JOB_LEASE is not set.
+const defaultExportJobLease = time.Minute
+
+// ErrExportJobLeaseLost stops a run whose lease ran out and may have been
+// claimed by another worker, the job is left to that worker.
+var ErrExportJobLeaseLost = errors.New("export_job_lease_lost")
+
+func (s *ExporterService) exportJobLease() time.Duration {
+	if s.config.ExportJobLease <= 0 {
+		return defaultExportJobLease
+	}
+
+	return s.config.ExportJobLease
+}
+
+// heartbeat calls renew every third of lease until stop is called or renew
+// returns false.
+func heartbeat(lease time.Duration, renew func() bool) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(lease / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			if !renew() {
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// claimExportJob takes the lease on job id for one run and renews it until
+// release is called. The returned context is cancelled with
+// ErrExportJobLeaseLost if a renewal finds the lease gone.
+func (s *ExporterService) claimExportJob(ctx context.Context, id string) (context.Context, func(), error) {
+	lease := s.exportJobLease()
+	owner := helper.GenerateUUID()
+
+	if err := s.jobs.Claim(ctx, id, owner, lease); err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	stop := heartbeat(lease, func() bool {
+		err := s.jobs.Renew(context.WithoutCancel(ctx), id, owner, lease)
+		if errors.Is(err, domain.ErrExportJobClaimed) {
+			s.logger.Error(
+				"export_job_lease_lost",
+				zap.String("job_id", id),
+			)
+			cancel(ErrExportJobLeaseLost)
+			return false
+		}
+
+		// a failed renewal is retried on the next beat, the lease outlives
+		// two of them
+		if err != nil {
+			s.logger.Error(
+				"error_renew_export_job_lease",
+				zap.String("job_id", id),
+				zap.Error(err),
+			)
+		}
+		return true
+	})
+
+	release := func() {
+		stop()
+		cancel(nil)
+
+		if err := s.jobs.Release(context.WithoutCancel(ctx), id, owner); err != nil {
+			s.logger.Error(
+				"error_release_export_job_lease",
+				zap.String("job_id", id),
+				zap.Error(err),
+			)
+		}
+	}
+
+	return ctx, release, nil
+}
+
+// awaitExportJobLease waits up to one lease for the lease on job id to be
+// released or to run out, which is what happens when the worker holding it
+// died, and claims it then. It returns domain.ErrExportJobClaimed when the
+// holder is still renewing it after that.
+func (s *ExporterService) awaitExportJobLease(ctx context.Context, id string) (context.Context, func(), error) {
+	lease := s.exportJobLease()
+	deadline := time.Now().Add(lease)
+
+	ticker := time.NewTicker(lease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-drainSignal(ctx):
+			return nil, nil, domain.ErrExportInterrupted
+		case <-ticker.C:
+		}
+
+		claimed, release, err := s.claimExportJob(ctx, id)
+		if !errors.Is(err, domain.ErrExportJobClaimed) || !time.Now().Before(deadline) {
+			return claimed, release, err
+		}
+	}
+}
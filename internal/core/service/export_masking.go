@@ -0,0 +1,214 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"event-registration/internal/common"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	maskRuleLast4  = "last4"
+	maskRuleHash   = "hash"
+	maskRuleRedact = "redact"
+
+	// hashed values keep this many hex characters
+	maskHashLength = 16
+	maskRedacted   = "***"
+)
+
+//go:embed masking/export_masking.yaml
+var builtinMasking []byte
+
+type maskingConfig struct {
+	Default  string                       `mapstructure:"default"`
+	Policies map[string]map[string]string `mapstructure:"policies"`
+	Roles    map[string]string            `mapstructure:"roles"`
+}
+
+// ExportMasking maps requester roles to the masking policy applied to the
+// sensitive columns of their exports.
+type ExportMasking struct {
+	config maskingConfig
+	secret []byte
+}
+
+func NewExportMasking(config *common.Config) (*ExportMasking, error) {
+	masking, err := parseMasking(builtinMasking)
+	if err != nil {
+		return nil, fmt.Errorf("builtin masking policies: %w", err)
+	}
+
+	if config.ExportMaskingFile != "" {
+		data, err := os.ReadFile(config.ExportMaskingFile)
+		if err != nil {
+			return nil, err
+		}
+
+		custom, err := parseMasking(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", config.ExportMaskingFile, err)
+		}
+
+		if custom.Default != "" {
+			masking.Default = custom.Default
+		}
+		for name, rules := range custom.Policies {
+			masking.Policies[name] = rules
+		}
+		for role, policy := range custom.Roles {
+			masking.Roles[role] = policy
+		}
+	}
+
+	if _, ok := masking.Policies[masking.Default]; !ok {
+		return nil, fmt.Errorf("default masking policy %s is not defined", masking.Default)
+	}
+
+	for role, policy := range masking.Roles {
+		if _, ok := masking.Policies[policy]; !ok {
+			return nil, fmt.Errorf("role %s uses undefined masking policy %s", role, policy)
+		}
+	}
+
+	for name, rules := range masking.Policies {
+		for field, rule := range rules {
+			switch rule {
+			case maskRuleLast4, maskRuleHash, maskRuleRedact:
+			default:
+				return nil, fmt.Errorf("masking policy %s: unknown rule %s for %s", name, rule, field)
+			}
+		}
+	}
+
+	secret := config.ExportMaskingSecret
+	if secret == "" {
+		secret = config.JwtSecret
+	}
+
+	return &ExportMasking{config: masking, secret: []byte(secret)}, nil
+}
+
+var (
+	defaultMaskingOnce sync.Once
+	defaultMasking     *ExportMasking
+)
+
+// builtinExportMasking is used when the service was built without masking
+// policies.
+func builtinExportMasking() *ExportMasking {
+	defaultMaskingOnce.Do(func() {
+		masking, err := NewExportMasking(&common.Config{})
+		if err != nil {
+			panic(err)
+		}
+		defaultMasking = masking
+	})
+
+	return defaultMasking
+}
+
+func parseMasking(data []byte) (maskingConfig, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return maskingConfig{}, err
+	}
+
+	config := maskingConfig{Policies: map[string]map[string]string{}, Roles: map[string]string{}}
+	if err := v.Unmarshal(&config); err != nil {
+		return config, err
+	}
+
+	// policies without rules, such as none, are dropped by Unmarshal
+	for name := range v.GetStringMap("policies") {
+		if _, ok := config.Policies[name]; !ok {
+			config.Policies[name] = map[string]string{}
+		}
+	}
+
+	return config, nil
+}
+
+// PolicyFor returns the least restrictive policy among roles' policies, by
+// fewest masked fields, or the default policy.
+func (m *ExportMasking) PolicyFor(roles []string) string {
+	policy := ""
+	for _, role := range roles {
+		candidate, ok := m.config.Roles[strings.ToLower(role)]
+		if !ok {
+			continue
+		}
+
+		if policy == "" || len(m.config.Policies[candidate]) < len(m.config.Policies[policy]) {
+			policy = candidate
+		}
+	}
+
+	if policy == "" {
+		return m.config.Default
+	}
+
+	return policy
+}
+
+// maskers returns the mask to apply per field for policy and the name of
+// the policy applied. An unknown policy falls back to the default one, never
+// to no masking.
+func (m *ExportMasking) maskers(policy string) (string, map[string]func(string) string) {
+	rules, ok := m.config.Policies[policy]
+	if !ok {
+		policy = m.config.Default
+		rules = m.config.Policies[policy]
+	}
+
+	maskers := map[string]func(string) string{}
+	for field, rule := range rules {
+		switch rule {
+		case maskRuleLast4:
+			maskers[field] = maskLast4
+		case maskRuleHash:
+			maskers[field] = m.maskHash
+		case maskRuleRedact:
+			maskers[field] = maskRedact
+		}
+	}
+
+	return policy, maskers
+}
+
+func maskLast4(value string) string {
+	runes := []rune(value)
+	if len(runes) <= 4 {
+		return value
+	}
+
+	return strings.Repeat("*", len(runes)-4) + string(runes[len(runes)-4:])
+}
+
+func (m *ExportMasking) maskHash(value string) string {
+	if value == "" {
+		return value
+	}
+
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(value))
+
+	return hex.EncodeToString(mac.Sum(nil))[:maskHashLength]
+}
+
+func maskRedact(value string) string {
+	if value == "" {
+		return value
+	}
+
+	return maskRedacted
+}
@@ -0,0 +1,132 @@
+package service_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"event-registration/internal/common"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"event-registration/internal/core/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tokenExporterRepo streams one transaksi with every sensitive field set.
+type tokenExporterRepo struct {
+	flakyExporterRepo
+}
+
+func (r *tokenExporterRepo) StreamTransaksi(ctx context.Context, req *request.RekapRequest, after *domain.ExportCursor, limit int, fn func(*domain.Transaksi) error) error {
+	return fn(&domain.Transaksi{
+		ID:        "1",
+		Name:      "Budi",
+		MeterID:   "532100112233",
+		Token:     "1234567890123456",
+		CreatedAt: "2026-02-01 00:00:00",
+	})
+}
+
+func TestExportMasking(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("files", 0o750))
+
+	require.NoError(t, os.WriteFile("layouts.yaml", []byte(`
+transaksi:
+  sensitive:
+    columns:
+      - { field: name, header: { id: "Nama" } }
+      - { field: meter_number, header: { id: "ID Meteran" } }
+      - { field: token, header: { id: "Token" } }
+`), 0o600))
+
+	cfg := &common.Config{ExportLayoutsFile: "layouts.yaml", ExportMaskingSecret: "secret"}
+
+	layouts, err := service.NewExportLayouts(cfg)
+	require.NoError(t, err)
+
+	masking, err := service.NewExportMasking(cfg)
+	require.NoError(t, err)
+
+	s := newExporterService(service.ExporterParams{Repo: &tokenExporterRepo{}, Layouts: layouts, Masking: masking, Config: cfg})
+
+	export := func(policy string) []string {
+		req := &request.RekapRequest{UnitCode: "54110", DateStart: "2026/02/01", DateEnd: "2026/02/28", Format: "csv", Layout: "sensitive", MaskingPolicy: policy}
+		require.NoError(t, s.ExportRekapTransaksi(t.Context(), req, nil))
+
+		data, err := os.ReadFile("files/UNIT_54110_20260201_20260228.csv")
+		require.NoError(t, err)
+
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		require.Len(t, lines, 2)
+		return strings.Split(lines[1], ",")
+	}
+
+	t.Run("policy by role", func(t *testing.T) {
+		require.Equal(t, "none", masking.PolicyFor([]string{"viewer", "Admin"}))
+		require.Equal(t, "strict", masking.PolicyFor([]string{"viewer"}))
+		require.Equal(t, "standard", masking.PolicyFor([]string{"unknown"}))
+		require.Equal(t, "standard", masking.PolicyFor(nil))
+	})
+
+	t.Run("none", func(t *testing.T) {
+		require.Equal(t, []string{"Budi", "532100112233", "1234567890123456"}, export("none"))
+	})
+
+	t.Run("standard", func(t *testing.T) {
+		row := export("standard")
+		require.Equal(t, "Budi", row[0])
+		require.Len(t, row[1], 16)
+		require.NotEqual(t, "532100112233", row[1])
+		require.Equal(t, "************3456", row[2])
+
+		// hashes are stable so masked files can still be joined
+		require.Equal(t, row[1], export("standard")[1])
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		row := export("strict")
+		require.Equal(t, "***", row[0])
+		require.Equal(t, "***", row[2])
+	})
+
+	t.Run("jobs keep their own files", func(t *testing.T) {
+		jobs := &memoryExportJobRepo{jobs: map[string]*domain.ExportJob{}}
+		s := newExporterService(service.ExporterParams{Repo: &tokenExporterRepo{}, Jobs: jobs, Layouts: layouts, Masking: masking, Config: cfg})
+
+		for _, policy := range []string{"none", "strict"} {
+			req := &request.RekapRequest{UnitCode: "54110", DateStart: "2026/02/01", DateEnd: "2026/02/28", Format: "csv", Layout: "sensitive", MaskingPolicy: policy}
+			job := &domain.ExportJob{ID: "job-" + policy, Type: domain.EXPORT_TYPE_TRANSAKSI, Status: domain.EXPORT_JOB_QUEUED, Request: req}
+			jobs.jobs[job.ID] = job
+			s.RunExportJob(t.Context(), job)
+			require.Equal(t, domain.EXPORT_JOB_DONE, job.Status)
+		}
+
+		require.Equal(t, "files/job-none/UNIT_54110_20260201_20260228.csv", jobs.jobs["job-none"].Files[0].Path)
+		require.Equal(t, "files/job-strict/UNIT_54110_20260201_20260228.csv", jobs.jobs["job-strict"].Files[0].Path)
+
+		none, err := os.ReadFile(jobs.jobs["job-none"].Files[0].Path)
+		require.NoError(t, err)
+		require.Contains(t, string(none), "1234567890123456")
+
+		strict, err := os.ReadFile(jobs.jobs["job-strict"].Files[0].Path)
+		require.NoError(t, err)
+		require.NotContains(t, string(strict), "1234567890123456")
+	})
+
+	t.Run("unknown policy falls back to default", func(t *testing.T) {
+		require.Equal(t, "************3456", export("missing")[2])
+	})
+
+	t.Run("undefined policy", func(t *testing.T) {
+		require.NoError(t, os.WriteFile("masking.yaml", []byte(`
+roles:
+  operator: lenient
+`), 0o600))
+
+		_, err := service.NewExportMasking(&common.Config{ExportMaskingFile: "masking.yaml"})
+		require.ErrorContains(t, err, "undefined masking policy lenient")
+	})
+}
@@ -0,0 +1,342 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"event-registration/internal/common"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var ErrWebhookHostNotAllowed = errors.New("webhook_host_not_allowed")
+
+// ExportNotifier tells requesters their export finished, through a signed
+// webhook and an email, as set in their notification settings.
+//
+// Webhooks only go to EXPORT_WEBHOOK_ALLOWED_HOSTS. Without an allowlist
+// they go to any host that resolves to a public address, so a requester
+// cannot point the exporter at the internal network.
+type ExportNotifier struct {
+	repo domain.ExportNotificationRepository
+	// mailer is optional, without it no email is sent
+	mailer  domain.Mailer
+	client  *http.Client
+	config  *common.Config
+	logger  *zap.Logger
+	pending sync.WaitGroup
+}
+
+func NewExportNotifier(repo domain.ExportNotificationRepository, mailer domain.Mailer, config *common.Config, logger *zap.Logger) *ExportNotifier {
+	dialer := &net.Dialer{Timeout: config.ExportWebhookTimeout}
+	if len(config.ExportWebhookAllowedHosts) == 0 {
+		dialer.Control = dialPublicOnly
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &ExportNotifier{
+		repo:   repo,
+		mailer: mailer,
+		client: &http.Client{
+			Timeout:   config.ExportWebhookTimeout,
+			Transport: transport,
+			// a redirect could lead anywhere, it is reported as a failure
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		config: config,
+		logger: logger,
+	}
+}
+
+// dialPublicOnly refuses connections to loopback, private, link-local and
+// other non-public addresses. It runs on the resolved address, so a public
+// name resolving to an internal address is refused too.
+func dialPublicOnly(network, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !publicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrWebhookHostNotAllowed, host)
+	}
+
+	return nil
+}
+
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified()
+}
+
+// checkWebhookURL rejects webhook URLs outside the allowlist, or with a
+// non-public address literal when there is no allowlist.
+func (n *ExportNotifier) checkWebhookURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("%w: %s", ErrWebhookHostNotAllowed, parsed.Scheme)
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	if len(n.config.ExportWebhookAllowedHosts) > 0 {
+		if !slices.Contains(n.config.ExportWebhookAllowedHosts, host) {
+			return fmt.Errorf("%w: %s", ErrWebhookHostNotAllowed, host)
+		}
+		return nil
+	}
+
+	if ip := net.ParseIP(host); ip != nil && !publicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrWebhookHostNotAllowed, host)
+	}
+
+	return nil
+}
+
+// FindSetting returns the notification setting of a user, an empty one when
+// they never saved any.
+func (n *ExportNotifier) FindSetting(ctx context.Context, userID string) (*domain.ExportNotificationSetting, error) {
+	setting, err := n.repo.Find(ctx, userID)
+	if errors.Is(err, domain.ErrExportNotificationSettingNotFound) {
+		return &domain.ExportNotificationSetting{UserID: userID}, nil
+	}
+	if err != nil {
+		n.logger.Error(
+			"error_find_notification_setting",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	return setting, nil
+}
+
+func (n *ExportNotifier) SaveSetting(ctx context.Context, userID string, req *request.ExportNotificationRequest) (*domain.ExportNotificationSetting, error) {
+	if len(req.WebhookURL) > 0 {
+		if err := n.checkWebhookURL(req.WebhookURL); err != nil {
+			return nil, err
+		}
+	}
+
+	setting := &domain.ExportNotificationSetting{
+		UserID:     userID,
+		WebhookURL: req.WebhookURL,
+		Email:      req.Email,
+		UpdatedAt:  time.Now(),
+	}
+
+	if err := n.repo.Save(ctx, setting); err != nil {
+		n.logger.Error(
+			"error_save_notification_setting",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	return setting, nil
+}
+
+// notifyAsync sends notification in the background, so a slow webhook or
+// mail server does not hold up the export that finished.
+func (n *ExportNotifier) notifyAsync(ctx context.Context, notification *domain.ExportNotification) {
+	n.pending.Add(1)
+	go func() {
+		defer n.pending.Done()
+		n.Notify(ctx, notification)
+	}()
+}
+
+// Wait blocks until the notifications sent in the background are done or
+// ctx ends.
+func (n *ExportNotifier) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		n.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Notify sends notification to the destinations its requester set. Failures
+// are logged, the export result stands either way.
+func (n *ExportNotifier) Notify(ctx context.Context, notification *domain.ExportNotification) {
+	setting, err := n.repo.Find(ctx, notification.RequestedBy)
+	if err != nil {
+		if !errors.Is(err, domain.ErrExportNotificationSettingNotFound) {
+			n.logger.Error(
+				"error_find_notification_setting",
+				zap.String("user_id", notification.RequestedBy),
+				zap.Error(err),
+			)
+		}
+		return
+	}
+
+	if len(setting.WebhookURL) > 0 {
+		if err := n.sendWebhook(ctx, setting.WebhookURL, notification); err != nil {
+			n.logger.Error(
+				"error_send_export_webhook",
+				zap.String("job_id", notification.JobID),
+				zap.String("url", setting.WebhookURL),
+				zap.Error(err),
+			)
+		}
+	}
+
+	if len(setting.Email) > 0 && n.mailer != nil {
+		if err := n.mailer.Send(exportMail(setting.Email, notification)); err != nil {
+			n.logger.Error(
+				"error_send_export_email",
+				zap.String("job_id", notification.JobID),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+func (n *ExportNotifier) sendWebhook(ctx context.Context, url string, notification *domain.ExportNotification) error {
+	if len(n.config.ExportWebhookSecret) == 0 {
+		return errors.New("EXPORT_WEBHOOK_SECRET is not set")
+	}
+
+	// the allowlist may have changed since the URL was saved
+	if err := n.checkWebhookURL(url); err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Export-Timestamp", timestamp)
+	req.Header.Set("X-Export-Signature", "sha256="+WebhookSignature(n.config.ExportWebhookSecret, timestamp, body))
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// WebhookSignature is the hex HMAC-SHA256 of "<timestamp>.<body>" that a
+// receiver compares with the X-Export-Signature header.
+func WebhookSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func exportMail(to string, notification *domain.ExportNotification) *domain.MailMessage {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Export %s %s is %s.\n\n", notification.ExportType, notification.JobID, notification.Status)
+	fmt.Fprintf(&body, "Rows: %d\n", notification.Rows)
+	if len(notification.Error) > 0 {
+		fmt.Fprintf(&body, "Error: %s\n", notification.Error)
+	}
+
+	if len(notification.Files) > 0 {
+		body.WriteString("\nFiles:\n")
+		for _, file := range notification.Files {
+			fmt.Fprintf(&body, "- %s (%d rows)\n  %s\n", file.Path, file.Rows, file.URL)
+		}
+		fmt.Fprintf(&body, "\nThe links expire at %s.\n", notification.ExpiresAt.Format(time.RFC1123))
+	}
+
+	return &domain.MailMessage{
+		To:      []string{to},
+		Subject: fmt.Sprintf("Export %s %s", notification.ExportType, notification.Status),
+		Body:    body.String(),
+	}
+}
+
+// notifyExportJob sends a finished job to its requester with download links
+// for its files. Cancelled jobs were stopped by the requester, they are not
+// notified.
+func (s *ExporterService) notifyExportJob(ctx context.Context, job *domain.ExportJob) {
+	if s.notifier == nil || job.Status == domain.EXPORT_JOB_CANCELLED {
+		return
+	}
+
+	expiresAt := time.Now().Add(s.config.ExportDownloadExpiration)
+	baseURL := strings.TrimSuffix(s.config.ExportPublicURL, "/")
+
+	notification := &domain.ExportNotification{
+		JobID:       job.ID,
+		ExportType:  job.Type,
+		Status:      job.Status,
+		Error:       job.Error,
+		RequestedBy: job.RequestedBy,
+		Rows:        job.RowsWritten,
+		Files:       []domain.ExportNotificationFile{},
+		ExpiresAt:   expiresAt,
+	}
+
+	if job.FinishedAt != nil {
+		notification.FinishedAt = *job.FinishedAt
+	}
+
+	for _, file := range job.Files {
+		notification.Files = append(notification.Files, domain.ExportNotificationFile{
+			Path: file.Path,
+			Rows: file.Rows,
+			URL:  baseURL + s.SignDownloadURL(file.Path, expiresAt),
+		})
+	}
+
+	s.notifier.notifyAsync(ctx, notification)
+}
@@ -0,0 +1,174 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"event-registration/internal/common"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"event-registration/internal/core/service"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type memoryNotificationRepo struct {
+	settings map[string]*domain.ExportNotificationSetting
+}
+
+func (r *memoryNotificationRepo) Find(ctx context.Context, userID string) (*domain.ExportNotificationSetting, error) {
+	setting, ok := r.settings[userID]
+	if !ok {
+		return nil, domain.ErrExportNotificationSettingNotFound
+	}
+	return setting, nil
+}
+
+func (r *memoryNotificationRepo) Save(ctx context.Context, setting *domain.ExportNotificationSetting) error {
+	r.settings[setting.UserID] = setting
+	return nil
+}
+
+type memoryMailer struct {
+	sent []*domain.MailMessage
+}
+
+func (m *memoryMailer) Send(message *domain.MailMessage) error {
+	m.sent = append(m.sent, message)
+	return nil
+}
+
+func TestExportNotification(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("files", 0o750))
+
+	var webhooks []domain.ExportNotification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		signature := service.WebhookSignature("webhook-secret", r.Header.Get("X-Export-Timestamp"), body)
+		require.Equal(t, "sha256="+signature, r.Header.Get("X-Export-Signature"))
+
+		var notification domain.ExportNotification
+		require.NoError(t, json.Unmarshal(body, &notification))
+		webhooks = append(webhooks, notification)
+	}))
+	defer server.Close()
+
+	cfg := &common.Config{
+		ExportPublicURL:           "https://exporter.pln.co.id/",
+		ExportWebhookSecret:       "webhook-secret",
+		ExportWebhookTimeout:      time.Second,
+		ExportWebhookAllowedHosts: []string{"127.0.0.1"},
+		ExportDownloadSecret:      "download-secret",
+		ExportDownloadExpiration:  time.Hour,
+		ExportRetryAttempts:       1,
+	}
+
+	settings := &memoryNotificationRepo{settings: map[string]*domain.ExportNotificationSetting{}}
+	mailer := &memoryMailer{}
+	notifier := service.NewExportNotifier(settings, mailer, cfg, zap.NewNop())
+
+	_, err := notifier.SaveSetting(context.Background(), "user-1", &request.ExportNotificationRequest{WebhookURL: server.URL, Email: "budi@pln.co.id"})
+	require.NoError(t, err)
+
+	repo := &flakyExporterRepo{failures: map[string]int{"21": 1}}
+	jobs := &memoryExportJobRepo{jobs: map[string]*domain.ExportJob{}}
+	s := newExporterService(service.ExporterParams{Repo: repo, Jobs: jobs, Notifier: notifier, Config: cfg})
+
+	run := func(id, requestedBy, area string) {
+		s.RunExportJob(t.Context(), &domain.ExportJob{
+			ID:          id,
+			Type:        domain.EXPORT_TYPE_TRANSAKSI,
+			RequestedBy: requestedBy,
+			Files:       []domain.ExportFile{},
+			Request:     &request.RekapRequest{Area: area, DateStart: "2026/02/01", DateEnd: "2026/02/28", Format: "csv"},
+		})
+		require.NoError(t, notifier.Wait(t.Context()))
+	}
+
+	t.Run("failed", func(t *testing.T) {
+		run("job-1", "user-1", "21")
+
+		require.Len(t, webhooks, 1)
+		require.Equal(t, "job-1", webhooks[0].JobID)
+		require.Equal(t, domain.EXPORT_JOB_FAILED, webhooks[0].Status)
+		require.Contains(t, webhooks[0].Error, "connection reset")
+		require.Empty(t, webhooks[0].Files)
+
+		require.Len(t, mailer.sent, 1)
+		require.Equal(t, "Export transaksi failed", mailer.sent[0].Subject)
+	})
+
+	t.Run("succeeded", func(t *testing.T) {
+		run("job-2", "user-1", "21")
+
+		require.Len(t, webhooks, 2)
+		require.Equal(t, domain.EXPORT_JOB_DONE, webhooks[1].Status)
+		require.Equal(t, int64(2), webhooks[1].Rows)
+		require.Len(t, webhooks[1].Files, 1)
+		require.Equal(t, "files/job-2/AREA_21_20260201_20260228.csv", webhooks[1].Files[0].Path)
+		require.True(t, strings.HasPrefix(webhooks[1].Files[0].URL, "https://exporter.pln.co.id/exports/download?"))
+
+		require.Len(t, mailer.sent, 2)
+		require.Equal(t, []string{"budi@pln.co.id"}, mailer.sent[1].To)
+		require.Contains(t, mailer.sent[1].Body, webhooks[1].Files[0].URL)
+	})
+
+	t.Run("requester without setting", func(t *testing.T) {
+		run("job-3", "user-2", "22")
+
+		require.Len(t, webhooks, 2)
+		require.Len(t, mailer.sent, 2)
+	})
+}
+
+func TestExportWebhookHosts(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer server.Close()
+
+	save := func(cfg *common.Config, url string) error {
+		settings := &memoryNotificationRepo{settings: map[string]*domain.ExportNotificationSetting{}}
+		notifier := service.NewExportNotifier(settings, nil, cfg, zap.NewNop())
+		_, err := notifier.SaveSetting(context.Background(), "user-1", &request.ExportNotificationRequest{WebhookURL: url})
+		return err
+	}
+
+	t.Run("internal addresses are refused", func(t *testing.T) {
+		cfg := &common.Config{ExportWebhookSecret: "webhook-secret", ExportWebhookTimeout: time.Second}
+
+		require.ErrorIs(t, save(cfg, server.URL), service.ErrWebhookHostNotAllowed)
+		require.ErrorIs(t, save(cfg, "http://169.254.169.254/latest/meta-data"), service.ErrWebhookHostNotAllowed)
+		require.ErrorIs(t, save(cfg, "http://10.0.0.8/hook"), service.ErrWebhookHostNotAllowed)
+		require.NoError(t, save(cfg, "https://hooks.pln.co.id/exports"))
+
+		// a name resolving to an internal address is refused when dialing
+		settings := &memoryNotificationRepo{settings: map[string]*domain.ExportNotificationSetting{
+			"user-1": {UserID: "user-1", WebhookURL: strings.Replace(server.URL, "127.0.0.1", "localhost", 1)},
+		}}
+		notifier := service.NewExportNotifier(settings, nil, cfg, zap.NewNop())
+		notifier.Notify(context.Background(), &domain.ExportNotification{JobID: "job-1", RequestedBy: "user-1"})
+		require.Zero(t, hits.Load())
+	})
+
+	t.Run("allowlist", func(t *testing.T) {
+		cfg := &common.Config{ExportWebhookAllowedHosts: []string{"hooks.pln.co.id"}}
+
+		require.NoError(t, save(cfg, "https://hooks.pln.co.id/exports"))
+		require.ErrorIs(t, save(cfg, "https://hooks.example.com/exports"), service.ErrWebhookHostNotAllowed)
+		require.ErrorIs(t, save(cfg, server.URL), service.ErrWebhookHostNotAllowed)
+	})
+}
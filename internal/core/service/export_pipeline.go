@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// progressEvery is how many written rows are batched into one progress
+// update.
+const progressEvery = 10000
+
+// rowOverheadBytes approximates the per value cost of an []interface{} row on
+// top of the string payload.
+const rowOverheadBytes = 16
+
+// memoryBudget blocks producers while the rows in flight between the database
+// and the writer use more than limit bytes.
+type memoryBudget struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	limit int
+	used  int
+}
+
+func newMemoryBudget(limit int) *memoryBudget {
+	b := &memoryBudget{limit: limit}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// acquire waits for n bytes of budget. A single row larger than the whole
+// budget is still let through once nothing else is in flight.
+func (b *memoryBudget) acquire(ctx context.Context, n int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for b.used > 0 && b.used+n > b.limit {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		b.cond.Wait()
+	}
+
+	b.used += n
+	return nil
+}
+
+func (b *memoryBudget) release(n int) {
+	b.mu.Lock()
+	b.used -= n
+	b.mu.Unlock()
+	b.cond.Broadcast()
+}
+
+// wake unblocks waiting producers so they can observe a cancelled context.
+func (b *memoryBudget) wake() {
+	b.mu.Lock()
+	b.mu.Unlock()
+	b.cond.Broadcast()
+}
+
+func rowSize(values []interface{}) int {
+	size := 0
+	for _, value := range values {
+		size += rowOverheadBytes
+		if str, ok := value.(string); ok {
+			size += len(str)
+		}
+	}
+	return size
+}
+
+type pipelineRow struct {
+	values []interface{}
+	size   int
+}
+
+// rowEmitter hands one mapped row to the writer stage, blocking while the
+// memory budget is used up.
+type rowEmitter func(values []interface{}) error
+
+// streamRows runs produce and the writer concurrently. produce reads rows
+// from the database and emits them into a bounded channel that feeds w, so
+// at most the configured memory budget is held between the two stages.
+// Cancelling ctx makes the next emit fail.
+func (s *ExporterService) streamRows(ctx context.Context, label string, w rowWriter, progress *ExportProgress, produce func(emit rowEmitter) error) (rows int, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	budget := newMemoryBudget(s.memoryBudgetBytes())
+	stop := context.AfterFunc(ctx, budget.wake)
+	defer stop()
+	ch := make(chan pipelineRow, 1024)
+
+	var fetchErr error
+	var fetched int
+	var fetchDuration time.Duration
+
+	go func() {
+		defer close(ch)
+
+		start := time.Now()
+		fetchErr = produce(func(values []interface{}) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			size := rowSize(values)
+			if err := budget.acquire(ctx, size); err != nil {
+				return err
+			}
+
+			select {
+			case ch <- pipelineRow{values: values, size: size}:
+				fetched++
+				return nil
+			case <-ctx.Done():
+				budget.release(size)
+				return ctx.Err()
+			}
+		})
+		fetchDuration = time.Since(start)
+	}()
+
+	start := time.Now()
+	var writeErr error
+	for row := range ch {
+		if writeErr == nil {
+			if writeErr = w.WriteRow(row.values); writeErr == nil {
+				rows++
+				if rows%progressEvery == 0 {
+					progress.AddRows(progressEvery)
+				}
+			} else {
+				// stop the producer, keep draining so it can exit
+				cancel()
+			}
+		}
+		budget.release(row.size)
+	}
+	writeDuration := time.Since(start)
+
+	progress.AddRows(rows % progressEvery)
+
+	s.logThroughput(label, "fetch", fetched, fetchDuration)
+	s.logThroughput(label, "write", rows, writeDuration)
+
+	if writeErr != nil {
+		return rows, writeErr
+	}
+
+	if fetchErr != nil {
+		return rows, fmt.Errorf("error fetch rows : %w", fetchErr)
+	}
+
+	return rows, nil
+}
+
+func (s *ExporterService) memoryBudgetBytes() int {
+	budget := s.config.ExportMemoryBudgetMB
+	if budget <= 0 {
+		budget = 64
+	}
+
+	return budget * 1024 * 1024
+}
+
+func (s *ExporterService) logThroughput(label, stage string, rows int, duration time.Duration) {
+	var rowsPerSec float64
+	if duration > 0 {
+		rowsPerSec = float64(rows) / duration.Seconds()
+	}
+
+	s.logger.Info(
+		"pipeline_throughput",
+		zap.String("file", label),
+		zap.String("stage", stage),
+		zap.Int("rows", rows),
+		zap.Duration("duration", duration),
+		zap.Float64("rows_per_sec", rowsPerSec),
+	)
+}
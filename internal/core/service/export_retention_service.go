@@ -0,0 +1,326 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"event-registration/internal/common"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+const (
+	retentionReasonMaxAge = "max_age"
+	retentionReasonQuota  = "quota"
+)
+
+var ErrExportRetentionForbidden = errors.New("export_retention_forbidden")
+
+// ExportRetentionService keeps files/ within a maximum age and a total size
+// quota. Files and folders of pinned jobs and of jobs still queued or
+// running are never removed.
+type ExportRetentionService struct {
+	jobs   domain.ExportJobRepository
+	config *common.Config
+	logger *zap.Logger
+	cron   *cron.Cron
+}
+
+func NewExportRetentionService(jobs domain.ExportJobRepository, config *common.Config, logger *zap.Logger) *ExportRetentionService {
+	return &ExportRetentionService{
+		jobs:   jobs,
+		config: config,
+		logger: logger,
+	}
+}
+
+func (s *ExportRetentionService) Start() error {
+	if s.config.ExportRetentionInterval <= 0 {
+		s.logger.Info("export_retention_disabled")
+		return nil
+	}
+
+	s.cron = cron.New()
+	s.cron.Schedule(cron.Every(s.config.ExportRetentionInterval), cron.FuncJob(func() {
+		s.RunRetention(context.Background(), s.config.ExportRetentionDryRun)
+	}))
+	s.cron.Start()
+
+	s.logger.Info(
+		"export_retention_started",
+		zap.Duration("interval", s.config.ExportRetentionInterval),
+		zap.Duration("max_age", s.config.ExportRetentionMaxAge),
+		zap.Int64("quota_mb", s.config.ExportRetentionQuotaMB),
+		zap.Bool("dry_run", s.config.ExportRetentionDryRun),
+	)
+
+	return nil
+}
+
+func (s *ExportRetentionService) Stop() {
+	if s.cron == nil {
+		return
+	}
+
+	<-s.cron.Stop().Done()
+}
+
+// TriggerRetention runs retention on request. Only requesters with one of
+// EXPORT_RETENTION_ROLES may, and files are only deleted when req asks to.
+func (s *ExportRetentionService) TriggerRetention(ctx context.Context, req *request.RetentionRequest, roles []string) (*domain.RetentionReport, error) {
+	if !s.canTrigger(roles) {
+		return nil, ErrExportRetentionForbidden
+	}
+
+	return s.RunRetention(ctx, !req.Delete)
+}
+
+func (s *ExportRetentionService) canTrigger(roles []string) bool {
+	for _, role := range roles {
+		for _, allowed := range s.config.ExportRetentionRoles {
+			if strings.EqualFold(role, allowed) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+type retentionCandidate struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// RunRetention deletes files older than the maximum age, then the oldest
+// files until files/ fits the quota. With dryRun nothing is deleted, the
+// report lists what would have been.
+func (s *ExportRetentionService) RunRetention(ctx context.Context, dryRun bool) (*domain.RetentionReport, error) {
+	now := time.Now()
+	report := &domain.RetentionReport{DryRun: dryRun, Deleted: []domain.RetentionFile{}}
+
+	protection, err := s.protection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []retentionCandidate
+	err = filepath.WalkDir(strings.TrimSuffix(filesDir, "/"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		report.ScannedFiles++
+		report.ScannedBytes += info.Size()
+
+		path = filepath.ToSlash(path)
+
+		// anything written since the oldest running job started may still
+		// be one of its parts
+		if protection.files[path] || (protection.activeSince != nil && !info.ModTime().Before(*protection.activeSince)) {
+			report.ProtectedFiles++
+			return nil
+		}
+
+		candidates = append(candidates, retentionCandidate{path: path, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		s.logger.Error(
+			"error_scan_files_dir",
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].modTime.Before(candidates[j].modTime)
+	})
+
+	remaining := report.ScannedBytes
+	quota := s.config.ExportRetentionQuotaMB * 1024 * 1024
+
+	for _, candidate := range candidates {
+		reason := ""
+		switch {
+		case s.config.ExportRetentionMaxAge > 0 && now.Sub(candidate.modTime) > s.config.ExportRetentionMaxAge:
+			reason = retentionReasonMaxAge
+		case quota > 0 && remaining > quota:
+			reason = retentionReasonQuota
+		default:
+			continue
+		}
+
+		if !s.deleteFile(candidate, reason, dryRun) {
+			continue
+		}
+
+		remaining -= candidate.size
+		report.DeletedBytes += candidate.size
+		report.Deleted = append(report.Deleted, domain.RetentionFile{
+			Path:    candidate.path,
+			Size:    candidate.size,
+			ModTime: candidate.modTime,
+			Reason:  reason,
+		})
+	}
+
+	report.RemainingBytes = remaining
+
+	if !dryRun {
+		s.removeEmptyDirs(ctx)
+	}
+
+	s.logger.Info(
+		"export_retention_finished",
+		zap.Bool("dry_run", dryRun),
+		zap.Int("scanned_files", report.ScannedFiles),
+		zap.Int("deleted_files", len(report.Deleted)),
+		zap.Int64("deleted_bytes", report.DeletedBytes),
+		zap.Int64("remaining_bytes", report.RemainingBytes),
+	)
+
+	return report, nil
+}
+
+func (s *ExportRetentionService) deleteFile(candidate retentionCandidate, reason string, dryRun bool) bool {
+	fields := []zap.Field{
+		zap.String("path", candidate.path),
+		zap.Int64("size", candidate.size),
+		zap.Time("mod_time", candidate.modTime),
+		zap.String("reason", reason),
+	}
+
+	if dryRun {
+		s.logger.Info("retention_would_delete", fields...)
+		return true
+	}
+
+	if err := validateFilesPath(candidate.path); err != nil {
+		s.logger.Error("invalid_retention_path", append(fields, zap.Error(err))...)
+		return false
+	}
+
+	if err := os.Remove(candidate.path); err != nil {
+		s.logger.Error("error_retention_delete", append(fields, zap.Error(err))...)
+		return false
+	}
+
+	s.logger.Info("retention_deleted", fields...)
+	return true
+}
+
+// retentionProtection is what a retention run must leave in place.
+type retentionProtection struct {
+	// files of pinned and active jobs
+	files map[string]bool
+	// folders of pinned and active jobs, an active job's folder may still
+	// be empty while its first part is being queried
+	dirs map[string]bool
+	// start of the oldest active job
+	activeSince *time.Time
+}
+
+// protection lists the files and folders of pinned and active jobs.
+func (s *ExportRetentionService) protection(ctx context.Context) (*retentionProtection, error) {
+	pinned, err := s.jobs.FindPinned(ctx)
+	if err != nil {
+		s.logger.Error(
+			"error_find_pinned_export_jobs",
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	active, err := s.jobs.FindActive(ctx)
+	if err != nil {
+		s.logger.Error(
+			"error_find_active_export_jobs",
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	protection := &retentionProtection{files: map[string]bool{}, dirs: map[string]bool{}}
+	for _, job := range append(pinned, active...) {
+		protection.dirs[strings.TrimSuffix(exportJobDir(job.ID), "/")] = true
+
+		for _, file := range append(append([]domain.ExportFile{}, job.Files...), job.Archives...) {
+			protection.files[filepath.ToSlash(filepath.Clean(file.Path))] = true
+		}
+	}
+
+	for _, job := range active {
+		since := job.CreatedAt
+		if protection.activeSince == nil || since.Before(*protection.activeSince) {
+			protection.activeSince = &since
+		}
+	}
+
+	return protection, nil
+}
+
+// removeEmptyDirs drops the job and unit folders left empty by deletions.
+// Jobs are listed again so the folder of a job started during the run is
+// kept too.
+func (s *ExportRetentionService) removeEmptyDirs(ctx context.Context) {
+	protection, err := s.protection(ctx)
+	if err != nil {
+		return
+	}
+
+	s.removeEmptySubdirs(strings.TrimSuffix(filesDir, "/"), protection.dirs)
+}
+
+// removeEmptySubdirs removes the empty folders below parent, deepest first so
+// a job folder holding only empty unit folders goes too.
+func (s *ExportRetentionService) removeEmptySubdirs(parent string, protected map[string]bool) {
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		dir := parent + "/" + entry.Name()
+		if protected[dir] {
+			continue
+		}
+
+		s.removeEmptySubdirs(dir, protected)
+
+		children, err := os.ReadDir(dir)
+		if err != nil || len(children) > 0 {
+			continue
+		}
+
+		if err := os.Remove(dir); err != nil {
+			s.logger.Error(
+				"error_remove_empty_dir",
+				zap.String("dir", dir),
+				zap.Error(err),
+			)
+		}
+	}
+}
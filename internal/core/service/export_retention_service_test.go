@@ -0,0 +1,96 @@
+package service_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"event-registration/internal/common"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"event-registration/internal/core/service"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func writeAgedFile(t *testing.T, path string, size int, age time.Duration) {
+	require.NoError(t, os.WriteFile(path, make([]byte, size), 0o600))
+	modTime := time.Now().Add(-age)
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestRunRetention(t *testing.T) {
+	setup := func(t *testing.T) *service.ExportRetentionService {
+		t.Chdir(t.TempDir())
+		require.NoError(t, os.MkdirAll("files/UP3_MENTENG", 0o750))
+		// the running job is still querying its first part
+		require.NoError(t, os.MkdirAll("files/running/UNIT_54110", 0o750))
+		require.NoError(t, os.MkdirAll("files/done", 0o750))
+
+		writeAgedFile(t, "files/EXPIRED.xlsx", 1024, 40*24*time.Hour)
+		writeAgedFile(t, "files/PINNED.xlsx", 1024, 40*24*time.Hour)
+		writeAgedFile(t, "files/UP3_MENTENG/OLDEST.csv", 1024*1024, 10*24*time.Hour)
+		writeAgedFile(t, "files/NEWER.csv", 1024*1024, 5*24*time.Hour)
+		writeAgedFile(t, "files/RUNNING_PART_1.xlsx", 1024*1024, time.Minute)
+
+		repo := &memoryExportJobRepo{jobs: map[string]*domain.ExportJob{
+			"pinned":  {ID: "pinned", Status: domain.EXPORT_JOB_DONE, Pinned: true, Files: []domain.ExportFile{{Path: "files/PINNED.xlsx"}}},
+			"running": {ID: "running", Status: domain.EXPORT_JOB_RUNNING, CreatedAt: time.Now().Add(-time.Hour)},
+		}}
+
+		cfg := &common.Config{ExportRetentionMaxAge: 30 * 24 * time.Hour, ExportRetentionQuotaMB: 3, ExportRetentionRoles: []string{"admin"}}
+		return service.NewExportRetentionService(repo, cfg, zap.NewNop())
+	}
+
+	t.Run("deletes expired then oldest over quota", func(t *testing.T) {
+		s := setup(t)
+
+		report, err := s.RunRetention(context.Background(), false)
+		require.NoError(t, err)
+		require.Equal(t, 2, report.ProtectedFiles)
+
+		require.Len(t, report.Deleted, 2)
+		require.Equal(t, "files/EXPIRED.xlsx", report.Deleted[0].Path)
+		require.Equal(t, "max_age", report.Deleted[0].Reason)
+		require.Equal(t, "files/UP3_MENTENG/OLDEST.csv", report.Deleted[1].Path)
+		require.Equal(t, "quota", report.Deleted[1].Reason)
+
+		require.NoFileExists(t, "files/EXPIRED.xlsx")
+		require.NoDirExists(t, "files/UP3_MENTENG")
+		require.FileExists(t, "files/PINNED.xlsx")
+		require.FileExists(t, "files/NEWER.csv")
+		require.FileExists(t, "files/RUNNING_PART_1.xlsx")
+		require.DirExists(t, "files/running/UNIT_54110")
+		require.NoDirExists(t, "files/done")
+	})
+
+	t.Run("dry run keeps files", func(t *testing.T) {
+		s := setup(t)
+
+		report, err := s.RunRetention(context.Background(), true)
+		require.NoError(t, err)
+		require.Len(t, report.Deleted, 2)
+		require.FileExists(t, "files/EXPIRED.xlsx")
+		require.FileExists(t, "files/UP3_MENTENG/OLDEST.csv")
+	})
+	t.Run("triggered by request", func(t *testing.T) {
+		s := setup(t)
+
+		_, err := s.TriggerRetention(context.Background(), &request.RetentionRequest{Delete: true}, []string{"viewer"})
+		require.ErrorIs(t, err, service.ErrExportRetentionForbidden)
+		require.FileExists(t, "files/EXPIRED.xlsx")
+
+		// a dry run unless deleting is asked for
+		report, err := s.TriggerRetention(context.Background(), &request.RetentionRequest{}, []string{"Admin"})
+		require.NoError(t, err)
+		require.True(t, report.DryRun)
+		require.FileExists(t, "files/EXPIRED.xlsx")
+
+		report, err = s.TriggerRetention(context.Background(), &request.RetentionRequest{Delete: true}, []string{"admin"})
+		require.NoError(t, err)
+		require.False(t, report.DryRun)
+		require.NoFileExists(t, "files/EXPIRED.xlsx")
+	})
+}
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"event-registration/internal/common"
+	"event-registration/internal/common/helper"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"fmt"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+const scheduleRunHistory = 50
+
+var ErrInvalidCronExpr = errors.New("invalid_cron_expr")
+
+// ExportScheduleService runs the export schedules stored in the database.
+// Every minute it looks for schedules whose cron expression fired since
+// their last run and exports their date window through ExporterService.
+type ExportScheduleService struct {
+	exporter *ExporterService
+	repo     domain.ExportScheduleRepository
+	config   *common.Config
+	logger   *zap.Logger
+	location *time.Location
+	cron     *cron.Cron
+}
+
+func NewExportScheduleService(exporter *ExporterService, repo domain.ExportScheduleRepository, config *common.Config, logger *zap.Logger) *ExportScheduleService {
+	location, err := time.LoadLocation(config.ExportScheduleTimezone)
+	if err != nil {
+		logger.Warn(
+			"invalid_schedule_timezone",
+			zap.String("timezone", config.ExportScheduleTimezone),
+			zap.Error(err),
+		)
+		location = time.Local
+	}
+
+	return &ExportScheduleService{
+		exporter: exporter,
+		repo:     repo,
+		config:   config,
+		logger:   logger,
+		location: location,
+	}
+}
+
+func (s *ExportScheduleService) Start() error {
+	if !s.config.ExportSchedulerEnabled {
+		s.logger.Info("export_scheduler_disabled")
+		return nil
+	}
+
+	s.cron = cron.New(cron.WithLocation(s.location))
+	if _, err := s.cron.AddFunc("@every 1m", func() { s.RunDue(context.Background(), time.Now()) }); err != nil {
+		return err
+	}
+
+	s.cron.Start()
+
+	s.logger.Info(
+		"export_scheduler_started",
+		zap.String("timezone", s.location.String()),
+	)
+
+	return nil
+}
+
+// Stop stops looking for due schedules. Runs already started are left to
+// finish with the process.
+func (s *ExportScheduleService) Stop() {
+	if s.cron == nil {
+		return
+	}
+
+	<-s.cron.Stop().Done()
+}
+
+// RunDue starts every enabled schedule that is due at now.
+func (s *ExportScheduleService) RunDue(ctx context.Context, now time.Time) {
+	schedules, err := s.repo.FindEnabled(ctx)
+	if err != nil {
+		s.logger.Error(
+			"error_find_enabled_schedules",
+			zap.Error(err),
+		)
+		return
+	}
+
+	for _, schedule := range schedules {
+		parsed, err := cron.ParseStandard(schedule.CronExpr)
+		if err != nil {
+			s.logger.Error(
+				"invalid_schedule_cron_expr",
+				zap.String("schedule_id", schedule.ID),
+				zap.String("cron_expr", schedule.CronExpr),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		from := schedule.CreatedAt
+		if schedule.LastRunAt != nil {
+			from = *schedule.LastRunAt
+		}
+
+		if parsed.Next(from.In(s.location)).After(now) {
+			continue
+		}
+
+		claimed, err := s.repo.ClaimRun(ctx, schedule.ID, schedule.LastRunAt, now)
+		if err != nil {
+			s.logger.Error(
+				"error_claim_schedule_run",
+				zap.String("schedule_id", schedule.ID),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		if !claimed {
+			continue
+		}
+
+		go s.RunSchedule(ctx, schedule, now)
+	}
+}
+
+// RunSchedule exports the schedule's window as of now and records the run.
+func (s *ExportScheduleService) RunSchedule(ctx context.Context, schedule *domain.ExportSchedule, now time.Time) *domain.ExportScheduleRun {
+	run := &domain.ExportScheduleRun{
+		ID:         helper.GenerateUUID(),
+		ScheduleID: schedule.ID,
+		Status:     domain.EXPORT_JOB_RUNNING,
+		StartedAt:  now,
+	}
+
+	s.logger.Info(
+		"schedule_run_started",
+		zap.String("schedule_id", schedule.ID),
+		zap.String("name", schedule.Name),
+	)
+
+	dateStart, dateEnd, err := ScheduleWindow(schedule.Window, now.In(s.location))
+	if err != nil {
+		return s.finishRun(ctx, run, err)
+	}
+
+	run.DateStart, run.DateEnd = dateStart, dateEnd
+
+	req := &request.RekapRequest{
+		UnitCode:      schedule.UnitCode,
+		Area:          schedule.Area,
+		Induk:         schedule.Induk,
+		IsDBPlnMobile: schedule.IsDBPlnMobile,
+		DateStart:     dateStart,
+		DateEnd:       dateEnd,
+		Format:        schedule.Format,
+		MaskingPolicy: schedule.MaskingPolicy,
+	}
+
+	job, err := s.exporter.createExportJob(ctx, schedule.ExportType, req, schedule.CreatedBy)
+	if err != nil {
+		return s.finishRun(ctx, run, err)
+	}
+
+	// saved before the job starts, the job records its outcome on the run
+	// once it finishes
+	run.JobID = job.ID
+	run.Status = job.Status
+	s.saveRun(ctx, run)
+
+	if s.exporter.queue == nil {
+		s.exporter.runTracked(job)
+	} else if err := s.exporter.startExportJob(ctx, job); err != nil {
+		return s.finishRun(ctx, run, err)
+	}
+
+	run.Status = job.Status
+	run.Rows = job.RowsWritten
+	run.Error = job.Error
+	run.FinishedAt = job.FinishedAt
+
+	s.logger.Info(
+		"schedule_run_job_started",
+		zap.String("schedule_id", run.ScheduleID),
+		zap.String("job_id", run.JobID),
+		zap.String("status", run.Status),
+	)
+
+	return run
+}
+
+func (s *ExportScheduleService) finishRun(ctx context.Context, run *domain.ExportScheduleRun, err error) *domain.ExportScheduleRun {
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+
+	if err != nil {
+		run.Status = domain.EXPORT_JOB_FAILED
+		run.Error = err.Error()
+	}
+
+	s.saveRun(ctx, run)
+
+	s.logger.Info(
+		"schedule_run_finished",
+		zap.String("schedule_id", run.ScheduleID),
+		zap.String("job_id", run.JobID),
+		zap.String("status", run.Status),
+	)
+
+	return run
+}
+
+// finishScheduleRun records a finished job on the schedule run that
+// created it, whichever process ran the job.
+func (s *ExporterService) finishScheduleRun(ctx context.Context, job *domain.ExportJob) {
+	if s.schedules == nil {
+		return
+	}
+
+	if err := s.schedules.FinishJobRun(ctx, job); err != nil {
+		s.logger.Error(
+			"error_finish_schedule_run",
+			zap.String("job_id", job.ID),
+			zap.Error(err),
+		)
+	}
+}
+
+func (s *ExportScheduleService) saveRun(ctx context.Context, run *domain.ExportScheduleRun) {
+	if err := s.repo.SaveRun(ctx, run); err != nil {
+		s.logger.Error(
+			"error_save_schedule_run",
+			zap.String("schedule_id", run.ScheduleID),
+			zap.Error(err),
+		)
+	}
+}
+
+func (s *ExportScheduleService) CreateSchedule(ctx context.Context, req *request.ExportScheduleRequest, createdBy string, roles []string) (*domain.ExportSchedule, error) {
+	if _, err := cron.ParseStandard(req.CronExpr); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCronExpr, err.Error())
+	}
+
+	schedule := &domain.ExportSchedule{
+		ID:            helper.GenerateUUID(),
+		Name:          req.Name,
+		CronExpr:      req.CronExpr,
+		Window:        req.Window,
+		ExportType:    req.ExportType,
+		Induk:         req.Induk,
+		Area:          req.Area,
+		UnitCode:      req.UnitCode,
+		IsDBPlnMobile: req.IsDBPlnMobile,
+		Format:        req.Format,
+		Enabled:       true,
+		CreatedBy:     createdBy,
+		// runs are masked with the creator's policy at creation time
+		MaskingPolicy: s.exporter.MaskingPolicyFor(roles),
+	}
+
+	if err := s.repo.Create(ctx, schedule); err != nil {
+		s.logger.Error(
+			"error_create_schedule",
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	return schedule, nil
+}
+
+func (s *ExportScheduleService) FindSchedules(ctx context.Context) ([]*domain.ExportSchedule, error) {
+	schedules, err := s.repo.FindAll(ctx)
+	if err != nil {
+		s.logger.Error(
+			"error_find_schedules",
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+// FindScheduleRuns returns the latest runs of a schedule, newest first.
+func (s *ExportScheduleService) FindScheduleRuns(ctx context.Context, id string) ([]*domain.ExportScheduleRun, error) {
+	if _, err := s.repo.FindByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	runs, err := s.repo.FindRuns(ctx, id, scheduleRunHistory)
+	if err != nil {
+		s.logger.Error(
+			"error_find_schedule_runs",
+			zap.String("schedule_id", id),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+// ScheduleWindow resolves a relative window into the date_start and date_end
+// of a RekapRequest, both inclusive.
+func ScheduleWindow(window string, now time.Time) (dateStart, dateEnd string, err error) {
+	const layout = "2006/01/02"
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch window {
+	case domain.SCHEDULE_WINDOW_PREVIOUS_DAY:
+		yesterday := today.AddDate(0, 0, -1)
+		return yesterday.Format(layout), yesterday.Format(layout), nil
+	case domain.SCHEDULE_WINDOW_LAST_7_DAYS:
+		return today.AddDate(0, 0, -7).Format(layout), today.AddDate(0, 0, -1).Format(layout), nil
+	case domain.SCHEDULE_WINDOW_PREVIOUS_MONTH:
+		firstOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return firstOfMonth.AddDate(0, -1, 0).Format(layout), firstOfMonth.AddDate(0, 0, -1).Format(layout), nil
+	default:
+		return "", "", fmt.Errorf("unknown schedule window: %s", window)
+	}
+}
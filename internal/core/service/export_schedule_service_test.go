@@ -0,0 +1,112 @@
+package service_test
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"event-registration/internal/common"
+	"event-registration/internal/core/domain"
+	"event-registration/internal/core/service"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestScheduleWindow(t *testing.T) {
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	require.NoError(t, err)
+
+	now := time.Date(2026, time.March, 1, 2, 0, 0, 0, jakarta)
+
+	tests := []struct {
+		window    string
+		dateStart string
+		dateEnd   string
+	}{
+		{domain.SCHEDULE_WINDOW_PREVIOUS_DAY, "2026/02/28", "2026/02/28"},
+		{domain.SCHEDULE_WINDOW_LAST_7_DAYS, "2026/02/22", "2026/02/28"},
+		{domain.SCHEDULE_WINDOW_PREVIOUS_MONTH, "2026/02/01", "2026/02/28"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.window, func(t *testing.T) {
+			dateStart, dateEnd, err := service.ScheduleWindow(tt.window, now)
+			require.NoError(t, err)
+			require.Equal(t, tt.dateStart, dateStart)
+			require.Equal(t, tt.dateEnd, dateEnd)
+		})
+	}
+
+	_, _, err = service.ScheduleWindow("next_year", now)
+	require.Error(t, err)
+}
+
+// memoryScheduleRepo keeps schedule runs in memory, the schedule methods
+// are left to the embedded interface.
+type memoryScheduleRepo struct {
+	domain.ExportScheduleRepository
+	mu   sync.Mutex
+	runs map[string]*domain.ExportScheduleRun
+}
+
+func (r *memoryScheduleRepo) SaveRun(ctx context.Context, run *domain.ExportScheduleRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	saved := *run
+	r.runs[run.ID] = &saved
+	return nil
+}
+
+func (r *memoryScheduleRepo) FinishJobRun(ctx context.Context, job *domain.ExportJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, run := range r.runs {
+		if run.JobID == job.ID {
+			run.Status = job.Status
+			run.Rows = job.RowsWritten
+			run.Error = job.Error
+			run.FinishedAt = job.FinishedAt
+		}
+	}
+	return nil
+}
+
+func TestRunScheduleRecordsJobOutcome(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("files", 0o750))
+
+	schedules := &memoryScheduleRepo{runs: map[string]*domain.ExportScheduleRun{}}
+	jobs := &memoryExportJobRepo{jobs: map[string]*domain.ExportJob{}}
+	cfg := &common.Config{}
+	exporter := newExporterService(service.ExporterParams{Repo: &cursorRecordingRepo{}, Jobs: jobs, Queue: newMemoryExportQueue(), Schedules: schedules, Config: cfg})
+	scheduler := service.NewExportScheduleService(exporter, schedules, cfg, zap.NewNop())
+
+	schedule := &domain.ExportSchedule{
+		ID:         "schedule-1",
+		Window:     domain.SCHEDULE_WINDOW_PREVIOUS_DAY,
+		ExportType: domain.EXPORT_TYPE_TRANSAKSI,
+		UnitCode:   "54110",
+		Format:     domain.EXPORT_FORMAT_CSV,
+		CreatedBy:  "user-1",
+	}
+	run := scheduler.RunSchedule(context.Background(), schedule, time.Date(2026, time.March, 1, 2, 0, 0, 0, time.UTC))
+
+	// the job only got queued, the run is not over yet
+	require.Equal(t, domain.EXPORT_JOB_QUEUED, run.Status)
+	require.Nil(t, run.FinishedAt)
+	require.Equal(t, domain.EXPORT_JOB_QUEUED, schedules.runs[run.ID].Status)
+
+	// a worker runs the job later
+	require.NoError(t, exporter.ProcessExportJob(context.Background(), run.JobID))
+
+	saved := schedules.runs[run.ID]
+	require.Equal(t, domain.EXPORT_JOB_DONE, saved.Status)
+	require.Equal(t, jobs.jobs[run.JobID].RowsWritten, saved.Rows)
+	require.Positive(t, saved.Rows)
+	require.NotNil(t, saved.FinishedAt)
+}
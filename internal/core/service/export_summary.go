@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+	"go.uber.org/zap"
+)
+
+const summarySheetName = "Ringkasan"
+
+// summarySheet holds the aggregates rendered into the "Ringkasan" sheet of a
+// transaksi workbook.
+type summarySheet struct {
+	period string
+	parts  int
+	rows   []*domain.TransaksiSummary
+}
+
+// datasetSummary loads the aggregates of req for datasets that have them.
+// Only xlsx has room for a second sheet, other formats get nil.
+func (s *ExporterService) datasetSummary(ctx context.Context, dataset domain.ExportDataset, req *request.RekapRequest, parts int) (*summarySheet, error) {
+	summarized, ok := dataset.(summarizedDataset)
+	if !ok || !splitsFiles(req.Format) {
+		return nil, nil
+	}
+
+	rows, err := summarized.Summarize(ctx, req)
+	if err != nil {
+		s.logger.Error(
+			"error_summarize_transaksi",
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	return &summarySheet{
+		period: req.DateStart + " - " + req.DateEnd,
+		parts:  parts,
+		rows:   rows,
+	}, nil
+}
+
+type summarySection struct {
+	title     string
+	dimension string
+	headers   []string
+	values    func(row *domain.TransaksiSummary) []interface{}
+}
+
+var summarySections = []summarySection{
+	{
+		title:     "Per Unit",
+		dimension: domain.SUMMARY_DIMENSION_UNIT,
+		headers:   []string{"Unit UPI", "Nama Unit UPI", "Unit AP", "Nama Unit AP", "Unit UP", "Nama Unit UP", "Jumlah Transaksi", "Total Nominal"},
+		values: func(row *domain.TransaksiSummary) []interface{} {
+			return []interface{}{row.UnitUPI, row.NameUnitUpi, row.UnitAP, row.NameUnitAP, row.UnitUP, row.NameUnitUP, row.Count, row.TotalAmount}
+		},
+	},
+	{
+		title:     "Per Payment Gateway",
+		dimension: domain.SUMMARY_DIMENSION_PAYMENT_GATEWAY,
+		headers:   []string{"Payment Gateway", "Jumlah Transaksi", "Total Nominal"},
+		values: func(row *domain.TransaksiSummary) []interface{} {
+			return []interface{}{row.PaymentGateway, row.Count, row.TotalAmount}
+		},
+	},
+	{
+		title:     "Per Jenis Transaksi",
+		dimension: domain.SUMMARY_DIMENSION_TYPE,
+		headers:   []string{"Jenis Transaksi", "Jumlah Transaksi", "Total Nominal"},
+		values: func(row *domain.TransaksiSummary) []interface{} {
+			return []interface{}{row.Type, row.Count, row.TotalAmount}
+		},
+	},
+	{
+		title:     "Per Status",
+		dimension: domain.SUMMARY_DIMENSION_STATUS_CODE,
+		headers:   []string{"Status", "Jumlah Transaksi", "Total Nominal"},
+		values: func(row *domain.TransaksiSummary) []interface{} {
+			return []interface{}{row.StatusCode, row.Count, row.TotalAmount}
+		},
+	},
+}
+
+// writeSummarySheet adds the "Ringkasan" sheet to f: the grand total, then
+// one table per dimension.
+func writeSummarySheet(f *excelize.File, summary *summarySheet) error {
+	if _, err := f.NewSheet(summarySheetName); err != nil {
+		return err
+	}
+
+	sw, err := f.NewStreamWriter(summarySheetName)
+	if err != nil {
+		return err
+	}
+
+	headerStyle, err := f.NewStyle(&HeaderStyle)
+	if err != nil {
+		return err
+	}
+
+	titleStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Size: 12, Bold: true}})
+	if err != nil {
+		return err
+	}
+
+	if err := sw.SetColWidth(1, 8, 22); err != nil {
+		return err
+	}
+
+	rowIndex := 1
+	setRow := func(values []interface{}) error {
+		cell, err := excelize.CoordinatesToCellName(1, rowIndex)
+		if err != nil {
+			return err
+		}
+		rowIndex++
+		return sw.SetRow(cell, values)
+	}
+
+	var total domain.TransaksiSummary
+	for _, row := range summary.rows {
+		if row.Dimension == domain.SUMMARY_DIMENSION_TOTAL {
+			total = *row
+		}
+	}
+
+	lines := [][]interface{}{
+		{excelize.Cell{StyleID: titleStyle, Value: "Ringkasan Transaksi"}},
+		{"Periode", summary.period},
+		{"Jumlah Transaksi", total.Count},
+		{"Total Nominal", total.TotalAmount},
+	}
+	if summary.parts > 1 {
+		lines = append(lines, []interface{}{"Catatan", fmt.Sprintf("Ringkasan mencakup seluruh %d part", summary.parts)})
+	}
+
+	for _, line := range lines {
+		if err := setRow(line); err != nil {
+			return err
+		}
+	}
+
+	for _, section := range summarySections {
+		rowIndex++
+
+		if err := setRow([]interface{}{excelize.Cell{StyleID: titleStyle, Value: section.title}}); err != nil {
+			return err
+		}
+
+		headers := make([]interface{}, len(section.headers))
+		for i, header := range section.headers {
+			headers[i] = excelize.Cell{StyleID: headerStyle, Value: header}
+		}
+		if err := setRow(headers); err != nil {
+			return err
+		}
+
+		for _, row := range summary.rows {
+			if row.Dimension != section.dimension {
+				continue
+			}
+			if err := setRow(section.values(row)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return sw.Flush()
+}
@@ -0,0 +1,34 @@
+package service_test
+
+import (
+	"os"
+	"testing"
+
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/service"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestExportRekapTransaksiSummarySheet(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("files", 0o750))
+
+	s := newExporterService(service.ExporterParams{Repo: &flakyExporterRepo{}})
+
+	req := &request.RekapRequest{UnitCode: "54110", DateStart: "2026/02/01", DateEnd: "2026/02/28"}
+	require.NoError(t, s.ExportRekapTransaksi(t.Context(), req, nil))
+
+	f, err := excelize.OpenFile("files/UNIT_54110_20260201_20260228.xlsx")
+	require.NoError(t, err)
+	defer f.Close()
+
+	require.Equal(t, []string{"Sheet1", "Ringkasan"}, f.GetSheetList())
+
+	rows, err := f.GetRows("Ringkasan")
+	require.NoError(t, err)
+	require.Equal(t, []string{"Periode", "2026/02/01 - 2026/02/28"}, rows[1])
+	require.Equal(t, []string{"Jumlah Transaksi", "2"}, rows[2])
+	require.Contains(t, rows, []string{"BRI", "2", "40000"})
+}
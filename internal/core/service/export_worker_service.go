@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"event-registration/internal/core/domain"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+type drainKey struct{}
+
+// withDrain returns a context whose exports stop after the file part they
+// are writing once drain is closed, instead of being cancelled halfway.
+func withDrain(ctx context.Context, drain <-chan struct{}) context.Context {
+	return context.WithValue(ctx, drainKey{}, drain)
+}
+
+// drainSignal returns the drain channel of ctx, nil outside a worker.
+func drainSignal(ctx context.Context) <-chan struct{} {
+	drain, _ := ctx.Value(drainKey{}).(<-chan struct{})
+	return drain
+}
+
+func draining(ctx context.Context) bool {
+	drain := drainSignal(ctx)
+	if drain == nil {
+		return false
+	}
+
+	select {
+	case <-drain:
+		return true
+	default:
+		return false
+	}
+}
+
+// ProcessExportJob runs a job taken off the export queue. The job is claimed
+// first. A message redelivered while the job is claimed waits for the lease
+// to run out, since its worker likely died, and goes back to the queue if
+// another worker still holds it. Jobs that already finished are skipped, so
+// a redelivered message does not export twice.
+func (s *ExporterService) ProcessExportJob(ctx context.Context, id string) error {
+	claimed, release, err := s.claimExportJob(ctx, id)
+	if errors.Is(err, domain.ErrExportJobClaimed) {
+		s.logger.Info(
+			"export_job_claimed_elsewhere",
+			zap.String("job_id", id),
+		)
+		claimed, release, err = s.awaitExportJobLease(ctx, id)
+	}
+	if errors.Is(err, domain.ErrExportJobClaimed) || errors.Is(err, domain.ErrExportInterrupted) {
+		return err
+	}
+	if err != nil {
+		s.logger.Error(
+			"error_claim_export_job",
+			zap.String("job_id", id),
+			zap.Error(err),
+		)
+		return err
+	}
+	defer release()
+	ctx = claimed
+
+	job, err := s.findExportJob(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if job.Status != domain.EXPORT_JOB_QUEUED && job.Status != domain.EXPORT_JOB_RUNNING {
+		s.logger.Info(
+			"export_job_already_finished",
+			zap.String("job_id", job.ID),
+			zap.String("status", job.Status),
+		)
+		return nil
+	}
+
+	// holding the lease, a running job was left behind by a worker that died
+	resetExportJob(job)
+
+	s.RunExportJob(ctx, job)
+
+	if job.Status == domain.EXPORT_JOB_QUEUED {
+		return domain.ErrExportInterrupted
+	}
+
+	return nil
+}
+
+// ExportWorkerService consumes the export queue in the export worker
+// process. Stop lets the jobs being run finish their current file part and
+// puts them back on the queue.
+type ExportWorkerService struct {
+	exporter *ExporterService
+	queue    domain.ExportQueue
+	logger   *zap.Logger
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	drain    chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func NewExportWorkerService(exporter *ExporterService, queue domain.ExportQueue, logger *zap.Logger) *ExportWorkerService {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &ExportWorkerService{
+		exporter: exporter,
+		queue:    queue,
+		logger:   logger,
+		ctx:      ctx,
+		cancel:   cancel,
+		drain:    make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Run consumes export messages until Stop is called or the queue connection
+// is lost.
+func (w *ExportWorkerService) Run() error {
+	defer close(w.done)
+
+	w.logger.Info("export_worker_started")
+
+	if err := w.queue.Consume(w.ctx, w.handle); err != nil {
+		w.logger.Error(
+			"error_consume_exports",
+			zap.Error(err),
+		)
+		return err
+	}
+
+	w.logger.Info("export_worker_stopped")
+
+	return nil
+}
+
+func (w *ExportWorkerService) handle(message *domain.ExportMessage) error {
+	w.logger.Info(
+		"export_message_received",
+		zap.String("job_id", message.JobID),
+	)
+
+	return w.exporter.ProcessExportJob(withDrain(context.Background(), w.drain), message.JobID)
+}
+
+// Stop stops taking messages and waits, until ctx is done, for the running
+// jobs to reach the end of their file part.
+func (w *ExportWorkerService) Stop(ctx context.Context) error {
+	w.stopOnce.Do(func() {
+		close(w.drain)
+		w.cancel()
+	})
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
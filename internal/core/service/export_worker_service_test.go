@@ -0,0 +1,255 @@
+package service_test
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"event-registration/internal/common"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"event-registration/internal/core/service"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// memoryExportQueue hands the messages sent to deliveries to the consumer
+// and reports what each one was settled with. stopping is closed once the
+// consumer is told to stop.
+type memoryExportQueue struct {
+	mu         sync.Mutex
+	published  []*domain.ExportMessage
+	deliveries chan *domain.ExportMessage
+	settled    chan error
+	stopping   chan struct{}
+}
+
+func newMemoryExportQueue() *memoryExportQueue {
+	return &memoryExportQueue{
+		deliveries: make(chan *domain.ExportMessage),
+		settled:    make(chan error, 1),
+		stopping:   make(chan struct{}),
+	}
+}
+
+func (q *memoryExportQueue) Publish(message *domain.ExportMessage) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.published = append(q.published, message)
+	return nil
+}
+
+func (q *memoryExportQueue) Consume(ctx context.Context, handle func(message *domain.ExportMessage) error) error {
+	go func() {
+		<-ctx.Done()
+		close(q.stopping)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case message := <-q.deliveries:
+			q.settled <- handle(message)
+		}
+	}
+}
+
+func (q *memoryExportQueue) Close() error {
+	return nil
+}
+
+// stallingExporterRepo holds the first part of an export until the worker
+// is told to stop.
+type stallingExporterRepo struct {
+	cursorRecordingRepo
+	started chan struct{}
+	stop    <-chan struct{}
+}
+
+func (r *stallingExporterRepo) StreamTransaksi(ctx context.Context, req *request.RekapRequest, after *domain.ExportCursor, limit int, fn func(*domain.Transaksi) error) error {
+	if len(r.cursors) == 0 {
+		close(r.started)
+		<-r.stop
+	}
+	return r.cursorRecordingRepo.StreamTransaksi(ctx, req, after, limit, fn)
+}
+
+func newQueuedExporter(t *testing.T, repo domain.ExporterRepository, queue domain.ExportQueue) (*service.ExporterService, *memoryExportJobRepo, *memoryCheckpointRepo) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("files", 0o750))
+
+	jobs := &memoryExportJobRepo{jobs: map[string]*domain.ExportJob{}}
+	checkpoints := &memoryCheckpointRepo{checkpoints: map[string]*domain.ExportCheckpoint{}}
+
+	cfg := &common.Config{ExportJobLease: 30 * time.Millisecond}
+	s := newExporterService(service.ExporterParams{Repo: repo, Jobs: jobs, Checkpoints: checkpoints, Queue: queue, Config: cfg})
+	return s, jobs, checkpoints
+}
+
+func TestExportWorker(t *testing.T) {
+	queue := newMemoryExportQueue()
+	repo := &cursorRecordingRepo{}
+	s, jobs, _ := newQueuedExporter(t, repo, queue)
+
+	req := &request.RekapRequest{UnitCode: "54110", DateStart: "2026/02/01", DateEnd: "2026/02/28"}
+	job, err := s.EnqueueExport(context.Background(), domain.EXPORT_TYPE_TRANSAKSI, req, "user-1", nil)
+	require.NoError(t, err)
+
+	// the exporter only publishes
+	require.Equal(t, []*domain.ExportMessage{{JobID: job.ID}}, queue.published)
+	require.Equal(t, domain.EXPORT_JOB_QUEUED, jobs.jobs[job.ID].Status)
+	require.NoError(t, s.ResumeExportJobs(context.Background()))
+	require.Empty(t, repo.cursors)
+
+	worker := service.NewExportWorkerService(s, queue, zap.NewNop())
+	go worker.Run()
+	t.Cleanup(func() { require.NoError(t, worker.Stop(context.Background())) })
+
+	queue.deliveries <- queue.published[0]
+	require.NoError(t, <-queue.settled)
+	require.Equal(t, domain.EXPORT_JOB_DONE, jobs.jobs[job.ID].Status)
+	require.Len(t, jobs.jobs[job.ID].Files, 2)
+
+	t.Run("redelivered job is not exported twice", func(t *testing.T) {
+		streamed := len(repo.cursors)
+
+		queue.deliveries <- queue.published[0]
+		require.NoError(t, <-queue.settled)
+		require.Len(t, repo.cursors, streamed)
+	})
+
+	t.Run("lease is released", func(t *testing.T) {
+		require.Empty(t, jobs.leases)
+	})
+
+	t.Run("redelivery after a crash runs once the lease lapses", func(t *testing.T) {
+		crashed, err := s.EnqueueExport(context.Background(), domain.EXPORT_TYPE_TRANSAKSI, &request.RekapRequest{UnitCode: "54112", DateStart: "2026/02/01", DateEnd: "2026/02/28"}, "user-1", nil)
+		require.NoError(t, err)
+
+		crashed.Status = domain.EXPORT_JOB_RUNNING
+		require.NoError(t, jobs.Claim(context.Background(), crashed.ID, "crashed-worker", time.Minute))
+		streamed := len(repo.cursors)
+
+		// the dead worker stops renewing, its lease runs out while the
+		// message waits
+		time.AfterFunc(15*time.Millisecond, func() {
+			jobs.leaseMu.Lock()
+			delete(jobs.leases, crashed.ID)
+			jobs.leaseMu.Unlock()
+		})
+
+		queue.deliveries <- &domain.ExportMessage{JobID: crashed.ID}
+		require.NoError(t, <-queue.settled)
+		require.Greater(t, len(repo.cursors), streamed)
+		require.Equal(t, domain.EXPORT_JOB_DONE, jobs.jobs[crashed.ID].Status)
+		require.Empty(t, jobs.leases)
+	})
+
+	t.Run("job claimed by another worker goes back to the queue", func(t *testing.T) {
+		running, err := s.EnqueueExport(context.Background(), domain.EXPORT_TYPE_TRANSAKSI, &request.RekapRequest{UnitCode: "54111", DateStart: "2026/02/01", DateEnd: "2026/02/28"}, "user-1", nil)
+		require.NoError(t, err)
+
+		running.Status = domain.EXPORT_JOB_RUNNING
+		running.RowsWritten = 10
+		require.NoError(t, jobs.Claim(context.Background(), running.ID, "other-worker", time.Minute))
+		streamed := len(repo.cursors)
+
+		queue.deliveries <- &domain.ExportMessage{JobID: running.ID}
+		require.ErrorIs(t, <-queue.settled, domain.ErrExportJobClaimed)
+		require.Len(t, repo.cursors, streamed)
+		require.Equal(t, domain.EXPORT_JOB_RUNNING, jobs.jobs[running.ID].Status)
+		require.Equal(t, int64(10), jobs.jobs[running.ID].RowsWritten)
+		require.Equal(t, "other-worker", jobs.leases[running.ID])
+	})
+
+	t.Run("unknown job is rejected", func(t *testing.T) {
+		queue.deliveries <- &domain.ExportMessage{JobID: "missing"}
+		require.ErrorIs(t, <-queue.settled, domain.ErrExportJobNotFound)
+	})
+}
+
+func TestExportWorkerStopsAfterPart(t *testing.T) {
+	queue := newMemoryExportQueue()
+	repo := &stallingExporterRepo{started: make(chan struct{}), stop: queue.stopping}
+	s, jobs, checkpoints := newQueuedExporter(t, repo, queue)
+
+	req := &request.RekapRequest{UnitCode: "54110", DateStart: "2026/02/01", DateEnd: "2026/02/28"}
+	job, err := s.EnqueueExport(context.Background(), domain.EXPORT_TYPE_TRANSAKSI, req, "user-1", nil)
+	require.NoError(t, err)
+
+	worker := service.NewExportWorkerService(s, queue, zap.NewNop())
+	go worker.Run()
+
+	queue.deliveries <- queue.published[0]
+	<-repo.started
+
+	require.NoError(t, worker.Stop(context.Background()))
+
+	// part 1 of 2 is finished and checkpointed, the job goes back on the queue
+	require.ErrorIs(t, <-queue.settled, domain.ErrExportInterrupted)
+	require.Equal(t, domain.EXPORT_JOB_QUEUED, jobs.jobs[job.ID].Status)
+	require.Len(t, jobs.jobs[job.ID].Files, 1)
+	require.FileExists(t, "files/"+job.ID+"/UNIT_54110_20260201_20260228_PART_1.xlsx")
+	require.NoFileExists(t, "files/"+job.ID+"/UNIT_54110_20260201_20260228_PART_2.xlsx")
+
+	checkpoint := checkpoints.byJob(job.ID)
+	require.NotNil(t, checkpoint)
+	require.Len(t, checkpoint.Parts, 1)
+
+	t.Run("redelivery resumes after the saved part", func(t *testing.T) {
+		streamed := len(repo.cursors)
+
+		require.NoError(t, s.ProcessExportJob(context.Background(), job.ID))
+
+		require.Equal(t, []*domain.ExportCursor{checkpoint.Cursor}, repo.cursors[streamed:])
+		require.Equal(t, domain.EXPORT_JOB_DONE, jobs.jobs[job.ID].Status)
+		require.Len(t, jobs.jobs[job.ID].Files, 2)
+		require.FileExists(t, "files/"+job.ID+"/UNIT_54110_20260201_20260228_PART_2.xlsx")
+		require.Empty(t, checkpoints.checkpoints)
+	})
+}
+
+// blockingExporterRepo streams nothing until the export is cancelled.
+type blockingExporterRepo struct {
+	cursorRecordingRepo
+	started chan struct{}
+}
+
+func (r *blockingExporterRepo) StreamTransaksi(ctx context.Context, req *request.RekapRequest, after *domain.ExportCursor, limit int, fn func(*domain.Transaksi) error) error {
+	close(r.started)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestExportWorkerLeaseLost(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("files", 0o750))
+
+	queue := newMemoryExportQueue()
+	repo := &blockingExporterRepo{started: make(chan struct{})}
+	jobs := &memoryExportJobRepo{jobs: map[string]*domain.ExportJob{}}
+	s := newExporterService(service.ExporterParams{Repo: repo, Jobs: jobs, Queue: queue, Config: &common.Config{ExportJobLease: 30 * time.Millisecond}})
+
+	req := &request.RekapRequest{UnitCode: "54110", DateStart: "2026/02/01", DateEnd: "2026/02/28"}
+	job, err := s.EnqueueExport(context.Background(), domain.EXPORT_TYPE_TRANSAKSI, req, "user-1", nil)
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() { done <- s.ProcessExportJob(context.Background(), job.ID) }()
+	<-repo.started
+
+	// the lease expired and another worker took it over
+	jobs.leaseMu.Lock()
+	jobs.leases[job.ID] = "other-worker"
+	jobs.leaseMu.Unlock()
+
+	require.NoError(t, <-done)
+	require.Equal(t, domain.EXPORT_JOB_RUNNING, jobs.jobs[job.ID].Status)
+	require.Nil(t, jobs.jobs[job.ID].FinishedAt)
+	require.Equal(t, "other-worker", jobs.leases[job.ID])
+}
@@ -0,0 +1,309 @@
+package service
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"event-registration/internal/core/domain"
+	"fmt"
+	"os"
+
+	"github.com/xuri/excelize/v2"
+	"go.uber.org/zap"
+)
+
+// rowWriter writes export rows to a single output file. Close flushes and
+// saves the file.
+type rowWriter interface {
+	WriteHeader(headers []string) error
+	WriteRow(values []interface{}) error
+	Close() error
+}
+
+type writerOptions struct {
+	sheetName string
+	// colWidths per column, 0 keeps the default width
+	colWidths []float64
+	// colTypes per column, xlsx writes numbers and dates as typed cells
+	colTypes []string
+	// maskingPolicy is recorded in the xlsx document properties
+	maskingPolicy string
+	// summary adds a "Ringkasan" sheet, xlsx only
+	summary *summarySheet
+}
+
+// exportFormat falls back to xlsx when the request does not pick one.
+func exportFormat(format string) string {
+	if format == "" {
+		return domain.EXPORT_FORMAT_XLSX
+	}
+
+	return format
+}
+
+// splitsFiles reports whether the format is bound to Excel's row limit.
+func splitsFiles(format string) bool {
+	return exportFormat(format) == domain.EXPORT_FORMAT_XLSX
+}
+
+func (s *ExporterService) newRowWriter(format, path string, opts writerOptions) (rowWriter, error) {
+	switch exportFormat(format) {
+	case domain.EXPORT_FORMAT_XLSX:
+		return s.newXlsxWriter(path, opts)
+	case domain.EXPORT_FORMAT_CSV:
+		return newCsvWriter(path)
+	case domain.EXPORT_FORMAT_NDJSON:
+		return newNdjsonWriter(path)
+	default:
+		return nil, fmt.Errorf("unknown export format: %s", format)
+	}
+}
+
+type xlsxWriter struct {
+	s         *ExporterService
+	f         *excelize.File
+	sw        *excelize.StreamWriter
+	path      string
+	colWidths []float64
+	rowIndex  int
+	summary   *summarySheet
+
+	colTypes []string
+	styles   []int
+	// textCells counts the typed values that did not parse, per column
+	textCells []int
+}
+
+func (s *ExporterService) newXlsxWriter(path string, opts writerOptions) (*xlsxWriter, error) {
+	f := excelize.NewFile()
+
+	sheetName := "Sheet1"
+	if opts.sheetName != "" {
+		if err := f.SetSheetName(sheetName, opts.sheetName); err != nil {
+			f.Close()
+			return nil, err
+		}
+		sheetName = opts.sheetName
+	}
+
+	if opts.maskingPolicy != "" {
+		err := f.SetDocProps(&excelize.DocProperties{
+			Keywords:    "masking_policy=" + opts.maskingPolicy,
+			Description: "Masking policy: " + opts.maskingPolicy,
+		})
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+
+	styles, err := cellStyles(f, opts.colTypes)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	sw, err := f.NewStreamWriter(sheetName)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &xlsxWriter{
+		s:         s,
+		f:         f,
+		sw:        sw,
+		path:      path,
+		colWidths: opts.colWidths,
+		rowIndex:  1,
+		summary:   opts.summary,
+		colTypes:  opts.colTypes,
+		styles:    styles,
+		textCells: make([]int, len(opts.colTypes)),
+	}, nil
+}
+
+func (w *xlsxWriter) WriteHeader(headers []string) error {
+	for i, width := range w.colWidths {
+		if width <= 0 {
+			continue
+		}
+		if err := w.sw.SetColWidth(i+1, i+1, width); err != nil {
+			return err
+		}
+	}
+
+	if err := w.s.setHeaders(w.sw, w.f, headers); err != nil {
+		return err
+	}
+
+	w.rowIndex = 2
+	return nil
+}
+
+func (w *xlsxWriter) WriteRow(values []interface{}) error {
+	cell, err := excelize.CoordinatesToCellName(1, w.rowIndex)
+	if err != nil {
+		return err
+	}
+
+	for i, cellType := range w.colTypes {
+		text, ok := values[i].(string)
+		if !ok || w.styles[i] == 0 || text == "" {
+			continue
+		}
+
+		typed, ok := typedCell(cellType, text, w.s.exportLocation())
+		if !ok {
+			// keep the text, log the first one of each column
+			if w.textCells[i] == 0 {
+				w.s.logger.Warn(
+					"error_parse_cell",
+					zap.String("path", w.path),
+					zap.Int("column", i+1),
+					zap.String("type", cellType),
+					zap.String("value", text),
+				)
+			}
+			w.textCells[i]++
+			continue
+		}
+
+		values[i] = excelize.Cell{StyleID: w.styles[i], Value: typed}
+	}
+
+	if err := w.sw.SetRow(cell, values); err != nil {
+		return fmt.Errorf("error set row : %s", err.Error())
+	}
+
+	w.rowIndex++
+	return nil
+}
+
+func (w *xlsxWriter) Close() error {
+	defer w.f.Close()
+
+	if err := w.sw.Flush(); err != nil {
+		return err
+	}
+
+	if w.summary != nil {
+		if err := writeSummarySheet(w.f, w.summary); err != nil {
+			return err
+		}
+	}
+
+	for i, count := range w.textCells {
+		if count > 0 {
+			w.s.logger.Warn(
+				"cells_kept_as_text",
+				zap.String("path", w.path),
+				zap.Int("column", i+1),
+				zap.String("type", w.colTypes[i]),
+				zap.Int("count", count),
+			)
+		}
+	}
+
+	return w.f.SaveAs(w.path)
+}
+
+type csvWriter struct {
+	file *os.File
+	w    *csv.Writer
+}
+
+func newCsvWriter(path string) (*csvWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &csvWriter{file: file, w: csv.NewWriter(file)}, nil
+}
+
+func (w *csvWriter) WriteHeader(headers []string) error {
+	return w.w.Write(headers)
+}
+
+func (w *csvWriter) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		record[i] = fmt.Sprint(value)
+	}
+
+	return w.w.Write(record)
+}
+
+func (w *csvWriter) Close() error {
+	defer w.file.Close()
+
+	w.w.Flush()
+	if err := w.w.Error(); err != nil {
+		return err
+	}
+
+	return w.file.Sync()
+}
+
+type ndjsonWriter struct {
+	file    *os.File
+	w       *bufio.Writer
+	headers []string
+}
+
+func newNdjsonWriter(path string) (*ndjsonWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ndjsonWriter{file: file, w: bufio.NewWriter(file)}, nil
+}
+
+// WriteHeader keeps the headers as object keys, nothing is written yet.
+func (w *ndjsonWriter) WriteHeader(headers []string) error {
+	w.headers = headers
+	return nil
+}
+
+// WriteRow writes one JSON object per line, keys follow the header order.
+func (w *ndjsonWriter) WriteRow(values []interface{}) error {
+	if len(values) != len(w.headers) {
+		return fmt.Errorf("row has %d values for %d headers", len(values), len(w.headers))
+	}
+
+	w.w.WriteByte('{')
+	for i, header := range w.headers {
+		if i > 0 {
+			w.w.WriteByte(',')
+		}
+
+		key, err := json.Marshal(header)
+		if err != nil {
+			return err
+		}
+
+		value, err := json.Marshal(values[i])
+		if err != nil {
+			return err
+		}
+
+		w.w.Write(key)
+		w.w.WriteByte(':')
+		w.w.Write(value)
+	}
+	w.w.WriteByte('}')
+
+	return w.w.WriteByte('\n')
+}
+
+func (w *ndjsonWriter) Close() error {
+	defer w.file.Close()
+
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+
+	return w.file.Sync()
+}
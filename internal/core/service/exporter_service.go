@@ -1,25 +1,29 @@
 package service
 
 import (
-	"archive/tar"
-	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"event-registration/internal/common"
 	"event-registration/internal/common/helper"
 	"event-registration/internal/common/request"
 	"event-registration/internal/core/domain"
 	"fmt"
-	"io"
 	"os"
-	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/xuri/excelize/v2"
+	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
 
 const (
 	BATCH_SIZE = 50000
-	filesDir   = "files/"
+	// MAX_ROWS_PER_FILE splits rekap transaksi xlsx exports into parts
+	MAX_ROWS_PER_FILE = 100000
+	filesDir          = "files/"
 )
 
 var HeaderStyle excelize.Style = excelize.Style{
@@ -54,269 +58,136 @@ func (s *ExporterService) setHeaders(sw *excelize.StreamWriter, f *excelize.File
 }
 
 type ExporterService struct {
-	repo   domain.ExporterRepository
-	cache  domain.EventCache
-	logger *zap.Logger
+	repo  domain.ExporterRepository
+	cache domain.EventCache
+	jobs  domain.ExportJobRepository
+	// checkpoints is optional, without it exports always start over
+	checkpoints domain.ExportCheckpointRepository
+	// audits is optional, without it runs are not audited
+	audits domain.ExportAuditRepository
+	// queue is optional, without it jobs run in this process
+	queue domain.ExportQueue
+	// admission is optional, without it admission and the running limit
+	// only hold within this process
+	admission domain.ExportAdmissionRepository
+	// schedules is optional, it records how scheduled jobs ended
+	schedules domain.ExportScheduleRepository
+	// layouts falls back to the built-in ones when nil
+	layouts *ExportLayouts
+	// masking falls back to the built-in policies when nil
+	masking *ExportMasking
+	// notifier is optional, without it requesters are not notified
+	notifier *ExportNotifier
+	// datasets by export type, see RegisterDataset
+	datasets map[string]domain.ExportDataset
+	config   *common.Config
+	logger   *zap.Logger
+
+	// location dates are written in, see exportLocation
+	locationOnce sync.Once
+	location     *time.Location
+
+	// cancel functions of the jobs running in this process
+	runningMu sync.Mutex
+	running   map[string]context.CancelFunc
+	// gate limits the exports running in this process at once when there
+	// is no shared admission
+	gate *exportGate
+	// admitMu serialises admissions when there is no admission repository
+	admitMu sync.Mutex
 }
 
-func NewExporterService(repo domain.ExporterRepository, cache domain.EventCache, logger *zap.Logger) *ExporterService {
-	return &ExporterService{
-		repo:   repo,
-		cache:  cache,
-		logger: logger,
-	}
+// ExporterParams are the dependencies of ExporterService. Repo, Config and
+// Logger are required, every other one switches its feature off when nil.
+type ExporterParams struct {
+	fx.In
+
+	Repo        domain.ExporterRepository
+	Cache       domain.EventCache                 `optional:"true"`
+	Jobs        domain.ExportJobRepository        `optional:"true"`
+	Checkpoints domain.ExportCheckpointRepository `optional:"true"`
+	Audits      domain.ExportAuditRepository      `optional:"true"`
+	Queue       domain.ExportQueue                `optional:"true"`
+	Admission   domain.ExportAdmissionRepository  `optional:"true"`
+	Schedules   domain.ExportScheduleRepository   `optional:"true"`
+	Layouts     *ExportLayouts                    `optional:"true"`
+	Masking     *ExportMasking                    `optional:"true"`
+	Notifier    *ExportNotifier                   `optional:"true"`
+	Config      *common.Config
+	Logger      *zap.Logger
 }
 
-func (s *ExporterService) ExportRekapTransaksi(req *request.RekapRequest) (err error) {
-	const MAX_ROWS_PER_FILE = 100000
-	const FETCH_BATCH_SIZE = 100000
-
-	var baseFilename string
-	var tanggal string = strings.ReplaceAll(req.DateStart+"_"+req.DateEnd, "/", "")
-
-	// Set filename based on filter
-	if len(req.Induk) > 0 {
-		baseFilename = "INDUK_" + req.Induk + "_" + tanggal
-	} else if len(req.Area) > 0 {
-		baseFilename = "AREA_" + req.Area + "_" + tanggal
-	} else if len(req.UnitCode) > 0 {
-		baseFilename = "UNIT_" + req.UnitCode + "_" + tanggal
-	} else {
-		baseFilename = "NASIONAL" + "_" + tanggal
-	}
-
-	s.logger.Info(
-		"starting_count_query",
-		zap.String("filename", baseFilename),
-	)
-
-	// Count total rows first
-	totalRows, err := s.repo.CountTransaksi(req)
-	if err != nil {
-		s.logger.Error(
-			"error_count_transaksi",
-			zap.Error(err),
-		)
-		return err
-	}
-
-	s.logger.Info(
-		"total_rows_to_export",
-		zap.Int64("total_rows", totalRows),
-		zap.Int("max_rows_per_file", MAX_ROWS_PER_FILE),
-	)
-
-	if totalRows == 0 {
-		s.logger.Info("no_data_to_export")
-		return nil
-	}
-
-	// Calculate number of files needed
-	totalFiles := (int(totalRows) + MAX_ROWS_PER_FILE - 1) / MAX_ROWS_PER_FILE
+func NewExporterService(p ExporterParams) *ExporterService {
+	transaksi := &transaksiDataset{repo: p.Repo}
 
-	s.logger.Info(
-		"file_calculation",
-		zap.Int("total_files", totalFiles),
-	)
-
-	// Headers for Excel
-	headers := []string{
-		"Nama Pelanggan",
-		"Nama di Meteran",
-		"Jenis Transaksi",
-		"Nominal",
-		"Status",
-		"ID Meteran",
-		"Deskripsi",
-		"Payment Gateway",
-		"Tanggal Transaksi",
-		"Token",
-		"Unit UP",
-		"Nama Unit UP",
-		"Nama Unit AP",
-		"Nama Unit UPI",
+	return &ExporterService{
+		repo:        p.Repo,
+		cache:       p.Cache,
+		jobs:        p.Jobs,
+		checkpoints: p.Checkpoints,
+		audits:      p.Audits,
+		queue:       p.Queue,
+		admission:   p.Admission,
+		schedules:   p.Schedules,
+		layouts:     p.Layouts,
+		masking:     p.Masking,
+		notifier:    p.Notifier,
+		config:      p.Config,
+		logger:      p.Logger,
+		running:     map[string]context.CancelFunc{},
+		gate:        newExportGate(p.Config.ExportMaxConcurrent),
+		datasets: map[string]domain.ExportDataset{
+			domain.EXPORT_TYPE_TRANSAKSI:     transaksi,
+			domain.EXPORT_TYPE_TRANSAKSI_ALL: transaksi,
+			domain.EXPORT_TYPE_PELANGGAN:     &pelangganDataset{repo: p.Repo},
+		},
 	}
+}
 
-	var generatedFiles []string
-
-	// Process data and create multiple files
-	for fileNum := 0; fileNum < totalFiles; fileNum++ {
-		fileOffset := fileNum * MAX_ROWS_PER_FILE
-		remainingRows := int(totalRows) - fileOffset
-		rowsForThisFile := MAX_ROWS_PER_FILE
-		if remainingRows < MAX_ROWS_PER_FILE {
-			rowsForThisFile = remainingRows
-		}
-
-		s.logger.Info(
-			"creating_file",
-			zap.Int("file_number", fileNum+1),
-			zap.Int("total_files", totalFiles),
-			zap.Int("rows_for_this_file", rowsForThisFile),
-		)
-
-		// Create new Excel file
-		f := excelize.NewFile()
-		sheetName := "Sheet1"
-		sw, err := f.NewStreamWriter(sheetName)
-		if err != nil {
-			f.Close()
-			s.logger.Error("error_create_stream_writer", zap.Error(err))
-			return err
-		}
-
-		// Set headers
-		if err := s.setHeaders(sw, f, headers); err != nil {
-			f.Close()
-			s.logger.Error("error_set_headers", zap.Error(err))
-			return err
-		}
-
-		// Calculate number of fetch batches needed for this file
-		numFetchBatches := (rowsForThisFile + FETCH_BATCH_SIZE - 1) / FETCH_BATCH_SIZE
-		excelRowIndex := 2 // Start from row 2 (row 1 is header)
-
-		// Fetch and write data in batches
-		for fetchBatch := 0; fetchBatch < numFetchBatches; fetchBatch++ {
-			dbOffset := fileOffset + (fetchBatch * FETCH_BATCH_SIZE)
-			dbLimit := FETCH_BATCH_SIZE
-
-			// Adjust limit for last batch
-			remainingForFile := rowsForThisFile - (fetchBatch * FETCH_BATCH_SIZE)
-			if remainingForFile < FETCH_BATCH_SIZE {
-				dbLimit = remainingForFile
-			}
-
-			s.logger.Info(
-				"fetching_batch",
-				zap.Int("file_number", fileNum+1),
-				zap.Int("fetch_batch", fetchBatch+1),
-				zap.Int("num_fetch_batches", numFetchBatches),
-				zap.Int("db_offset", dbOffset),
-				zap.Int("db_limit", dbLimit),
-			)
-
-			batchReq := &request.RekapRequest{
-				UnitCode:      req.UnitCode,
-				Area:          req.Area,
-				Induk:         req.Induk,
-				Pusat:         req.Pusat,
-				DateStart:     req.DateStart,
-				DateEnd:       req.DateEnd,
-				IsDBPlnMobile: req.IsDBPlnMobile,
-				Limit:         dbLimit,
-				Offset:        dbOffset,
-			}
-
-			res, err := s.repo.FindTransaksi(batchReq)
-			if err != nil {
-				f.Close()
-				s.logger.Error(
-					"error_fetch_batch",
-					zap.Int("file_number", fileNum+1),
-					zap.Int("fetch_batch", fetchBatch+1),
-					zap.Error(err),
-				)
-				return err
-			}
-
-			s.logger.Info(
-				"batch_fetched",
-				zap.Int("file_number", fileNum+1),
-				zap.Int("fetch_batch", fetchBatch+1),
-				zap.Int("rows_fetched", len(res)),
-			)
-
-			// Write batch data to Excel
-			for _, row := range res {
-				cell, _ := excelize.CoordinatesToCellName(1, excelRowIndex)
-				if err := sw.SetRow(cell, []interface{}{
-					row.Name,
-					row.ConsumerName,
-					row.Type,
-					row.Amount,
-					row.StatusCode,
-					row.MeterID,
-					row.Title,
-					row.PaymentGateway,
-					row.CreatedAt,
-					row.Token,
-					row.UnitUP,
-					row.NameUnitUP,
-					row.NameUnitAP,
-					row.NameUnitUpi,
-				}); err != nil {
-					f.Close()
-					s.logger.Error("error_set_row", zap.Error(err))
-					return err
-				}
-				excelRowIndex++
+func (s *ExporterService) ExportRekapTransaksi(ctx context.Context, req *request.RekapRequest, progress *ExportProgress) error {
+	dir := progress.dir()
+	baseFilename := exportBaseFilename(req)
+	format := exportFormat(req.Format)
+
+	return s.exportDataset(ctx, &datasetExport{
+		exportType:  domain.EXPORT_TYPE_TRANSAKSI,
+		req:         req,
+		rowsPerFile: MAX_ROWS_PER_FILE,
+		path: func(part, parts int) string {
+			// Generate filename with part number if multiple files
+			if parts > 1 {
+				return fmt.Sprintf("%s%s_PART_%d.%s", dir, baseFilename, part, format)
 			}
-
-			// Free memory
-			res = nil
-			runtime.GC()
-		}
-
-		// Flush and save file
-		if err := sw.Flush(); err != nil {
-			f.Close()
-			s.logger.Error("error_flush_stream", zap.Error(err))
-			return err
-		}
-
-		// Generate filename with part number if multiple files
-		var filePath string
-		if totalFiles > 1 {
-			filePath = fmt.Sprintf("%s%s_PART_%d.xlsx", filesDir, baseFilename, fileNum+1)
-		} else {
-			filePath = fmt.Sprintf("%s%s.xlsx", filesDir, baseFilename)
-		}
-
-		if err := f.SaveAs(filePath); err != nil {
-			f.Close()
-			s.logger.Error(
-				"error_save_excel",
-				zap.String("filepath", filePath),
-				zap.Error(err),
-			)
-			return err
-		}
-
-		f.Close()
-		generatedFiles = append(generatedFiles, filePath)
-
-		s.logger.Info(
-			"file_saved",
-			zap.Int("file_number", fileNum+1),
-			zap.String("filepath", filePath),
-			zap.Int("rows_in_file", excelRowIndex-2),
-		)
-	}
-
-	s.logger.Info(
-		"export_completed",
-		zap.String("base_filename", baseFilename),
-		zap.Int64("total_rows", totalRows),
-		zap.Int("total_files", totalFiles),
-		zap.Strings("generated_files", generatedFiles),
-	)
-
-	return nil
+			return fmt.Sprintf("%s%s.%s", dir, baseFilename, format)
+		},
+		resumable: true,
+	}, progress)
 }
 
-func (s *ExporterService) ExportAllRekapTransaksi(req *request.RekapRequest) (err error) {
+// ExportAllRekapTransaksi writes one folder per regional, induk, area and
+// unit. Units are retried independently, a unit that keeps failing does not
+// stop the others. The returned report is also written to the job folder as
+// JSON.
+func (s *ExporterService) ExportAllRekapTransaksi(ctx context.Context, req *request.RekapRequest, progress *ExportProgress) (report *domain.ExportReport, err error) {
 	var payload []Payload
-	units, err := s.repo.GetAllUnit()
+	units, err := s.repo.GetAllUnit(ctx)
 	if err != nil {
 		s.logger.Error(
 			"error_get_all_units",
 			zap.Error(err),
 		)
-		return err
+		return nil, err
 	}
 
 	for _, unit := range units {
+		// a regional without its id would export nationwide
+		if len(unit.IDRegAPKT) > 0 {
+			payload = append(payload, Payload{
+				filename: helper.NormalizeString("REGIONAL " + unit.NamaRegional),
+				req:      unitRequest(req, unit.IDRegAPKT, "", "", ""),
+			})
+		}
+
 		if len(unit.Induk) > 0 {
 			for _, induk := range unit.Induk {
 
@@ -324,14 +195,7 @@ func (s *ExporterService) ExportAllRekapTransaksi(req *request.RekapRequest) (er
 
 				payload = append(payload, Payload{
 					filename: filename,
-					req: &request.RekapRequest{
-						UnitCode:  "",
-						Area:      "",
-						Induk:     induk.IDUnitUPI,
-						Pusat:     "",
-						DateStart: req.DateStart,
-						DateEnd:   req.DateEnd,
-					},
+					req:      unitRequest(req, "", induk.IDUnitUPI, "", ""),
 				})
 
 				if len(induk.Area) > 0 {
@@ -341,14 +205,7 @@ func (s *ExporterService) ExportAllRekapTransaksi(req *request.RekapRequest) (er
 						payload = append(payload, Payload{
 							filename: filename,
 
-							req: &request.RekapRequest{
-								UnitCode:  "",
-								Area:      area.IDUnitAP,
-								Induk:     "",
-								Pusat:     "",
-								DateStart: req.DateStart,
-								DateEnd:   req.DateEnd,
-							},
+							req: unitRequest(req, "", "", area.IDUnitAP, ""),
 						})
 
 						if len(area.Unit) > 0 {
@@ -358,14 +215,7 @@ func (s *ExporterService) ExportAllRekapTransaksi(req *request.RekapRequest) (er
 								payload = append(payload, Payload{
 									filename: filename,
 
-									req: &request.RekapRequest{
-										UnitCode:  unit.IDUnitUP,
-										Area:      "",
-										Induk:     "",
-										Pusat:     "",
-										DateStart: req.DateStart,
-										DateEnd:   req.DateEnd,
-									},
+									req: unitRequest(req, "", "", "", unit.IDUnitUP),
 								})
 							}
 						}
@@ -380,13 +230,68 @@ func (s *ExporterService) ExportAllRekapTransaksi(req *request.RekapRequest) (er
 		zap.Any("payload", payload),
 	)
 
-	s.ProcessIndukDataWithWorkerPool(payload)
+	startedAt := time.Now()
+	results := s.ProcessIndukDataWithWorkerPool(ctx, payload, progress)
+	finishedAt := time.Now()
+
+	report = &domain.ExportReport{
+		Path:       fmt.Sprintf("%sREKAP_TRANSAKSI_ALL_REPORT_%s_%s.json", progress.dir(), strings.ReplaceAll(req.DateStart+"_"+req.DateEnd, "/", ""), startedAt.Format("20060102150405")),
+		StartedAt:  startedAt,
+		FinishedAt: finishedAt,
+		DurationMs: finishedAt.Sub(startedAt).Milliseconds(),
+		Units:      results,
+	}
+
+	// units are not resumable, an interrupted export starts over
+	for _, result := range results {
+		if result.Error == domain.ErrExportInterrupted.Error() {
+			return nil, domain.ErrExportInterrupted
+		}
+	}
+
+	for _, result := range results {
+		if result.Status == domain.EXPORT_UNIT_SUCCEEDED {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+		report.TotalRows += result.Rows
+	}
+
+	if err := s.writeReport(report); err != nil {
+		return report, err
+	}
+
+	progress.AddFile(report.Path, 0)
 
 	s.logger.Info(
 		"done_export",
+		zap.Int("succeeded", report.Succeeded),
+		zap.Int("failed", report.Failed),
+		zap.String("report", report.Path),
 	)
 
-	return nil
+	if ctx.Err() != nil {
+		return report, ctx.Err()
+	}
+
+	if report.Failed > 0 {
+		return report, fmt.Errorf("%w: %d of %d units", ErrExportPartiallyFailed, report.Failed, len(results))
+	}
+
+	return report, nil
+}
+
+// unitRequest narrows req down to one induk, area or unit, keeping every
+// other filter.
+func unitRequest(req *request.RekapRequest, pusat, induk, area, unitCode string) *request.RekapRequest {
+	unitReq := *req
+	unitReq.Pusat = pusat
+	unitReq.Induk = induk
+	unitReq.Area = area
+	unitReq.UnitCode = unitCode
+
+	return &unitReq
 }
 
 type Payload struct {
@@ -394,10 +299,17 @@ type Payload struct {
 	req      *request.RekapRequest
 }
 
-func (s *ExporterService) ProcessIndukDataWithWorkerPool(data []Payload) {
-	workerCount := 10
-	jobs := make(chan Payload, len(data))
-	errorsChan := make(chan error, len(data)) // Channel for collecting errors
+// ProcessIndukDataWithWorkerPool exports every payload with the configured
+// number of workers. Payloads still queued when ctx is cancelled are reported
+// as failed without being started.
+func (s *ExporterService) ProcessIndukDataWithWorkerPool(ctx context.Context, data []Payload, progress *ExportProgress) []domain.ExportUnitResult {
+	workerCount := s.config.ExportWorkers
+	if workerCount <= 0 {
+		workerCount = 10
+	}
+
+	jobs := make(chan int, len(data))
+	results := make([]domain.ExportUnitResult, len(data))
 
 	var wg sync.WaitGroup
 
@@ -405,538 +317,212 @@ func (s *ExporterService) ProcessIndukDataWithWorkerPool(data []Payload) {
 		wg.Add(1)
 		go func(workerID int) {
 			defer wg.Done()
-			for d := range jobs {
+			for index := range jobs {
+				d := data[index]
 				s.logger.Info(
 					"worker_processing",
 					zap.Int("worker_id", workerID),
 					zap.String("filename", d.filename),
 				)
-				if err := s.process(d); err != nil {
-					errorsChan <- fmt.Errorf("worker %d: %w", workerID, err) // Add worker ID to error
-				}
+				results[index] = s.exportUnit(ctx, d, progress)
 			}
 		}(i + 1)
 	}
 
-	for _, d := range data {
-		jobs <- d
+	for index := range data {
+		jobs <- index
 	}
 	close(jobs)
 
 	wg.Wait()
-	close(errorsChan)
-
-	var allErrors []error
-	for err := range errorsChan {
-		allErrors = append(allErrors, err)
-	}
-
-	if len(allErrors) > 0 {
-		fmt.Println("\nErrors encountered:")
-		for _, err := range allErrors {
-			fmt.Println(err)
-			s.logger.Error(
-				"error_from_channel",
-				zap.Error(err),
-			)
-		}
-	}
-}
-
-func (s *ExporterService) process(data Payload) error {
-	res, err := s.repo.FindTransaksi(data.req)
-	if err != nil {
-		s.logger.Error(
-			"error_find_transaksi",
-			zap.Error(err),
-			zap.String("filename", data.filename),
-		)
-
-		return err
-	}
-
-	_, err = s.generateXlsx(res, data.filename)
-	if err != nil {
-		s.logger.Error(
-			"error_find_transaksi",
-			zap.Error(err),
-			zap.String("filename", data.filename),
-		)
 
-		return err
-	}
-
-	return nil
+	return results
 }
 
-func (s *ExporterService) ExportRekapPelanggan(req *request.RekapRequest) (err error) {
-
-	var filename string
-	var tanggal string = strings.ReplaceAll(req.DateStart+"_"+req.DateEnd, "/", "")
-
-	s.logger.Info("starting_query")
-
-	batchSize := 25_000 * 6
-
-	offset := 0
-
-	var files []string
-
-	if len(req.Induk) > 0 {
-		filename = "INDUK_" + req.Induk + "_" + tanggal
-	} else if len(req.Area) > 0 {
-		filename = "AREA_" + req.Area + "_" + tanggal
-	} else if len(req.UnitCode) > 0 {
-		filename = "UNIT_" + req.UnitCode + "_" + tanggal
-	} else {
-		filename = "NASIONAL" + "_" + tanggal
+// exportUnit runs process with exponential backoff between attempts.
+func (s *ExporterService) exportUnit(ctx context.Context, data Payload, progress *ExportProgress) domain.ExportUnitResult {
+	result := domain.ExportUnitResult{
+		Name:     data.filename,
+		IDPusat:  data.req.Pusat,
+		IDInduk:  data.req.Induk,
+		IDArea:   data.req.Area,
+		UnitCode: data.req.UnitCode,
+		Status:   domain.EXPORT_UNIT_FAILED,
+		Files:    []string{},
 	}
 
-	// for {
-	// 	// Prepare batch request
-	batchReq := *req
-	batchReq.Limit = batchSize
-	batchReq.Offset = offset
-
-	// 	batch, err := s.repo.FindPelanggan(&batchReq)
-	// 	if err != nil {
-	// 		s.logger.Error("error_find_pelanggan_batch", zap.Error(err))
-	// 		return err
-	// 	}
-
-	// 	if len(batch) == 0 {
-	// 		break
-	// 	}
-
-	// 	s.logger.Info("processing_batch", zap.Int("offset", offset), zap.Int("batch_size", len(batch)))
-
-	// 	batchFiles, err := s.generateXlsxPelanggan(batch, filename)
-	// 	if err != nil {
-	// 		s.logger.Error("error_generate_xlsx_pelanggan", zap.Error(err))
-	// 		return err
-	// 	}
-	// 	files = append(files, batchFiles...)
-
-	// 	// Free unused memory after each batch
-	// 	runtime.GC()
-
-	// 	offset += batchSize
-
-	// 	// If batch size is less than requested, last batch reached
-	// 	if len(batch) < batchSize {
-	// 		break
-	// 	}
-	// }
-
-	count, err := s.repo.CountPelanggan(req)
-	if err != nil {
-		s.logger.Error("error_count_pelanggan", zap.Error(err))
-		return err
+	maxAttempts := s.config.ExportRetryAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
 	}
 
-	totalRows := int(count)
-
-	numBatches := (totalRows + batchSize - 1) / batchSize
-
-	s.logger.Info("info_count_pelanggan", zap.Int64("count", count), zap.Any("num_of_batch", numBatches))
-
-	for batchNum := 0; batchNum < numBatches; batchNum++ {
-		start := batchNum * batchSize
-		end := start + batchSize
-		if end > totalRows {
-			end = totalRows
-		}
+	start := time.Now()
+	defer func() {
+		result.DurationMs = time.Since(start).Milliseconds()
+	}()
 
-		pelanggan, err := s.repo.FindPelanggan(&batchReq)
-		if err != nil {
-			s.logger.Error("error_find_pelanggan_batch", zap.Error(err))
-			return err
+	backoff := s.config.ExportRetryBackoff
+	for attempt := 1; ; attempt++ {
+		if ctx.Err() != nil {
+			result.Error = ctx.Err().Error()
+			return result
 		}
 
-		s.logger.Info("info_query_pelanggan", zap.Any("request", batchReq))
-
-		batchFilename, err := s.generateXlsxPelanggan(pelanggan, filename, batchNum)
-		if err != nil {
-			return err
+		if draining(ctx) {
+			result.Error = domain.ErrExportInterrupted.Error()
+			return result
 		}
 
-		files = append(files, batchFilename...)
-
-		runtime.GC()
-
-		offset += batchSize
-
-		batchReq.Offset = offset
-	}
-
-	s.logger.Info("done_get_data", zap.Int("total_files", len(files)))
-
-	return nil
-}
-
-func (s *ExporterService) generateXlsx(res []*domain.Transaksi, filename string) (files []string, err error) {
-	sheetName := "Rekap Transaksi"
-	batchSize := 25_000 * 6
-	totalRows := len(res)
-
-	s.logger.Info(
-		"length_of_query_result",
-		zap.Int("total_rows", totalRows),
-	)
+		result.Attempts = attempt
 
-	numBatches := (totalRows + batchSize - 1) / batchSize
-
-	for batch := 0; batch < numBatches; batch++ {
-		start := batch * batchSize
-		end := start + batchSize
-		if end > totalRows {
-			end = totalRows
+		files, rows, err := s.process(ctx, data, progress)
+		if err == nil {
+			result.Status = domain.EXPORT_UNIT_SUCCEEDED
+			result.Rows = rows
+			result.Files = files
+			result.Error = ""
+			return result
 		}
 
-		// Create a new Excel file for each batch
-		f := excelize.NewFile()
+		result.Error = err.Error()
 
-		defer func() {
-			if err := f.Close(); err != nil {
-				s.logger.Error(
-					"error_create_new_file",
-					zap.Error(err),
-				)
-			}
-		}()
-
-		err = f.SetSheetName("Sheet1", sheetName)
-		if err != nil {
+		if attempt >= maxAttempts || ctx.Err() != nil || errors.Is(err, domain.ErrExportInterrupted) {
 			s.logger.Error(
-				"create_sheet",
+				"error_export_unit",
+				zap.String("filename", data.filename),
+				zap.Int("attempts", attempt),
 				zap.Error(err),
 			)
-			return files, err
+			return result
 		}
 
-		sw, err := f.NewStreamWriter(sheetName)
-		if err != nil {
-			s.logger.Error(
-				"error_create_stream_writer",
-				zap.Error(err),
-			)
-			return files, err
-		}
-
-		err = sw.SetColWidth(2, 15, 25)
-		if err != nil {
-			s.logger.Error(
-				"error_set_col",
-				zap.Error(err),
-			)
-			return files, err
-		}
+		s.logger.Warn(
+			"retry_export_unit",
+			zap.String("filename", data.filename),
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
 
-		err = s.setHeaders(sw, f, []string{"No.", "Nama Akun", "Nama Pelanggan", "Type", "Amount", "Status Code", "ID Pel", "Pembayaran", "Kanal Pembayaran", "Jenis Pembayaran", "Tanggal Transaksi", "Token", "Unit UPI", "Unit AP", "Unit UP"})
-		if err != nil {
-			s.logger.Error(
-				"error_set_error",
-				zap.Error(err),
-			)
-			return files, err
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
 		}
+		backoff *= 2
+	}
+}
 
-		s.logger.Info(
-			"length_of_batch",
-			zap.Int("rows", end-start),
-		)
+// process exports one unit. A failed attempt is rolled back, its files are
+// removed and its rows taken off the job progress, so a retry starts clean.
+func (s *ExporterService) process(ctx context.Context, data Payload, progress *ExportProgress) (files []string, rows int, err error) {
+	dataset, err := s.dataset(domain.EXPORT_TYPE_TRANSAKSI_ALL)
+	if err != nil {
+		return nil, 0, err
+	}
 
-		// Write rows for the current batch
-		for i, data := range res[start:end] {
-			rowIndex := i + 1
+	count, err := s.countDataset(ctx, dataset, data.req)
+	if err != nil {
+		return nil, 0, err
+	}
 
-			cell, err := excelize.CoordinatesToCellName(1, rowIndex+1)
-			if err != nil {
-				s.logger.Error(
-					"error_create_coordinate",
-					zap.Error(err),
-				)
-				return files, err
-			}
+	// units are only counted once a worker picks them up
+	progress.AddTotal(count)
 
-			var paymentType string = ""
-			if data.Type == "" {
-				paymentType = "-"
-			} else {
-				paymentType = data.Type
-			}
+	files, rows, err = s.generateTransaksiFiles(ctx, data.req, int(count), data.filename, progress)
+	if err != nil {
+		s.logger.Error(
+			"error_generate_transaksi_files",
+			zap.Error(err),
+			zap.String("filename", data.filename),
+		)
 
-			if err := sw.SetRow(cell, []interface{}{
-				rowIndex,
-				data.ConsumerName,
-				data.Name,
-				data.Type,
-				data.Amount,
-				data.StatusCode,
-				data.MeterID,
-				data.Title,
-				data.PaymentGateway,
-				paymentType,
-				data.CreatedAt,
-				data.Token,
-				data.NameUnitUpi,
-				data.NameUnitAP,
-				data.NameUnitUP,
-			}); err != nil {
-				s.logger.Error(
-					"error_set_row",
-					zap.Error(err),
-				)
-				return files, fmt.Errorf("error set row : %s", err.Error())
-			}
-		}
+		progress.Discard(files, rows, count)
 
-		if err = sw.Flush(); err != nil {
+		dir := progress.dir() + data.filename
+		if errRemove := os.RemoveAll(dir); errRemove != nil {
 			s.logger.Error(
-				"error_flush_file",
-				zap.Error(err),
+				"error_remove_unit_dir",
+				zap.String("dir", dir),
+				zap.Error(errRemove),
 			)
-			return files, err
 		}
 
-		path := fmt.Sprintf("%s%s", filesDir, filename)
+		return nil, 0, err
+	}
 
-		if _, err := os.Stat(path); os.IsNotExist(err) {
-			errMakeDir := os.Mkdir(path, 0o750) // Secure permissions: owner=rwx, group=rx
-			if errMakeDir != nil {
-				s.logger.Error(
-					"error_make_dir",
-					zap.Error(errMakeDir),
-				)
-				return nil, errMakeDir
-			}
-		}
+	return files, rows, nil
+}
 
-		// Save the file with a batch-specific name
-		batchFilename := fmt.Sprintf("files/%s/REKAP_TRANSAKSI_EXPORT_%s_PART_%d.xlsx", filename, filename, batch+1)
-		if err := f.SaveAs(batchFilename); err != nil {
-			s.logger.Error(
-				"error_save_excel_file",
-				zap.Error(err),
-			)
-			return files, err
-		}
+func (s *ExporterService) writeReport(report *domain.ExportReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
 
-		s.logger.Info(
-			"batch_saved",
-			zap.Int("batch", batch+1),
-			zap.String("to", batchFilename),
+	if err := os.WriteFile(report.Path, data, 0o600); err != nil {
+		s.logger.Error(
+			"error_write_report",
+			zap.String("path", report.Path),
+			zap.Error(err),
 		)
-
-		files = append(files, batchFilename)
+		return err
 	}
 
-	return files, nil
+	return nil
 }
 
-var pelangganHeaders = []string{
-	"No.", "ID PELANGGAN", "NAMA", "CONSUMER NAME", "TIPE ENERGI", "KWH", "ALAMAT", "METER NO", "TIPE METER", "UNIT UPI", "NAMA UNIT UPI", "UNIT AP", "NAMA UNIT AP", "UNIT UP", "NAMA UNIT UP", "CREATED AT",
+func (s *ExporterService) ExportRekapPelanggan(ctx context.Context, req *request.RekapRequest, progress *ExportProgress) error {
+	return s.ExportDataset(ctx, domain.EXPORT_TYPE_PELANGGAN, req, progress)
 }
 
-func (s *ExporterService) generateXlsxPelanggan(res []*domain.Pelanggan, filename string, batchNum int) (files []string, err error) {
-	sheetName := "Rekap Pelanggan"
-	batchSize := 25_000 * 6
-	totalRows := len(res)
+// generateTransaksiFiles writes one unit of a rekap transaksi all export into
+// its own folder and returns the files it saved and every row it wrote,
+// including those of a part that failed halfway.
+func (s *ExporterService) generateTransaksiFiles(ctx context.Context, req *request.RekapRequest, totalRows int, filename string, progress *ExportProgress) (files []string, rowsWritten int, err error) {
+	format := exportFormat(req.Format)
 
 	s.logger.Info(
 		"length_of_query_result",
 		zap.Int("total_rows", totalRows),
 	)
 
-	numBatches := (totalRows + batchSize - 1) / batchSize
-
-	for batch := 0; batch < numBatches; batch++ {
-		start := batch * batchSize
-		end := start + batchSize
-		if end > totalRows {
-			end = totalRows
-		}
-
-		batchFilename, err := s.createPelangganBatchFile(res, filename, batchNum, sheetName)
-		if err != nil {
-			return files, err
-		}
-		files = append(files, batchFilename)
-	}
-
-	return files, nil
-}
+	path := progress.dir() + filename
 
-func (s *ExporterService) createPelangganBatchFile(batch []*domain.Pelanggan, filename string, batchNum int, sheetName string) (string, error) {
-	f := excelize.NewFile()
-	defer func() {
-		if err := f.Close(); err != nil {
-			s.logger.Error("error_create_new_file", zap.Error(err))
+	if totalRows > 0 {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			errMakeDir := os.Mkdir(path, 0o750) // Secure permissions: owner=rwx, group=rx
+			if errMakeDir != nil {
+				s.logger.Error(
+					"error_make_dir",
+					zap.Error(errMakeDir),
+				)
+				return nil, 0, errMakeDir
+			}
 		}
-	}()
-
-	if err := f.SetSheetName("Sheet1", sheetName); err != nil {
-		s.logger.Error("create_sheet", zap.Error(err))
-		return "", err
-	}
-
-	sw, err := f.NewStreamWriter(sheetName)
-	if err != nil {
-		s.logger.Error("error_create_stream_writer", zap.Error(err))
-		return "", err
-	}
-
-	if err := sw.SetColWidth(2, 15, 26); err != nil {
-		s.logger.Error("error_set_col", zap.Error(err))
-		return "", err
 	}
 
-	if err := s.setHeaders(sw, f, pelangganHeaders); err != nil {
-		s.logger.Error("error_set_error", zap.Error(err))
-		return "", err
-	}
-
-	s.logger.Info("length_of_batch", zap.Int("rows", len(batch)))
-
-	if err := s.writePelangganRows(sw, batch); err != nil {
-		return "", err
-	}
-
-	if err := sw.Flush(); err != nil {
-		s.logger.Error("error_flush_file", zap.Error(err))
-		return "", err
-	}
-
-	batchFilename := fmt.Sprintf("files/REKAP_PELANGGAN_EXPORT_%s_PART_%d.xlsx", filename, batchNum+1)
-	if err := f.SaveAs(batchFilename); err != nil {
-		s.logger.Error("error_save_excel_file", zap.Error(err))
-		return "", err
-	}
-
-	s.logger.Info("batch_saved", zap.Int("batch", batchNum+1), zap.String("to", batchFilename))
-	return batchFilename, nil
+	return s.writeDataset(ctx, &datasetExport{
+		exportType:  domain.EXPORT_TYPE_TRANSAKSI_ALL,
+		req:         req,
+		total:       int64(totalRows),
+		rowsPerFile: datasetBatchSize,
+		path: func(part, parts int) string {
+			return fmt.Sprintf("%s/REKAP_TRANSAKSI_EXPORT_%s_PART_%d.%s", path, filename, part, format)
+		},
+		sheetName: "Rekap Transaksi",
+	}, progress)
 }
 
-func (s *ExporterService) writePelangganRows(sw *excelize.StreamWriter, batch []*domain.Pelanggan) error {
-	for i, data := range batch {
-		rowIndex := i + 1
-		cell, err := excelize.CoordinatesToCellName(1, rowIndex+1)
-		if err != nil {
-			s.logger.Error("error_create_coordinate", zap.Error(err))
-			return err
-		}
-		if err := sw.SetRow(cell, []interface{}{
-			rowIndex,
-			data.IDPel,
-			data.Name,
-			data.ConsumerName,
-			data.EnergyType,
-			data.KWH,
-			data.Address,
-			data.MeterNo,
-			data.MeterType,
-			data.UnitUpi,
-			data.NamaUnitUpi,
-			data.UnitAp,
-			data.NamaUnitAp,
-			data.UnitUp,
-			data.NamaUnitUp,
-			data.CreatedAt,
-		}); err != nil {
-			s.logger.Error("error_set_row", zap.Error(err))
-			return fmt.Errorf("error set row : %s", err.Error())
-		}
-	}
-	return nil
-}
-
-func (s *ExporterService) compressFiles(files []string, outputFile string) error {
-	s.logger.Info(
-		"compress_started",
-		zap.Int("file_count", len(files)),
-		zap.Strings("files", files),
-	)
-
+// validateFilesPath only accepts relative paths inside the 'files/' directory.
+func validateFilesPath(path string) error {
 	// Security: Prevent path traversal and absolute paths
-	if strings.Contains(outputFile, "..") || strings.HasPrefix(outputFile, "/") || strings.HasPrefix(outputFile, "\\") {
-		s.logger.Error("invalid_output_file_path", zap.String("outputFile", outputFile))
-		return fmt.Errorf("invalid output file path: %s", outputFile)
-	}
-
-	// Only allow output in the 'files/' directory
-	if !strings.HasPrefix(outputFile, "files/") && !strings.HasPrefix(outputFile, "files\\") {
-		s.logger.Error("output_file_must_be_in_files_dir", zap.String("outputFile", outputFile))
-		return fmt.Errorf("output file must be in 'files/' directory: %s", outputFile)
-	}
-
-	outFile, err := os.Create(outputFile)
-	if err != nil {
-		s.logger.Error(
-			"error_create_output_file",
-			zap.Error(err),
-		)
-		return err
+	if strings.Contains(path, "..") || strings.HasPrefix(path, "/") || strings.HasPrefix(path, "\\") {
+		return fmt.Errorf("invalid file path: %s", path)
 	}
-	defer outFile.Close()
-
-	gzipWriter := gzip.NewWriter(outFile)
-	defer gzipWriter.Close()
-
-	gzipWriter, err = gzip.NewWriterLevel(outFile, gzip.BestCompression)
-	if err != nil {
-		s.logger.Error(
-			"error_set_compression_level",
-			zap.Error(err),
-		)
-		return err
-	}
-
-	tarWriter := tar.NewWriter(gzipWriter)
-	defer tarWriter.Close()
-
-	for _, file := range files {
-		// Open the input file
-		inFile, err := os.Open(file)
-		if err != nil {
-			s.logger.Error(
-				"error_open_file",
-				zap.Error(err),
-			)
-			return err
-		}
-		defer inFile.Close()
-
-		fileInfo, err := inFile.Stat()
-		if err != nil {
-			s.logger.Error(
-				"error_get_file_info",
-				zap.Error(err),
-			)
-			return err
-		}
-
-		header := &tar.Header{
-			Name:    file,                   // File name
-			Mode:    int64(fileInfo.Mode()), // File mode
-			Size:    fileInfo.Size(),        // File size
-			ModTime: fileInfo.ModTime(),     // Modification time
-		}
 
-		if err := tarWriter.WriteHeader(header); err != nil {
-			s.logger.Error(
-				"error_writing_header",
-				zap.Error(err),
-			)
-			return err
-		}
-
-		if _, err := io.Copy(tarWriter, inFile); err != nil {
-			s.logger.Error(
-				"error_copy_file",
-				zap.Error(err),
-			)
-			return err
-		}
+	// Only allow files in the 'files/' directory
+	if !strings.HasPrefix(path, "files/") && !strings.HasPrefix(path, "files\\") {
+		return fmt.Errorf("file must be in 'files/' directory: %s", path)
 	}
 
 	return nil
@@ -0,0 +1,52 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"event-registration/internal/common"
+	"event-registration/internal/core/domain"
+	"event-registration/internal/core/service"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newExporterService builds the service from p, with an empty config and a
+// no-op logger unless p sets them.
+func newExporterService(p service.ExporterParams) *service.ExporterService {
+	if p.Config == nil {
+		p.Config = &common.Config{}
+	}
+
+	if p.Logger == nil {
+		p.Logger = zap.NewNop()
+	}
+
+	return service.NewExporterService(p)
+}
+
+func TestFindExportJob(t *testing.T) {
+	repo := &memoryExportJobRepo{jobs: map[string]*domain.ExportJob{
+		"job-1": {ID: "job-1", Status: domain.EXPORT_JOB_DONE, RequestedBy: "user-1"},
+	}}
+	s := newExporterService(service.ExporterParams{Jobs: repo})
+
+	t.Run("owner", func(t *testing.T) {
+		job, err := s.FindExportJob(context.Background(), "job-1", "user-1")
+		require.NoError(t, err)
+		require.Equal(t, "job-1", job.ID)
+	})
+
+	t.Run("other user", func(t *testing.T) {
+		_, err := s.FindExportJob(context.Background(), "job-1", "user-2")
+		require.ErrorIs(t, err, domain.ErrExportJobNotFound)
+
+		_, err = s.PinExportJob(context.Background(), "job-1", "user-2", true)
+		require.ErrorIs(t, err, domain.ErrExportJobNotFound)
+		require.False(t, repo.jobs["job-1"].Pinned)
+
+		_, err = s.CancelExportJob(context.Background(), "job-1", "user-2")
+		require.ErrorIs(t, err, domain.ErrExportJobNotFound)
+	})
+}
@@ -0,0 +1,295 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// reconcileSampleRows is how many mismatched rows get their ids compared
+	reconcileSampleRows = 50
+	// reconcileSampleIDs is how many ids of each side a row keeps
+	reconcileSampleIDs = 10
+)
+
+var reconciliationHeaders = []string{
+	"UNIT UP", "NAMA UNIT UP", "TANGGAL",
+	"JUMLAH DWH", "JUMLAH PLN MOBILE", "SELISIH JUMLAH",
+	"NOMINAL DWH", "NOMINAL PLN MOBILE", "SELISIH NOMINAL",
+	"STATUS", "HANYA DI DWH", "HANYA DI PLN MOBILE",
+}
+
+var reconciliationColTypes = []string{
+	"", "", "",
+	cellTypeNumber, cellTypeNumber, cellTypeNumber,
+	cellTypeRupiah, cellTypeRupiah, cellTypeRupiah,
+	"", "", "",
+}
+
+var reconciliationColWidths = []float64{12, 30, 12, 14, 18, 16, 18, 20, 18, 12, 40, 40}
+
+// ReconcileTransaksi compares the transactions of the DWH and of PLN Mobile
+// per unit and per day. A row is mismatched when its count or its amount
+// differs by more than the threshold, in percent of the larger side. The
+// first mismatched rows are sampled with the ids found on one side only.
+func (s *ExporterService) ReconcileTransaksi(ctx context.Context, req *request.RekapRequest, progress *ExportProgress) (*domain.ReconciliationReport, error) {
+	dwhReq, plnMobileReq := *req, *req
+	dwhReq.IsDBPlnMobile = false
+	plnMobileReq.IsDBPlnMobile = true
+
+	dwh, err := s.repo.DailyTotalsTransaksi(ctx, &dwhReq)
+	if err != nil {
+		return nil, fmt.Errorf("error reconcile dwh : %w", err)
+	}
+
+	plnMobile, err := s.repo.DailyTotalsTransaksi(ctx, &plnMobileReq)
+	if err != nil {
+		return nil, fmt.Errorf("error reconcile plnmobile : %w", err)
+	}
+
+	report := &domain.ReconciliationReport{
+		Path:             fmt.Sprintf("%sREKONSILIASI_%s.%s", progress.dir(), exportBaseFilename(req), domain.EXPORT_FORMAT_XLSX),
+		ThresholdPercent: s.reconcileThreshold(req),
+		Mismatches:       []domain.ReconciliationRow{},
+	}
+
+	rows := mergeDailyTotals(dwh, plnMobile, report.ThresholdPercent)
+	progress.SetTotal(int64(len(rows)))
+
+	for i := range rows {
+		row := &rows[i]
+
+		report.Rows++
+		report.DwhCount += row.DwhCount
+		report.PlnMobileCount += row.PlnMobileCount
+		report.DwhAmount += row.DwhAmount
+		report.PlnMobileAmount += row.PlnMobileAmount
+
+		if row.Status != domain.RECONCILIATION_MISMATCHED {
+			continue
+		}
+
+		report.Mismatched++
+		if report.Mismatched > reconcileSampleRows {
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if err := s.sampleMismatch(ctx, req, row); err != nil {
+			return nil, err
+		}
+
+		report.Mismatches = append(report.Mismatches, *row)
+	}
+
+	if err := s.writeReconciliation(report.Path, rows); err != nil {
+		return nil, err
+	}
+
+	progress.AddFile(report.Path, len(rows))
+	progress.AddRows(len(rows))
+
+	s.logger.Info(
+		"done_reconcile",
+		zap.String("path", report.Path),
+		zap.Int("rows", report.Rows),
+		zap.Int("mismatched", report.Mismatched),
+	)
+
+	return report, nil
+}
+
+func (s *ExporterService) reconcileThreshold(req *request.RekapRequest) float64 {
+	if req.ThresholdPercent != nil {
+		return *req.ThresholdPercent
+	}
+
+	return s.config.ReconcileThresholdPercent
+}
+
+// mergeDailyTotals pairs the totals of both sources by unit and day. Both
+// are sorted again here, the two databases may not order unit codes the
+// same way. A side without transactions counts as zero.
+func mergeDailyTotals(dwh, plnMobile []*domain.TransaksiDailyTotal, threshold float64) []domain.ReconciliationRow {
+	slices.SortFunc(dwh, compareDailyTotals)
+	slices.SortFunc(plnMobile, compareDailyTotals)
+
+	rows := []domain.ReconciliationRow{}
+	i, j := 0, 0
+
+	for i < len(dwh) || j < len(plnMobile) {
+		var row domain.ReconciliationRow
+
+		switch {
+		case j == len(plnMobile) || (i < len(dwh) && compareDailyTotals(dwh[i], plnMobile[j]) < 0):
+			row = reconciliationRow(dwh[i])
+			row.DwhCount, row.DwhAmount = dwh[i].Count, dwh[i].TotalAmount
+			i++
+		case i == len(dwh) || compareDailyTotals(plnMobile[j], dwh[i]) < 0:
+			row = reconciliationRow(plnMobile[j])
+			row.PlnMobileCount, row.PlnMobileAmount = plnMobile[j].Count, plnMobile[j].TotalAmount
+			j++
+		default:
+			row = reconciliationRow(dwh[i])
+			row.DwhCount, row.DwhAmount = dwh[i].Count, dwh[i].TotalAmount
+			row.PlnMobileCount, row.PlnMobileAmount = plnMobile[j].Count, plnMobile[j].TotalAmount
+			i++
+			j++
+		}
+
+		row.Status = domain.RECONCILIATION_MATCHED
+		if differsBy(float64(row.DwhCount), float64(row.PlnMobileCount), threshold) ||
+			differsBy(row.DwhAmount, row.PlnMobileAmount, threshold) {
+			row.Status = domain.RECONCILIATION_MISMATCHED
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// compareDailyTotals orders totals by unit, then day, byte by byte.
+func compareDailyTotals(a, b *domain.TransaksiDailyTotal) int {
+	if c := cmp.Compare(a.UnitUP, b.UnitUP); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Day, b.Day)
+}
+
+func reconciliationRow(total *domain.TransaksiDailyTotal) domain.ReconciliationRow {
+	return domain.ReconciliationRow{
+		UnitUP:     total.UnitUP,
+		NameUnitUP: total.NameUnitUP,
+		Day:        total.Day,
+	}
+}
+
+// differsBy reports whether a and b differ by more than threshold percent of
+// the larger one.
+func differsBy(a, b, threshold float64) bool {
+	larger := math.Max(math.Abs(a), math.Abs(b))
+	if larger == 0 {
+		return false
+	}
+
+	return math.Abs(a-b)/larger*100 > threshold
+}
+
+// sampleMismatch looks up the transactions of the row's unit and day on both
+// sides and keeps the first ids found on one side only.
+func (s *ExporterService) sampleMismatch(ctx context.Context, req *request.RekapRequest, row *domain.ReconciliationRow) error {
+	day := strings.ReplaceAll(row.Day, "-", "/")
+
+	dwhReq := unitRequest(req, "", "", "", row.UnitUP)
+	dwhReq.DateStart, dwhReq.DateEnd = day, day
+	dwhReq.IsDBPlnMobile = false
+
+	plnMobileReq := *dwhReq
+	plnMobileReq.IsDBPlnMobile = true
+
+	dwhIDs, err := s.repo.TransaksiIDs(ctx, dwhReq)
+	if err != nil {
+		return fmt.Errorf("error sample dwh ids : %w", err)
+	}
+
+	plnMobileIDs, err := s.repo.TransaksiIDs(ctx, &plnMobileReq)
+	if err != nil {
+		return fmt.Errorf("error sample plnmobile ids : %w", err)
+	}
+
+	row.OnlyInDwh = missingIDs(dwhIDs, plnMobileIDs)
+	row.OnlyInPlnMobile = missingIDs(plnMobileIDs, dwhIDs)
+
+	return nil
+}
+
+// missingIDs returns up to reconcileSampleIDs ids of ids that are not in
+// others.
+func missingIDs(ids, others []string) []string {
+	seen := make(map[string]bool, len(others))
+	for _, id := range others {
+		seen[id] = true
+	}
+
+	var missing []string
+	for _, id := range ids {
+		if len(missing) == reconcileSampleIDs {
+			break
+		}
+		if !seen[id] {
+			missing = append(missing, id)
+		}
+	}
+
+	return missing
+}
+
+func (s *ExporterService) writeReconciliation(path string, rows []domain.ReconciliationRow) error {
+	if err := validateFilesPath(path); err != nil {
+		return err
+	}
+
+	w, err := s.newRowWriter(domain.EXPORT_FORMAT_XLSX, path, writerOptions{
+		sheetName: "Rekonsiliasi",
+		colWidths: reconciliationColWidths,
+		colTypes:  reconciliationColTypes,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := w.WriteHeader(reconciliationHeaders); err != nil {
+		w.Close()
+		return err
+	}
+
+	for _, row := range rows {
+		// typed cells are parsed from text
+		err := w.WriteRow([]interface{}{
+			row.UnitUP,
+			row.NameUnitUP,
+			row.Day,
+			strconv.FormatInt(row.DwhCount, 10),
+			strconv.FormatInt(row.PlnMobileCount, 10),
+			strconv.FormatInt(row.DwhCount-row.PlnMobileCount, 10),
+			formatAmount(row.DwhAmount),
+			formatAmount(row.PlnMobileAmount),
+			formatAmount(row.DwhAmount - row.PlnMobileAmount),
+			row.Status,
+			strings.Join(row.OnlyInDwh, ", "),
+			strings.Join(row.OnlyInPlnMobile, ", "),
+		})
+		if err != nil {
+			w.Close()
+			return err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		s.logger.Error(
+			"error_write_reconciliation",
+			zap.String("path", path),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
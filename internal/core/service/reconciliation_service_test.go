@@ -0,0 +1,129 @@
+package service_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"event-registration/internal/common"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"event-registration/internal/core/service"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// reconcilingRepo serves different daily totals and ids for the DWH and
+// for PLN Mobile.
+type reconcilingRepo struct {
+	flakyExporterRepo
+	dwh, plnMobile       []*domain.TransaksiDailyTotal
+	dwhIDs, plnMobileIDs []string
+	sampled              []*request.RekapRequest
+}
+
+func (r *reconcilingRepo) DailyTotalsTransaksi(ctx context.Context, req *request.RekapRequest) ([]*domain.TransaksiDailyTotal, error) {
+	if req.IsDBPlnMobile {
+		return r.plnMobile, nil
+	}
+	return r.dwh, nil
+}
+
+func (r *reconcilingRepo) TransaksiIDs(ctx context.Context, req *request.RekapRequest) ([]string, error) {
+	r.sampled = append(r.sampled, req)
+	if req.IsDBPlnMobile {
+		return r.plnMobileIDs, nil
+	}
+	return r.dwhIDs, nil
+}
+
+func TestReconcileTransaksi(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("files", 0o750))
+
+	repo := &reconcilingRepo{
+		dwh: []*domain.TransaksiDailyTotal{
+			{UnitUP: "54110", NameUnitUP: "UP Menteng", Day: "2026-02-01", Count: 100, TotalAmount: 1000000},
+			{UnitUP: "54110", NameUnitUP: "UP Menteng", Day: "2026-02-02", Count: 3, TotalAmount: 60000},
+			{UnitUP: "54120", NameUnitUP: "UP Bulungan", Day: "2026-02-01", Count: 1, TotalAmount: 20000},
+		},
+		plnMobile: []*domain.TransaksiDailyTotal{
+			// within the 1% threshold
+			{UnitUP: "54110", NameUnitUP: "UP Menteng", Day: "2026-02-01", Count: 100, TotalAmount: 995000},
+			{UnitUP: "54110", NameUnitUP: "UP Menteng", Day: "2026-02-02", Count: 2, TotalAmount: 40000},
+			{UnitUP: "54110", NameUnitUP: "UP Menteng", Day: "2026-02-03", Count: 1, TotalAmount: 20000},
+		},
+		dwhIDs:       []string{"a", "b", "c"},
+		plnMobileIDs: []string{"a", "c", "d"},
+	}
+
+	cfg := &common.Config{ReconcileThresholdPercent: 1}
+	s := newExporterService(service.ExporterParams{Repo: repo, Config: cfg})
+
+	req := &request.RekapRequest{Area: "54100", DateStart: "2026/02/01", DateEnd: "2026/02/28"}
+	report, err := s.ReconcileTransaksi(context.Background(), req, nil)
+	require.NoError(t, err)
+
+	require.Equal(t, "files/REKONSILIASI_AREA_54100_20260201_20260228.xlsx", report.Path)
+	require.Equal(t, 4, report.Rows)
+	require.Equal(t, 3, report.Mismatched)
+	require.Equal(t, int64(104), report.DwhCount)
+	require.Equal(t, int64(103), report.PlnMobileCount)
+
+	require.Len(t, report.Mismatches, 3)
+	mismatch := report.Mismatches[0]
+	require.Equal(t, "2026-02-02", mismatch.Day)
+	require.Equal(t, []string{"b"}, mismatch.OnlyInDwh)
+	require.Equal(t, []string{"d"}, mismatch.OnlyInPlnMobile)
+
+	// ids are compared for the unit and day of the mismatch only
+	require.Equal(t, "54110", repo.sampled[0].UnitCode)
+	require.Empty(t, repo.sampled[0].Area)
+	require.Equal(t, "2026/02/02", repo.sampled[0].DateStart)
+	require.Equal(t, "2026/02/02", repo.sampled[0].DateEnd)
+
+	f, err := excelize.OpenFile(report.Path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	rows, err := f.GetRows("Rekonsiliasi")
+	require.NoError(t, err)
+	require.Len(t, rows, 5)
+	require.Equal(t, domain.RECONCILIATION_MATCHED, rows[1][9])
+	require.Equal(t, domain.RECONCILIATION_MISMATCHED, rows[2][9])
+
+	t.Run("threshold from the request", func(t *testing.T) {
+		threshold := 0.0
+		req := &request.RekapRequest{Area: "54100", DateStart: "2026/02/01", DateEnd: "2026/02/28", ThresholdPercent: &threshold}
+
+		report, err := s.ReconcileTransaksi(context.Background(), req, nil)
+		require.NoError(t, err)
+		require.Equal(t, 4, report.Mismatched)
+	})
+}
+
+func TestReconcileTransaksiUnitOrder(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.MkdirAll("files", 0o750))
+
+	// a linguistic collation ignores the dash, byte order does not
+	repo := &reconcilingRepo{
+		dwh: []*domain.TransaksiDailyTotal{
+			{UnitUP: "5411", Day: "2026-02-01", Count: 1, TotalAmount: 20000},
+			{UnitUP: "54-110", Day: "2026-02-01", Count: 2, TotalAmount: 40000},
+		},
+		plnMobile: []*domain.TransaksiDailyTotal{
+			{UnitUP: "54-110", Day: "2026-02-01", Count: 2, TotalAmount: 40000},
+			{UnitUP: "5411", Day: "2026-02-01", Count: 1, TotalAmount: 20000},
+		},
+	}
+
+	s := newExporterService(service.ExporterParams{Repo: repo, Config: &common.Config{ReconcileThresholdPercent: 1}})
+
+	req := &request.RekapRequest{Area: "54100", DateStart: "2026/02/01", DateEnd: "2026/02/28"}
+	report, err := s.ReconcileTransaksi(context.Background(), req, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, report.Rows)
+	require.Zero(t, report.Mismatched)
+}
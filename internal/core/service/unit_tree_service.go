@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"event-registration/internal/common"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+// UnitTreeService serves the Regional→Induk→Area→Unit hierarchy from the
+// Redis cache. Every UNIT_TREE_REFRESH_INTERVAL it compares the version of
+// the cached tree with the unit tables and rebuilds it when a unit changed.
+type UnitTreeService struct {
+	repo   domain.ExporterRepository
+	cache  domain.UnitTreeCache
+	config *common.Config
+	logger *zap.Logger
+	cron   *cron.Cron
+
+	// refreshMu lets one rebuild query the unit tables at a time
+	refreshMu sync.Mutex
+}
+
+func NewUnitTreeService(repo domain.ExporterRepository, cache domain.UnitTreeCache, config *common.Config, logger *zap.Logger) *UnitTreeService {
+	return &UnitTreeService{
+		repo:   repo,
+		cache:  cache,
+		config: config,
+		logger: logger,
+	}
+}
+
+func (s *UnitTreeService) Start() error {
+	if s.config.UnitTreeRefreshInterval <= 0 {
+		s.logger.Info("unit_tree_refresh_disabled")
+		return nil
+	}
+
+	s.cron = cron.New()
+	s.cron.Schedule(cron.Every(s.config.UnitTreeRefreshInterval), cron.FuncJob(func() {
+		s.RefreshUnitTree(context.Background())
+	}))
+	s.cron.Start()
+
+	s.logger.Info(
+		"unit_tree_refresh_started",
+		zap.Duration("interval", s.config.UnitTreeRefreshInterval),
+	)
+
+	return nil
+}
+
+func (s *UnitTreeService) Stop() {
+	if s.cron == nil {
+		return
+	}
+
+	<-s.cron.Stop().Done()
+}
+
+// FindUnitTree returns the hierarchy, narrowed down to the subtree of
+// req.Code and to the units whose name contains req.Search when set. Matching
+// units keep their descendants and the ancestors leading to them.
+func (s *UnitTreeService) FindUnitTree(ctx context.Context, req *request.UnitTreeRequest) ([]*domain.Regional, error) {
+	tree, err := s.unitTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	regional := tree.Regional
+
+	if len(req.Code) > 0 {
+		regional = filterUnitTree(regional, func(code, name string) bool {
+			return code == req.Code
+		})
+		if len(regional) == 0 {
+			return nil, domain.ErrUnitNotFound
+		}
+	}
+
+	if len(req.Search) > 0 {
+		search := strings.ToLower(req.Search)
+		regional = filterUnitTree(regional, func(code, name string) bool {
+			return strings.Contains(strings.ToLower(name), search)
+		})
+	}
+
+	return regional, nil
+}
+
+func (s *UnitTreeService) unitTree(ctx context.Context) (*domain.UnitTree, error) {
+	tree, err := s.cache.Get(ctx)
+	if err == nil {
+		return tree, nil
+	}
+
+	if !errors.Is(err, domain.ErrUnitTreeNotCached) {
+		s.logger.Error(
+			"error_get_unit_tree",
+			zap.Error(err),
+		)
+	}
+
+	return s.RefreshUnitTree(ctx)
+}
+
+// RefreshUnitTree rebuilds the cached tree unless it was built from the
+// current version of the unit tables.
+func (s *UnitTreeService) RefreshUnitTree(ctx context.Context) (*domain.UnitTree, error) {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	version, err := s.repo.UnitsVersion(ctx)
+	if err != nil {
+		s.logger.Error(
+			"error_units_version",
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	if cached, err := s.cache.Get(ctx); err == nil && cached.Version == version {
+		return cached, nil
+	}
+
+	regional, err := s.repo.GetAllUnit(ctx)
+	if err != nil {
+		s.logger.Error(
+			"error_get_all_unit",
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	tree := &domain.UnitTree{
+		Version:  version,
+		Regional: regional,
+		BuiltAt:  time.Now(),
+	}
+
+	// the fresh tree is still served when it cannot be cached
+	if err := s.cache.Set(ctx, tree, s.config.UnitTreeTTL); err != nil {
+		s.logger.Error(
+			"error_set_unit_tree",
+			zap.Error(err),
+		)
+	}
+
+	s.logger.Info(
+		"unit_tree_built",
+		zap.String("version", version),
+		zap.Int("regional", len(regional)),
+	)
+
+	return tree, nil
+}
+
+// filterUnitTree keeps the nodes match accepts with all their descendants,
+// and the ancestors leading to them. The nodes of regional are not modified.
+func filterUnitTree(regional []*domain.Regional, match func(code, name string) bool) []*domain.Regional {
+	result := []*domain.Regional{}
+	for _, r := range regional {
+		if match(r.IDRegAPKT, r.NamaRegional) {
+			result = append(result, r)
+			continue
+		}
+
+		if induk := filterInduk(r.Induk, match); len(induk) > 0 {
+			narrowed := *r
+			narrowed.Induk = induk
+			result = append(result, &narrowed)
+		}
+	}
+
+	return result
+}
+
+func filterInduk(induk []domain.Induk, match func(code, name string) bool) []domain.Induk {
+	var result []domain.Induk
+	for _, i := range induk {
+		if match(i.IDUnitUPI, i.NamaUnitUPI) {
+			result = append(result, i)
+			continue
+		}
+
+		if area := filterArea(i.Area, match); len(area) > 0 {
+			i.Area = area
+			result = append(result, i)
+		}
+	}
+
+	return result
+}
+
+func filterArea(area []domain.Area, match func(code, name string) bool) []domain.Area {
+	var result []domain.Area
+	for _, a := range area {
+		if match(a.IDUnitAP, a.NamaUnitAP) {
+			result = append(result, a)
+			continue
+		}
+
+		var units []domain.Unit
+		for _, u := range a.Unit {
+			if match(u.IDUnitUP, u.NamaUnitUP) {
+				units = append(units, u)
+			}
+		}
+
+		if len(units) > 0 {
+			a.Unit = units
+			result = append(result, a)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,115 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"event-registration/internal/common"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"event-registration/internal/core/service"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type memoryUnitTreeCache struct {
+	tree *domain.UnitTree
+}
+
+func (c *memoryUnitTreeCache) Get(ctx context.Context) (*domain.UnitTree, error) {
+	if c.tree == nil {
+		return nil, domain.ErrUnitTreeNotCached
+	}
+	return c.tree, nil
+}
+
+func (c *memoryUnitTreeCache) Set(ctx context.Context, tree *domain.UnitTree, expiration time.Duration) error {
+	c.tree = tree
+	return nil
+}
+
+// versionedUnitRepo serves the units of flakyExporterRepo under version,
+// counting how often they are loaded.
+type versionedUnitRepo struct {
+	flakyExporterRepo
+	version string
+	loads   int
+}
+
+func (r *versionedUnitRepo) UnitsVersion(ctx context.Context) (string, error) {
+	return r.version, nil
+}
+
+func (r *versionedUnitRepo) GetAllUnit(ctx context.Context) ([]*domain.Regional, error) {
+	r.loads++
+
+	regional, err := r.flakyExporterRepo.GetAllUnit(ctx)
+	regional[0].IDRegAPKT = "1"
+	regional[0].NamaRegional = "Jawa Madura Bali"
+	regional[0].Induk[0].Area[0].Unit = []domain.Unit{
+		{IDUnitUP: "54110", NamaUnitUP: "Cikini"},
+		{IDUnitUP: "54120", NamaUnitUP: "Kramat Jati"},
+	}
+	return regional, err
+}
+
+func TestUnitTree(t *testing.T) {
+	repo := &versionedUnitRepo{version: "v1"}
+	cache := &memoryUnitTreeCache{}
+	s := service.NewUnitTreeService(repo, cache, &common.Config{UnitTreeTTL: time.Hour}, zap.NewNop())
+
+	tree, err := s.FindUnitTree(context.Background(), &request.UnitTreeRequest{})
+	require.NoError(t, err)
+	require.Len(t, tree, 1)
+	require.Len(t, tree[0].Induk[0].Area, 2)
+	require.Equal(t, "v1", cache.tree.Version)
+
+	t.Run("served from the cache", func(t *testing.T) {
+		_, err := s.FindUnitTree(context.Background(), &request.UnitTreeRequest{})
+		require.NoError(t, err)
+		require.Equal(t, 1, repo.loads)
+	})
+
+	t.Run("subtree by code", func(t *testing.T) {
+		tree, err := s.FindUnitTree(context.Background(), &request.UnitTreeRequest{Code: "21"})
+		require.NoError(t, err)
+
+		// the ancestors of area 21 and its whole subtree
+		require.Len(t, tree[0].Induk, 1)
+		require.Len(t, tree[0].Induk[0].Area, 1)
+		require.Equal(t, "Menteng", tree[0].Induk[0].Area[0].NamaUnitAP)
+		require.Len(t, tree[0].Induk[0].Area[0].Unit, 2)
+
+		// narrowing does not touch the cached tree
+		require.Len(t, cache.tree.Regional[0].Induk[0].Area, 2)
+	})
+
+	t.Run("unknown code", func(t *testing.T) {
+		_, err := s.FindUnitTree(context.Background(), &request.UnitTreeRequest{Code: "99999"})
+		require.ErrorIs(t, err, domain.ErrUnitNotFound)
+	})
+
+	t.Run("search by name", func(t *testing.T) {
+		tree, err := s.FindUnitTree(context.Background(), &request.UnitTreeRequest{Search: "kramat"})
+		require.NoError(t, err)
+		require.Equal(t, []domain.Unit{{IDUnitUP: "54120", NamaUnitUP: "Kramat Jati"}}, tree[0].Induk[0].Area[0].Unit)
+
+		tree, err = s.FindUnitTree(context.Background(), &request.UnitTreeRequest{Search: "bandung"})
+		require.NoError(t, err)
+		require.Empty(t, tree)
+	})
+
+	t.Run("refreshed when units change", func(t *testing.T) {
+		_, err := s.RefreshUnitTree(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 1, repo.loads)
+
+		repo.version = "v2"
+		_, err = s.RefreshUnitTree(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 2, repo.loads)
+		require.Equal(t, "v2", cache.tree.Version)
+	})
+}
@@ -44,8 +44,8 @@ func (s *UserService) Search(ctx context.Context, keyword string) (users []*doma
 	return users, nil
 }
 
-func (s *UserService) Roles() (roles []*domain.Role, err error) {
-	roles, err = s.repo.Roles()
+func (s *UserService) Roles(ctx context.Context) (roles []*domain.Role, err error) {
+	roles, err = s.repo.Roles(ctx)
 	if err != nil {
 		s.logger.Error("error_get_roles", zap.Error(err))
 		return nil, err
@@ -54,7 +54,7 @@ func (s *UserService) Roles() (roles []*domain.Role, err error) {
 	return roles, nil
 }
 
-func (s *UserService) GetUnits(level string) (units []*domain.UnitName, err error) {
+func (s *UserService) GetUnits(ctx context.Context, level string) (units []*domain.UnitName, err error) {
 	if level == "0" {
 		units = append(units, &domain.UnitName{
 			Label: "Pusat",
@@ -63,7 +63,7 @@ func (s *UserService) GetUnits(level string) (units []*domain.UnitName, err erro
 
 		return units, nil
 	}
-	units, err = s.repo.Unit(level)
+	units, err = s.repo.Unit(ctx, level)
 	if err != nil {
 		s.logger.Error("error_get_units", zap.Error(err))
 		return nil, err
@@ -103,14 +103,19 @@ func (s *UserService) Update(ctx context.Context, req *request.UpdateUserRequest
 		})
 	}
 
-	err = s.repo.Update(user)
+	err = s.repo.Update(ctx, user)
 	if err != nil {
 		s.logger.Error("error_update_user", zap.Error(err))
 		return err
 	}
 
+	// the request ctx is done once the response is sent, the index update
+	// outlives it
+	indexCtx := context.WithoutCancel(ctx)
 	go func() {
-		s.meilirepo.Update(ctx, user)
+		if err := s.meilirepo.Update(indexCtx, user); err != nil {
+			s.logger.Error("error_update_user_meilisearch", zap.Error(err))
+		}
 	}()
 
 	return nil
@@ -112,7 +112,7 @@ func (h *AuthHandler) Protected(c *fiber.Ctx) error {
 func (h *AuthHandler) RefreshToken(c *fiber.Ctx) error {
 	user := h.handler.ParseUser(c)
 
-	accessToken, refreshToken, err := h.service.GenerateToken(&user)
+	accessToken, refreshToken, err := h.service.GenerateToken(c.Context(), &user)
 	if err != nil {
 		return h.handler.ResponseWithStatus(c, http.StatusBadRequest, "Failed to generate access token", nil)
 	}
@@ -36,7 +36,7 @@ func (h *EventHandler) RegisterEvent(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	err := h.service.RegisterEvent(req.EventID, req.UserID)
+	err := h.service.RegisterEvent(c.Context(), req.EventID, req.UserID)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
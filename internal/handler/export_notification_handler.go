@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"event-registration/internal/common/constant"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/service"
+	validate "event-registration/internal/infrastructure/validator"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type ExportNotificationHandler struct {
+	service   *service.ExportNotifier
+	validator *validate.Validator
+	logger    *zap.Logger
+}
+
+func NewExportNotificationHandler(service *service.ExportNotifier, validator *validate.Validator, logger *zap.Logger) *ExportNotificationHandler {
+	return &ExportNotificationHandler{service: service, validator: validator, logger: logger}
+}
+
+// Find export notification setting godoc
+// @Summary Find export notification setting
+// @Description Where the requester is notified when one of their exports finishes
+// @Tags exporter
+// @Produce  json
+// @Success 200 {object} domain.ExportNotificationSetting
+// @Failure 400 {object} map[string]string
+// @Router /exports/notifications [get]
+func (h *ExportNotificationHandler) FindSetting(c *fiber.Ctx) error {
+	setting, err := h.service.FindSetting(c.Context(), requester(c))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": setting})
+}
+
+// Save export notification setting godoc
+// @Summary Save export notification setting
+// @Description Set the webhook URL and email the requester is notified on when one of their exports succeeds or fails, empty fields turn that notification off
+// @Tags exporter
+// @Accept  json
+// @Produce  json
+// @Param request body request.ExportNotificationRequest true "..."
+// @Success 200 {object} domain.ExportNotificationSetting
+// @Failure 400 {object} map[string]string
+// @Failure 422 {object} map[string][]string
+// @Router /exports/notifications [put]
+func (h *ExportNotificationHandler) SaveSetting(c *fiber.Ctx) error {
+	request := new(request.ExportNotificationRequest)
+
+	if err := c.BodyParser(request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constant.INVALID_REQUEST_BODY,
+		})
+	}
+
+	if err := h.validator.Struct(request); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error_validations": h.validator.ValidationErrors(err),
+		})
+	}
+
+	setting, err := h.service.SaveSetting(c.Context(), requester(c), request)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": setting})
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"errors"
+	"event-registration/internal/common/constant"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/service"
+	validate "event-registration/internal/infrastructure/validator"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type ExportRetentionHandler struct {
+	service   *service.ExportRetentionService
+	validator *validate.Validator
+	logger    *zap.Logger
+}
+
+func NewExportRetentionHandler(service *service.ExportRetentionService, validator *validate.Validator, logger *zap.Logger) *ExportRetentionHandler {
+	return &ExportRetentionHandler{service: service, validator: validator, logger: logger}
+}
+
+// Run export retention godoc
+// @Summary Run export retention
+// @Description Report export files past the maximum age or over the disk quota, delete removes them. Only EXPORT_RETENTION_ROLES may run it
+// @Tags exporter
+// @Produce  json
+// @Param request query request.RetentionRequest false "..."
+// @Success 200 {object} domain.RetentionReport
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /exports/retention [post]
+func (h *ExportRetentionHandler) RunRetention(c *fiber.Ctx) error {
+	request := new(request.RetentionRequest)
+
+	if err := c.QueryParser(request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constant.INVALID_REQUEST_BODY,
+		})
+	}
+
+	report, err := h.service.TriggerRetention(c.Context(), request, requesterRoles(c))
+	if err != nil {
+		if errors.Is(err, service.ErrExportRetentionForbidden) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": report})
+}
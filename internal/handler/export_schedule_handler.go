@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"errors"
+	"event-registration/internal/common/constant"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"event-registration/internal/core/service"
+	validate "event-registration/internal/infrastructure/validator"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type ExportScheduleHandler struct {
+	service   *service.ExportScheduleService
+	validator *validate.Validator
+	logger    *zap.Logger
+}
+
+func NewExportScheduleHandler(service *service.ExportScheduleService, validator *validate.Validator, logger *zap.Logger) *ExportScheduleHandler {
+	return &ExportScheduleHandler{service: service, validator: validator, logger: logger}
+}
+
+// Create export schedule godoc
+// @Summary Create export schedule
+// @Description Create a recurring export, the exporter runs it whenever its cron expression fires
+// @Tags exporter
+// @Accept  json
+// @Produce  json
+// @Param request body request.ExportScheduleRequest true "..."
+// @Success 201 {object} domain.ExportSchedule
+// @Failure 400 {object} map[string]string
+// @Failure 422 {object} map[string][]string
+// @Router /schedules [post]
+func (h *ExportScheduleHandler) CreateSchedule(c *fiber.Ctx) error {
+	request := new(request.ExportScheduleRequest)
+
+	if err := c.BodyParser(request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constant.INVALID_REQUEST_BODY,
+		})
+	}
+
+	if err := h.validator.Struct(request); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error_validations": h.validator.ValidationErrors(err),
+		})
+	}
+
+	schedule, err := h.service.CreateSchedule(c.Context(), request, requester(c), requesterRoles(c))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCronExpr) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"data": schedule})
+}
+
+// List export schedules godoc
+// @Summary List export schedules
+// @Description List every export schedule
+// @Tags exporter
+// @Produce  json
+// @Success 200 {object} []domain.ExportSchedule
+// @Failure 400 {object} map[string]string
+// @Router /schedules [get]
+func (h *ExportScheduleHandler) FindSchedules(c *fiber.Ctx) error {
+	schedules, err := h.service.FindSchedules(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": schedules})
+}
+
+// List export schedule runs godoc
+// @Summary List export schedule runs
+// @Description List the latest runs of an export schedule with their outcome
+// @Tags exporter
+// @Produce  json
+// @Param id path string true "Schedule ID"
+// @Success 200 {object} []domain.ExportScheduleRun
+// @Failure 404 {object} map[string]string
+// @Router /schedules/{id}/runs [get]
+func (h *ExportScheduleHandler) FindScheduleRuns(c *fiber.Ctx) error {
+	runs, err := h.service.FindScheduleRuns(c.Context(), c.Params("id"))
+	if err != nil {
+		if errors.Is(err, domain.ErrExportScheduleNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": runs})
+}
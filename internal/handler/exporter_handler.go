@@ -1,10 +1,15 @@
 package handler
 
 import (
+	"errors"
 	"event-registration/internal/common/constant"
 	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
 	"event-registration/internal/core/service"
 	validate "event-registration/internal/infrastructure/validator"
+	"math"
+	"path/filepath"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
@@ -27,9 +32,11 @@ func NewExporterHandler(service *service.ExporterService, validator *validate.Va
 // @Accept  json
 // @Produce  json
 // @Param request body request.RekapRequest false "..."
-// @Success 200 {object} map[string]string
+// @Success 202 {object} domain.ExportJob
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
 // @Failure 422 {object} map[string][]string
+// @Failure 429 {object} map[string]interface{}
 // @Router /transaksi [post]
 func (h *ExporterHandler) ExportRekapTransaksi(c *fiber.Ctx) error {
 	request := new(request.RekapRequest)
@@ -46,14 +53,12 @@ func (h *ExporterHandler) ExportRekapTransaksi(c *fiber.Ctx) error {
 		})
 	}
 
-	err := h.service.ExportRekapTransaksi(request)
+	job, err := h.service.EnqueueExport(c.Context(), domain.EXPORT_TYPE_TRANSAKSI, request, requester(c), requesterRoles(c))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return enqueueError(c, err)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": constant.SUCCESS_EXPORT})
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"data": job})
 }
 
 // Get transaksi godoc
@@ -63,9 +68,11 @@ func (h *ExporterHandler) ExportRekapTransaksi(c *fiber.Ctx) error {
 // @Accept  json
 // @Produce  json
 // @Param request body request.RekapRequest false "..."
-// @Success 200 {object} map[string]string
+// @Success 202 {object} domain.ExportJob
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
 // @Failure 422 {object} map[string][]string
+// @Failure 429 {object} map[string]interface{}
 // @Router /transaksi-all [post]
 func (h *ExporterHandler) ExportAllRekapTransaksi(c *fiber.Ctx) error {
 	request := new(request.RekapRequest)
@@ -82,14 +89,12 @@ func (h *ExporterHandler) ExportAllRekapTransaksi(c *fiber.Ctx) error {
 		})
 	}
 
-	err := h.service.ExportAllRekapTransaksi(request)
+	job, err := h.service.EnqueueExport(c.Context(), domain.EXPORT_TYPE_TRANSAKSI_ALL, request, requester(c), requesterRoles(c))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return enqueueError(c, err)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": constant.SUCCESS_EXPORT})
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"data": job})
 }
 
 // Get transaksi by unit id godoc
@@ -99,9 +104,11 @@ func (h *ExporterHandler) ExportAllRekapTransaksi(c *fiber.Ctx) error {
 // @Accept  json
 // @Produce  json
 // @Param request body request.RekapRequest false "..."
-// @Success 200 {object} map[string]string
+// @Success 202 {object} domain.ExportJob
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
 // @Failure 422 {object} map[string][]string
+// @Failure 429 {object} map[string]interface{}
 // @Router /pelanggan [post]
 func (h *ExporterHandler) ExportRekapPelanggan(c *fiber.Ctx) error {
 	request := new(request.RekapRequest)
@@ -118,14 +125,384 @@ func (h *ExporterHandler) ExportRekapPelanggan(c *fiber.Ctx) error {
 		})
 	}
 
-	err := h.service.ExportRekapPelanggan(request)
+	job, err := h.service.EnqueueExport(c.Context(), domain.EXPORT_TYPE_PELANGGAN, request, requester(c), requesterRoles(c))
+	if err != nil {
+		return enqueueError(c, err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"data": job})
+}
+
+// Reconcile transaksi godoc
+// @Summary Reconcile transaksi
+// @Description Compare the transactions of the DWH and of PLN Mobile per unit and per day, the report is written as xlsx
+// @Tags exporter
+// @Accept  json
+// @Produce  json
+// @Param request body request.RekapRequest false "..."
+// @Success 202 {object} domain.ExportJob
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
+// @Failure 422 {object} map[string][]string
+// @Failure 429 {object} map[string]interface{}
+// @Router /reconciliation [post]
+func (h *ExporterHandler) ReconcileTransaksi(c *fiber.Ctx) error {
+	request := new(request.RekapRequest)
+
+	if err := c.BodyParser(request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constant.INVALID_REQUEST_BODY,
+		})
+	}
+
+	if err := h.validator.Struct(request); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error_validations": h.validator.ValidationErrors(err),
+		})
+	}
+
+	job, err := h.service.EnqueueExport(c.Context(), domain.EXPORT_TYPE_RECONCILIATION, request, requester(c), requesterRoles(c))
+	if err != nil {
+		return enqueueError(c, err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"data": job})
+}
+
+// Get export job godoc
+// @Summary Get export job
+// @Description Get state, progress and generated files of an export job
+// @Tags exporter
+// @Produce  json
+// @Param id path string true "Job ID"
+// @Success 200 {object} domain.ExportJob
+// @Failure 404 {object} map[string]string
+// @Router /exports/{id} [get]
+func (h *ExporterHandler) FindExportJob(c *fiber.Ctx) error {
+	job, err := h.service.FindExportJob(c.Context(), c.Params("id"), requester(c))
+	if err != nil {
+		if errors.Is(err, domain.ErrExportJobNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": job})
+}
+
+// Cancel export job godoc
+// @Summary Cancel export job
+// @Description Stop a running export job, files written so far are kept
+// @Tags exporter
+// @Produce  json
+// @Param id path string true "Job ID"
+// @Success 202 {object} domain.ExportJob
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /exports/{id}/cancel [post]
+func (h *ExporterHandler) CancelExportJob(c *fiber.Ctx) error {
+	job, err := h.service.CancelExportJob(c.Context(), c.Params("id"), requester(c))
+	if err != nil {
+		if errors.Is(err, domain.ErrExportJobNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if errors.Is(err, service.ErrExportJobNotRunning) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"data": job})
+}
+
+// Pin export job godoc
+// @Summary Pin export job
+// @Description Keep the files of an export job out of the retention policy
+// @Tags exporter
+// @Produce  json
+// @Param id path string true "Job ID"
+// @Success 200 {object} domain.ExportJob
+// @Failure 404 {object} map[string]string
+// @Router /exports/{id}/pin [post]
+func (h *ExporterHandler) PinExportJob(c *fiber.Ctx) error {
+	return h.pinExportJob(c, true)
+}
+
+// Unpin export job godoc
+// @Summary Unpin export job
+// @Description Let the retention policy remove the files of an export job again
+// @Tags exporter
+// @Produce  json
+// @Param id path string true "Job ID"
+// @Success 200 {object} domain.ExportJob
+// @Failure 404 {object} map[string]string
+// @Router /exports/{id}/pin [delete]
+func (h *ExporterHandler) UnpinExportJob(c *fiber.Ctx) error {
+	return h.pinExportJob(c, false)
+}
+
+func (h *ExporterHandler) pinExportJob(c *fiber.Ctx, pinned bool) error {
+	job, err := h.service.PinExportJob(c.Context(), c.Params("id"), requester(c), pinned)
+	if err != nil {
+		if errors.Is(err, domain.ErrExportJobNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": job})
+}
+
+// List export files godoc
+// @Summary List export files
+// @Description List files generated by the current user's export jobs with signed download URLs
+// @Tags exporter
+// @Produce  json
+// @Success 200 {object} []domain.ExportArtifact
+// @Failure 400 {object} map[string]string
+// @Router /exports/files [get]
+func (h *ExporterHandler) ListExportArtifacts(c *fiber.Ctx) error {
+	artifacts, err := h.service.ListExportArtifacts(c.Context(), requester(c))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": constant.SUCCESS_EXPORT})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": artifacts})
+}
+
+// Estimate export godoc
+// @Summary Estimate export
+// @Description Count the rows of an export and estimate its files, size and duration from past runs, without writing any file
+// @Tags exporter
+// @Accept  json
+// @Produce  json
+// @Param type query string false "Export type" default(transaksi)
+// @Param request body request.RekapRequest false "..."
+// @Success 200 {object} domain.ExportEstimate
+// @Failure 400 {object} map[string]string
+// @Failure 422 {object} map[string][]string
+// @Router /exports/estimate [post]
+func (h *ExporterHandler) EstimateExport(c *fiber.Ctx) error {
+	request := new(request.RekapRequest)
+
+	if err := c.BodyParser(request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constant.INVALID_REQUEST_BODY,
+		})
+	}
+
+	if err := h.validator.Struct(request); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error_validations": h.validator.ValidationErrors(err),
+		})
+	}
+
+	estimate, err := h.service.EstimateExport(c.Context(), c.Query("type", domain.EXPORT_TYPE_TRANSAKSI), request, requester(c))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": estimate})
+}
+
+// Search export audit godoc
+// @Summary Search export audit
+// @Description Search the audit trail of export runs by user, unit and date, newest first. Requires an audit role
+// @Tags exporter
+// @Produce  json
+// @Param request query request.ExportAuditRequest false "..."
+// @Success 200 {object} []domain.ExportAudit
+// @Failure 403 {object} map[string]string
+// @Failure 422 {object} map[string][]string
+// @Router /exports/audit [get]
+func (h *ExporterHandler) SearchExportAudits(c *fiber.Ctx) error {
+	request := new(request.ExportAuditRequest)
+
+	if err := c.QueryParser(request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constant.INVALID_REQUEST_BODY,
+		})
+	}
+
+	if err := h.validator.Struct(request); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error_validations": h.validator.ValidationErrors(err),
+		})
+	}
+
+	audits, total, err := h.service.SearchExportAudits(c.Context(), request, requesterRoles(c))
+	if err != nil {
+		if errors.Is(err, service.ErrExportAuditForbidden) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": audits, "total": total})
+}
+
+// Archive export job godoc
+// @Summary Archive export job
+// @Description Package every file of a finished export job with a manifest into a zip or tar.gz
+// @Tags exporter
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Job ID"
+// @Param request body request.ArchiveRequest true "..."
+// @Success 201 {object} domain.ExportArtifact
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 422 {object} map[string][]string
+// @Router /exports/{id}/archive [post]
+func (h *ExporterHandler) ArchiveExport(c *fiber.Ctx) error {
+	request := new(request.ArchiveRequest)
+
+	if err := c.BodyParser(request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constant.INVALID_REQUEST_BODY,
+		})
+	}
+
+	if err := h.validator.Struct(request); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error_validations": h.validator.ValidationErrors(err),
+		})
+	}
+
+	artifact, err := h.service.ArchiveExport(c.Context(), c.Params("id"), requester(c), request.Format)
+	if err != nil {
+		if errors.Is(err, domain.ErrExportJobNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if errors.Is(err, service.ErrExportJobNotFinished) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"data": artifact})
+}
+
+// Download export file godoc
+// @Summary Download export file
+// @Description Download a generated export file through a signed URL
+// @Tags exporter
+// @Produce  octet-stream
+// @Param request query request.DownloadRequest true "..."
+// @Success 200 {file} file
+// @Failure 403 {object} map[string]string
+// @Failure 422 {object} map[string][]string
+// @Router /exports/download [get]
+func (h *ExporterHandler) Download(c *fiber.Ctx) error {
+	request := new(request.DownloadRequest)
+
+	if err := c.QueryParser(request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constant.INVALID_REQUEST_BODY,
+		})
+	}
+
+	if err := h.validator.Struct(request); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error_validations": h.validator.ValidationErrors(err),
+		})
+	}
+
+	path, err := h.service.ResolveDownload(request.Path, request.Expires, request.Signature)
+	if err != nil {
+		if errors.Is(err, service.ErrDownloadExpired) || errors.Is(err, service.ErrDownloadInvalidSignature) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": constant.FILE_NOT_FOUND,
+		})
+	}
+
+	return c.Download(path, filepath.Base(path))
+}
+
+// requester returns the ID of the authenticated user, if any.
+func requester(c *fiber.Ctx) string {
+	user, ok := c.Locals("user").(domain.User)
+	if !ok {
+		return ""
+	}
+
+	return user.ID
+}
+
+// requesterRoles returns the roles carried by the requester's token, which
+// pick the masking policy for their exports.
+func requesterRoles(c *fiber.Ctx) []string {
+	user, ok := c.Locals("user").(domain.User)
+	if !ok {
+		return nil
+	}
+
+	return user.Roles
+}
+
+// enqueueError answers a request turned away by admission control with 409
+// when the same export is already queued and 429 otherwise, both telling the
+// requester how long to wait. Other errors are bad requests.
+func enqueueError(c *fiber.Ctx, err error) error {
+	var rejection *service.ExportRejection
+	if !errors.As(err, &rejection) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	status := fiber.StatusTooManyRequests
+	if errors.Is(err, service.ErrExportAlreadyQueued) {
+		status = fiber.StatusConflict
+	}
+
+	retryAfter := int64(math.Ceil(rejection.RetryAfter.Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.FormatInt(retryAfter, 10))
+
+	return c.Status(status).JSON(fiber.Map{
+		"error":       err.Error(),
+		"retry_after": retryAfter,
+	})
 }
 
 func (h *ExporterHandler) HelloWorld(c *fiber.Ctx) error {
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"errors"
+	"event-registration/internal/common/constant"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"event-registration/internal/core/service"
+	validate "event-registration/internal/infrastructure/validator"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type UnitTreeHandler struct {
+	service   *service.UnitTreeService
+	validator *validate.Validator
+	logger    *zap.Logger
+}
+
+func NewUnitTreeHandler(service *service.UnitTreeService, validator *validate.Validator, logger *zap.Logger) *UnitTreeHandler {
+	return &UnitTreeHandler{service: service, validator: validator, logger: logger}
+}
+
+// Find unit tree godoc
+// @Summary Find unit tree
+// @Description Get the Regional, Induk (UPI), Area (AP) and Unit (UP) hierarchy, optionally narrowed to the subtree of a unit code or to the units matching a name
+// @Tags exporter
+// @Produce  json
+// @Param request query request.UnitTreeRequest false "..."
+// @Success 200 {object} []domain.Regional
+// @Failure 404 {object} map[string]string
+// @Failure 422 {object} map[string][]string
+// @Router /units/tree [get]
+func (h *UnitTreeHandler) FindUnitTree(c *fiber.Ctx) error {
+	request := new(request.UnitTreeRequest)
+
+	if err := c.QueryParser(request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constant.INVALID_REQUEST_BODY,
+		})
+	}
+
+	if err := h.validator.Struct(request); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error_validations": h.validator.ValidationErrors(err),
+		})
+	}
+
+	tree, err := h.service.FindUnitTree(c.Context(), request)
+	if err != nil {
+		if errors.Is(err, domain.ErrUnitNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": tree})
+}
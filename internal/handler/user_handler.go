@@ -60,7 +60,7 @@ func (h *UserHandler) Search(c *fiber.Ctx) error {
 // @Success 200 {object} []domain.Role
 // @Router /roles [get]
 func (h *UserHandler) Roles(c *fiber.Ctx) error {
-	roles, err := h.service.Roles()
+	roles, err := h.service.Roles(c.Context())
 	if err != nil {
 		return fiber.NewError(http.StatusBadRequest, err.Error())
 	}
@@ -88,7 +88,7 @@ func (h *UserHandler) GetUnits(c *fiber.Ctx) error {
 		return h.handler.ResponseValidationError(c, constant.VALIDATION_ERROR, h.handler.Validator.ValidationErrors(err))
 	}
 
-	units, err := h.service.GetUnits(request.Level)
+	units, err := h.service.GetUnits(c.Context(), request.Level)
 	if err != nil {
 		return fiber.NewError(http.StatusBadRequest, err.Error())
 	}
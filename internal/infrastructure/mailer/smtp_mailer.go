@@ -0,0 +1,53 @@
+package mailer
+
+import (
+	"event-registration/internal/common"
+	"event-registration/internal/core/domain"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SMTPMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPMailer returns nil when SMTP_HOST is empty, export emails are then
+// not sent.
+func NewSMTPMailer(cfg *common.Config) domain.Mailer {
+	if len(cfg.SmtpHost) == 0 {
+		return nil
+	}
+
+	mailer := &SMTPMailer{
+		addr: net.JoinHostPort(cfg.SmtpHost, strconv.Itoa(cfg.SmtpPort)),
+		from: cfg.SmtpFrom,
+	}
+
+	if len(cfg.SmtpUsername) > 0 {
+		mailer.auth = smtp.PlainAuth("", cfg.SmtpUsername, cfg.SmtpPassword, cfg.SmtpHost)
+	}
+
+	return mailer
+}
+
+// Send delivers a plain text message, upgrading to TLS when the server
+// offers STARTTLS.
+func (m *SMTPMailer) Send(message *domain.MailMessage) error {
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", m.from)
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(message.To, ", "))
+	fmt.Fprintf(&body, "Subject: %s\r\n", message.Subject)
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	body.WriteString("\r\n")
+	body.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+
+	return smtp.SendMail(m.addr, m.auth, m.from, message.To, []byte(body.String()))
+}
@@ -0,0 +1,120 @@
+package mailer_test
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"event-registration/internal/common"
+	"event-registration/internal/core/domain"
+	"event-registration/internal/infrastructure/mailer"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTPServer accepts one session and records its envelope and data.
+type fakeSMTPServer struct {
+	listener net.Listener
+	from     string
+	to       []string
+	data     string
+	done     chan struct{}
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeSMTPServer{listener: listener, done: make(chan struct{})}
+	go server.serve()
+
+	return server
+}
+
+func (s *fakeSMTPServer) serve() {
+	defer close(s.done)
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) {
+		conn.Write([]byte(line + "\r\n"))
+	}
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(line)
+
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			s.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			s.to = append(s.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.data = data.String()
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTPMailer(t *testing.T) {
+	server := newFakeSMTPServer(t)
+
+	host, port, err := net.SplitHostPort(server.listener.Addr().String())
+	require.NoError(t, err)
+	smtpPort, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	m := mailer.NewSMTPMailer(&common.Config{SmtpHost: host, SmtpPort: smtpPort, SmtpFrom: "exporter@pln.co.id"})
+	require.NotNil(t, m)
+
+	require.NoError(t, m.Send(&domain.MailMessage{
+		To:      []string{"budi@pln.co.id"},
+		Subject: "Export transaksi done",
+		Body:    "Files:\n- files/AREA_21.csv\n",
+	}))
+	<-server.done
+
+	require.Equal(t, "exporter@pln.co.id", server.from)
+	require.Equal(t, []string{"budi@pln.co.id"}, server.to)
+	require.Contains(t, server.data, "Subject: Export transaksi done\r\n")
+	require.Contains(t, server.data, "To: budi@pln.co.id\r\n")
+	require.True(t, strings.HasSuffix(server.data, "\r\nFiles:\r\n- files/AREA_21.csv\r\n"))
+}
+
+func TestSMTPMailerDisabled(t *testing.T) {
+	require.Nil(t, mailer.NewSMTPMailer(&common.Config{}))
+}
@@ -0,0 +1,226 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"event-registration/internal/common"
+	"event-registration/internal/core/domain"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+var ErrExportQueueClosed = errors.New("export_queue_closed")
+
+// ExportQueue carries export jobs from the exporter API to the export
+// workers. Messages the workers reject are dead-lettered to <name>.dead.
+type ExportQueue struct {
+	conn     *amqp.Connection
+	name     string
+	prefetch int
+	logger   *zap.Logger
+}
+
+// NewExportQueue returns nil when EXPORT_QUEUE_ENABLED is off, exports then
+// run inside the exporter process.
+func NewExportQueue(cfg *common.Config, logger *zap.Logger) (domain.ExportQueue, error) {
+	if !cfg.ExportQueueEnabled {
+		return nil, nil
+	}
+
+	conn, err := amqp.Dial(cfg.RabbitMQURL)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &ExportQueue{
+		conn:     conn,
+		name:     cfg.ExportQueueName,
+		prefetch: max(cfg.ExportWorkerPrefetch, 1),
+		logger:   logger,
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	defer ch.Close()
+
+	if err := q.declare(ch); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return q, nil
+}
+
+func (q *ExportQueue) deadLetterName() string {
+	return q.name + ".dead"
+}
+
+func (q *ExportQueue) declare(ch *amqp.Channel) error {
+	if _, err := ch.QueueDeclare(
+		q.deadLetterName(), // name
+		true,               // durable
+		false,              // auto-delete
+		false,              // exclusive
+		false,              // no-wait
+		nil,                // args
+	); err != nil {
+		return err
+	}
+
+	_, err := ch.QueueDeclare(
+		q.name, // name
+		true,   // durable
+		false,  // auto-delete
+		false,  // exclusive
+		false,  // no-wait
+		amqp.Table{
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": q.deadLetterName(),
+		},
+	)
+	return err
+}
+
+func (q *ExportQueue) Publish(message *domain.ExportMessage) error {
+	ch, err := q.conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	return ch.Publish(
+		"",     // exchange
+		q.name, // routing key
+		false,  // mandatory
+		false,  // immediate
+		amqp.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			Body:         body,
+		},
+	)
+}
+
+// Consume handles up to EXPORT_WORKER_PREFETCH messages at once. Messages
+// prefetched but not started when ctx is done go back to the queue.
+func (q *ExportQueue) Consume(ctx context.Context, handle func(message *domain.ExportMessage) error) error {
+	ch, err := q.conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	if err := ch.Qos(q.prefetch, 0, false); err != nil {
+		return err
+	}
+
+	deliveries, err := ch.Consume(
+		q.name, // queue
+		"",     // consumer
+		false,  // auto-ack
+		false,  // exclusive
+		false,  // no-local
+		false,  // no-wait
+		nil,    // args
+	)
+	if err != nil {
+		return err
+	}
+
+	var closed bool
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for i := 0; i < q.prefetch; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case delivery, ok := <-deliveries:
+					if !ok {
+						mu.Lock()
+						closed = true
+						mu.Unlock()
+						return
+					}
+
+					if ctx.Err() != nil {
+						q.settle(delivery, delivery.Nack(false, true))
+						return
+					}
+
+					q.deliver(delivery, handle)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if closed && ctx.Err() == nil {
+		return ErrExportQueueClosed
+	}
+
+	return nil
+}
+
+func (q *ExportQueue) deliver(delivery amqp.Delivery, handle func(message *domain.ExportMessage) error) {
+	var message domain.ExportMessage
+	if err := json.Unmarshal(delivery.Body, &message); err != nil || len(message.JobID) == 0 {
+		q.logger.Error(
+			"error_decode_export_message",
+			zap.ByteString("body", delivery.Body),
+			zap.Error(err),
+		)
+		q.settle(delivery, delivery.Nack(false, false))
+		return
+	}
+
+	err := handle(&message)
+	switch {
+	case err == nil:
+		q.settle(delivery, delivery.Ack(false))
+	case errors.Is(err, domain.ErrExportInterrupted), errors.Is(err, domain.ErrExportJobClaimed):
+		q.logger.Info(
+			"export_message_requeued",
+			zap.String("job_id", message.JobID),
+		)
+		q.settle(delivery, delivery.Nack(false, true))
+	default:
+		q.logger.Error(
+			"export_message_dead_lettered",
+			zap.String("job_id", message.JobID),
+			zap.String("queue", q.deadLetterName()),
+			zap.Error(err),
+		)
+		q.settle(delivery, delivery.Nack(false, false))
+	}
+}
+
+func (q *ExportQueue) settle(delivery amqp.Delivery, err error) {
+	if err != nil {
+		q.logger.Error(
+			"error_settle_export_message",
+			zap.Uint64("delivery_tag", delivery.DeliveryTag),
+			zap.Error(err),
+		)
+	}
+}
+
+func (q *ExportQueue) Close() error {
+	return q.conn.Close()
+}
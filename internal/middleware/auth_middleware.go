@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"event-registration/internal/common/constant"
 	"event-registration/internal/core/domain"
 	"net/http"
@@ -16,21 +17,9 @@ func (m *Middleware) AuthMiddleware() fiber.Handler {
 			return m.handler.ResponseWithStatus(c, fiber.StatusUnauthorized, "access_token_is_required", nil)
 		}
 
-		token, err := jwt.ParseWithClaims(accessToken, &jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
-			return []byte(m.cfg.JwtSecret), nil
-		})
-		if err != nil || !token.Valid {
-			return m.handler.ResponseWithStatus(c, fiber.StatusUnauthorized, "invalid_access_token", nil)
-		}
-
-		claims, ok := token.Claims.(*jwt.MapClaims)
-		if !ok {
-			return m.handler.ResponseWithStatus(c, fiber.StatusUnauthorized, "invalid_token_claims", nil)
-		}
-
-		tokenType, ok := (*claims)["type"].(string)
-		if !ok || tokenType != constant.ACCESS_TOKEN {
-			return m.handler.ResponseWithStatus(c, fiber.StatusUnauthorized, "invalid_token_type", nil)
+		user, err := AccessTokenUser(m.cfg.JwtSecret, accessToken)
+		if err != nil {
+			return m.handler.ResponseWithStatus(c, fiber.StatusUnauthorized, err.Error(), nil)
 		}
 
 		isBlacklisted, err := m.sessionService.IsAccessTokenBlacklisted(c.Context(), accessToken)
@@ -42,17 +31,42 @@ func (m *Middleware) AuthMiddleware() fiber.Handler {
 			return m.handler.ResponseWithStatus(c, fiber.StatusUnauthorized, "access_token_blacklisted", nil)
 		}
 
-		user := domain.User{
-			Email: (*claims)["email"].(string),
-			ID:    (*claims)["sub"].(string),
-		}
-
 		c.Locals("user", user)
 
 		return c.Next()
 	}
 }
 
+// AccessTokenUser verifies an access token signed with secret and returns the
+// user it was issued to, the error text is the response message.
+func AccessTokenUser(secret, accessToken string) (domain.User, error) {
+	token, err := jwt.ParseWithClaims(accessToken, &jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	})
+	if err != nil || !token.Valid {
+		return domain.User{}, errors.New("invalid_access_token")
+	}
+
+	claims, ok := token.Claims.(*jwt.MapClaims)
+	if !ok {
+		return domain.User{}, errors.New("invalid_token_claims")
+	}
+
+	tokenType, ok := (*claims)["type"].(string)
+	if !ok || tokenType != constant.ACCESS_TOKEN {
+		return domain.User{}, errors.New("invalid_token_type")
+	}
+
+	email, _ := (*claims)["email"].(string)
+	sub, _ := (*claims)["sub"].(string)
+
+	return domain.User{
+		Email: email,
+		ID:    sub,
+		Roles: claimRoles(*claims),
+	}, nil
+}
+
 func (m *Middleware) VerifyRefreshToken() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		refreshToken := c.Cookies("refresh_token")
@@ -90,3 +104,20 @@ func (m *Middleware) VerifyRefreshToken() fiber.Handler {
 		return c.Next()
 	}
 }
+
+// claimRoles reads the optional roles claim, a list of role names.
+func claimRoles(claims jwt.MapClaims) []string {
+	values, ok := claims["roles"].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	roles := make([]string, 0, len(values))
+	for _, value := range values {
+		if role, ok := value.(string); ok {
+			roles = append(roles, role)
+		}
+	}
+
+	return roles
+}
@@ -0,0 +1,66 @@
+package middleware_test
+
+import (
+	"context"
+	"testing"
+
+	"event-registration/internal/common"
+	"event-registration/internal/common/request"
+	"event-registration/internal/core/domain"
+	"event-registration/internal/core/service"
+	"event-registration/internal/middleware"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type roleUserRepo struct {
+	domain.UserRepository
+	roles map[string][]string
+}
+
+func (r *roleUserRepo) RoleNames(ctx context.Context, email string) ([]string, error) {
+	return r.roles[email], nil
+}
+
+func TestAccessTokenRoles(t *testing.T) {
+	config := &common.Config{JwtSecret: "secret", AccessJwtExpiration: 10, ExportAuditRoles: []string{"admin"}}
+	users := &roleUserRepo{roles: map[string][]string{
+		"admin@example.com":  {"admin"},
+		"viewer@example.com": {"viewer"},
+	}}
+	auth := service.NewAuthService(nil, users, zap.NewNop(), nil, config, nil)
+	exporter := service.NewExporterService(service.ExporterParams{Config: config, Logger: zap.NewNop()})
+
+	for email, policy := range map[string]string{
+		"admin@example.com":  "none",
+		"viewer@example.com": "strict",
+		"other@example.com":  "standard",
+	} {
+		token, err := auth.GenerateAccessTokenJWT(context.Background(), &domain.User{ID: email, Email: email})
+		require.NoError(t, err)
+
+		user, err := middleware.AccessTokenUser(config.JwtSecret, token)
+		require.NoError(t, err)
+		require.Equal(t, email, user.Email)
+		require.Equal(t, policy, exporter.MaskingPolicyFor(user.Roles), email)
+
+		_, _, err = exporter.SearchExportAudits(context.Background(), &request.ExportAuditRequest{}, user.Roles)
+		if policy == "none" {
+			require.NoError(t, err, email)
+		} else {
+			require.ErrorIs(t, err, service.ErrExportAuditForbidden, email)
+		}
+	}
+}
+
+func TestAccessTokenUserRejectsRefreshToken(t *testing.T) {
+	config := &common.Config{JwtSecret: "secret", RefreshTokenExpiration: 1}
+	auth := service.NewAuthService(nil, &roleUserRepo{}, zap.NewNop(), nil, config, nil)
+
+	token, err := auth.GenerateRefreshTokenJWT(&domain.User{ID: "1", Email: "a@example.com"})
+	require.NoError(t, err)
+
+	_, err = middleware.AccessTokenUser(config.JwtSecret, token)
+	require.EqualError(t, err, "invalid_token_type")
+}
@@ -1,28 +1,42 @@
 package gorm
 
 import (
+	"context"
+	"event-registration/internal/common"
 	"event-registration/internal/common/constant"
 	"event-registration/internal/core/domain"
+	"time"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
 type AuthRepo struct {
-	db     *gorm.DB
-	logger *zap.Logger
+	db           *gorm.DB
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	logger       *zap.Logger
 }
 
 func NewAuthRepo(
 	db *gorm.DB, // `name:"authDB"`
 	logger *zap.Logger,
+	cfg *common.Config,
 ) domain.AuthRepository {
-	return &AuthRepo{db: db, logger: logger}
+	return &AuthRepo{
+		db:           db,
+		readTimeout:  cfg.DBReadTimeout,
+		writeTimeout: cfg.DBWriteTimeout,
+		logger:       logger,
+	}
 }
 
-func (r *AuthRepo) IsRegistered(email string) (isRegistered bool, err error) {
+func (r *AuthRepo) IsRegistered(ctx context.Context, email string) (isRegistered bool, err error) {
+	ctx, cancel := withTimeout(ctx, r.readTimeout)
+	defer cancel()
+
 	var count int64
-	err = r.db.Table("users").
+	err = r.db.WithContext(ctx).Table("users").
 		Where("email = ?", email).
 		Count(&count).Error
 	if err != nil {
@@ -33,8 +47,11 @@ func (r *AuthRepo) IsRegistered(email string) (isRegistered bool, err error) {
 	return count > 0, nil
 }
 
-func (r *AuthRepo) Register(user domain.User) (err error) {
-	err = r.db.
+func (r *AuthRepo) Register(ctx context.Context, user domain.User) (err error) {
+	ctx, cancel := withTimeout(ctx, r.writeTimeout)
+	defer cancel()
+
+	err = r.db.WithContext(ctx).
 		Model(&domain.User{}).
 		Create(&user).
 		Error
@@ -47,8 +64,11 @@ func (r *AuthRepo) Register(user domain.User) (err error) {
 	return nil
 }
 
-func (r *AuthRepo) FindByEmail(email string) (user *domain.User, err error) {
-	err = r.db.Table("users").
+func (r *AuthRepo) FindByEmail(ctx context.Context, email string) (user *domain.User, err error) {
+	ctx, cancel := withTimeout(ctx, r.readTimeout)
+	defer cancel()
+
+	err = r.db.WithContext(ctx).Table("users").
 		Where("email = ?", email).
 		First(&user).Error
 	if err != nil {
@@ -1,9 +1,11 @@
 package gorm_test
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	"event-registration/internal/common"
 	"event-registration/internal/core/domain"
 
 	repo "event-registration/internal/repository/gorm"
@@ -43,7 +45,7 @@ func (s *AuthRepoTestSuite) SetupTest() {
 	s.db = db
 	s.mock = mock
 	logger := zap.NewNop()
-	s.repo = repo.NewAuthRepo(db, logger).(*repo.AuthRepo)
+	s.repo = repo.NewAuthRepo(db, logger, &common.Config{}).(*repo.AuthRepo)
 	s.cleanup = cleanup
 }
 
@@ -66,7 +68,7 @@ func (s *AuthRepoTestSuite) TestIsRegistered() {
 		s.Run(tc.name, func() {
 			rows := sqlmock.NewRows([]string{"count"}).AddRow(tc.mockRows)
 			s.mock.ExpectQuery("SELECT").WithArgs(tc.email).WillReturnRows(rows)
-			reg, err := s.repo.IsRegistered(tc.email)
+			reg, err := s.repo.IsRegistered(context.Background(), tc.email)
 			require.NoError(s.T(), err)
 			require.Equal(s.T(), tc.expectReg, reg)
 			require.NoError(s.T(), s.mock.ExpectationsWereMet())
@@ -81,7 +83,7 @@ func (s *AuthRepoTestSuite) TestRegister() {
 		s.mock.ExpectExec("INSERT").WillReturnResult(sqlmock.NewResult(1, 1))
 		s.mock.ExpectCommit()
 
-		err := s.repo.Register(user)
+		err := s.repo.Register(context.Background(), user)
 		require.NoError(s.T(), err)
 		require.NoError(s.T(), s.mock.ExpectationsWereMet())
 	})
@@ -95,7 +97,7 @@ func (s *AuthRepoTestSuite) TestFindByEmail() {
 			AddRow(user.ID, user.Email, user.Password, user.Name, user.Picture, nil, now, now)
 		s.mock.ExpectQuery("SELECT").WithArgs(user.Email, 1).WillReturnRows(rows)
 
-		result, err := s.repo.FindByEmail(user.Email)
+		result, err := s.repo.FindByEmail(context.Background(), user.Email)
 		require.NoError(s.T(), err)
 		require.NotNil(s.T(), result)
 		require.Equal(s.T(), user.Email, result.Email)
@@ -105,7 +107,7 @@ func (s *AuthRepoTestSuite) TestFindByEmail() {
 	s.Run("user not found", func() {
 		s.mock.ExpectQuery("SELECT").WithArgs("notfound@example.com", 1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password", "name", "picture", "email_verified_at", "created_at", "updated_at"}))
-		result, err := s.repo.FindByEmail("notfound@example.com")
+		result, err := s.repo.FindByEmail(context.Background(), "notfound@example.com")
 		require.Error(s.T(), err)
 		if result != nil {
 			require.Empty(s.T(), result.Email)
@@ -1,29 +1,47 @@
 package gorm
 
 import (
+	"context"
+	"event-registration/internal/common"
 	"event-registration/internal/core/domain"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type EventRepo struct {
-	db *gorm.DB
+	db           *gorm.DB
+	readTimeout  time.Duration
+	writeTimeout time.Duration
 }
 
-func NewEventRepo(db *gorm.DB) domain.EventRepository {
-	return &EventRepo{db: db}
+func NewEventRepo(db *gorm.DB, cfg *common.Config) domain.EventRepository {
+	return &EventRepo{
+		db:           db,
+		readTimeout:  cfg.DBReadTimeout,
+		writeTimeout: cfg.DBWriteTimeout,
+	}
 }
 
-func (r *EventRepo) FindByID(id string) (*domain.Event, error) {
+func (r *EventRepo) FindByID(ctx context.Context, id string) (*domain.Event, error) {
+	ctx, cancel := withTimeout(ctx, r.readTimeout)
+	defer cancel()
+
 	var event domain.Event
-	err := r.db.First(&event, "id = ?", id).Error
+	err := r.db.WithContext(ctx).First(&event, "id = ?", id).Error
 	return &event, err
 }
 
-func (r *EventRepo) Save(event *domain.Event) error {
-	return r.db.Create(event).Error
+func (r *EventRepo) Save(ctx context.Context, event *domain.Event) error {
+	ctx, cancel := withTimeout(ctx, r.writeTimeout)
+	defer cancel()
+
+	return r.db.WithContext(ctx).Create(event).Error
 }
 
-func (r *EventRepo) Update(event *domain.Event) error {
-	return r.db.Save(event).Error
+func (r *EventRepo) Update(ctx context.Context, event *domain.Event) error {
+	ctx, cancel := withTimeout(ctx, r.writeTimeout)
+	defer cancel()
+
+	return r.db.WithContext(ctx).Save(event).Error
 }
@@ -0,0 +1,85 @@
+package gorm
+
+import (
+	"context"
+	"event-registration/internal/common"
+	"event-registration/internal/core/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ExportAuditRepo struct {
+	db           *gorm.DB
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+}
+
+func NewExportAuditRepo(
+	db *gorm.DB, // `name:"DwhDB"`
+	cfg *common.Config,
+) (domain.ExportAuditRepository, error) {
+	if err := db.AutoMigrate(&domain.ExportAudit{}); err != nil {
+		return nil, err
+	}
+
+	return &ExportAuditRepo{
+		db:           db,
+		readTimeout:  cfg.DBReadTimeout,
+		writeTimeout: cfg.DBWriteTimeout,
+	}, nil
+}
+
+func (r *ExportAuditRepo) Create(ctx context.Context, audit *domain.ExportAudit) error {
+	ctx, cancel := withTimeout(ctx, r.writeTimeout)
+	defer cancel()
+
+	return r.db.WithContext(ctx).Create(audit).Error
+}
+
+func (r *ExportAuditRepo) Search(ctx context.Context, filter domain.ExportAuditFilter) (result []*domain.ExportAudit, total int64, err error) {
+	ctx, cancel := withTimeout(ctx, r.readTimeout)
+	defer cancel()
+
+	query := r.db.WithContext(ctx).Model(&domain.ExportAudit{})
+
+	if len(filter.RequestedBy) > 0 {
+		query = query.Where("requested_by = ?", filter.RequestedBy)
+	}
+
+	if len(filter.Unit) > 0 {
+		query = query.Where("? IN (id_pusat, id_induk, id_area, unit_code)", filter.Unit)
+	}
+
+	if filter.From != nil {
+		query = query.Where("started_at >= ?", *filter.From)
+	}
+
+	if filter.To != nil {
+		query = query.Where("started_at <= ?", *filter.To)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err = query.Order("started_at DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&result).Error
+
+	return result, total, err
+}
+
+func (r *ExportAuditRepo) FindRecentDone(ctx context.Context, exportType string, limit int) (result []*domain.ExportAudit, err error) {
+	ctx, cancel := withTimeout(ctx, r.readTimeout)
+	defer cancel()
+
+	err = r.db.WithContext(ctx).
+		Where("action = ? AND export_type = ? AND status = ?", domain.EXPORT_AUDIT_EXPORT, exportType, domain.EXPORT_JOB_DONE).
+		Order("started_at DESC").
+		Limit(limit).
+		Find(&result).Error
+
+	return result, err
+}
@@ -0,0 +1,52 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"event-registration/internal/common"
+	"event-registration/internal/core/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ExportNotificationRepo struct {
+	db           *gorm.DB
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+}
+
+func NewExportNotificationRepo(
+	db *gorm.DB, // `name:"DwhDB"`
+	cfg *common.Config,
+) (domain.ExportNotificationRepository, error) {
+	if err := db.AutoMigrate(&domain.ExportNotificationSetting{}); err != nil {
+		return nil, err
+	}
+
+	return &ExportNotificationRepo{
+		db:           db,
+		readTimeout:  cfg.DBReadTimeout,
+		writeTimeout: cfg.DBWriteTimeout,
+	}, nil
+}
+
+func (r *ExportNotificationRepo) Find(ctx context.Context, userID string) (*domain.ExportNotificationSetting, error) {
+	ctx, cancel := withTimeout(ctx, r.readTimeout)
+	defer cancel()
+
+	var setting domain.ExportNotificationSetting
+	err := r.db.WithContext(ctx).First(&setting, "user_id = ?", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrExportNotificationSettingNotFound
+	}
+
+	return &setting, err
+}
+
+func (r *ExportNotificationRepo) Save(ctx context.Context, setting *domain.ExportNotificationSetting) error {
+	ctx, cancel := withTimeout(ctx, r.writeTimeout)
+	defer cancel()
+
+	return r.db.WithContext(ctx).Save(setting).Error
+}
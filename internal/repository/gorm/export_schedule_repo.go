@@ -0,0 +1,113 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"event-registration/internal/common"
+	"event-registration/internal/core/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ExportScheduleRepo struct {
+	db           *gorm.DB
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+}
+
+func NewExportScheduleRepo(
+	db *gorm.DB, // `name:"DwhDB"`
+	cfg *common.Config,
+) (domain.ExportScheduleRepository, error) {
+	if err := db.AutoMigrate(&domain.ExportSchedule{}, &domain.ExportScheduleRun{}); err != nil {
+		return nil, err
+	}
+
+	return &ExportScheduleRepo{
+		db:           db,
+		readTimeout:  cfg.DBReadTimeout,
+		writeTimeout: cfg.DBWriteTimeout,
+	}, nil
+}
+
+func (r *ExportScheduleRepo) Create(ctx context.Context, schedule *domain.ExportSchedule) error {
+	ctx, cancel := withTimeout(ctx, r.writeTimeout)
+	defer cancel()
+
+	return r.db.WithContext(ctx).Create(schedule).Error
+}
+
+func (r *ExportScheduleRepo) FindAll(ctx context.Context) (result []*domain.ExportSchedule, err error) {
+	ctx, cancel := withTimeout(ctx, r.readTimeout)
+	defer cancel()
+
+	err = r.db.WithContext(ctx).Order("created_at").Find(&result).Error
+	return result, err
+}
+
+func (r *ExportScheduleRepo) FindEnabled(ctx context.Context) (result []*domain.ExportSchedule, err error) {
+	ctx, cancel := withTimeout(ctx, r.readTimeout)
+	defer cancel()
+
+	err = r.db.WithContext(ctx).Where("enabled = ?", true).Order("created_at").Find(&result).Error
+	return result, err
+}
+
+func (r *ExportScheduleRepo) FindByID(ctx context.Context, id string) (*domain.ExportSchedule, error) {
+	ctx, cancel := withTimeout(ctx, r.readTimeout)
+	defer cancel()
+
+	var schedule domain.ExportSchedule
+	err := r.db.WithContext(ctx).First(&schedule, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrExportScheduleNotFound
+	}
+
+	return &schedule, err
+}
+
+func (r *ExportScheduleRepo) ClaimRun(ctx context.Context, id string, previous *time.Time, at time.Time) (bool, error) {
+	ctx, cancel := withTimeout(ctx, r.writeTimeout)
+	defer cancel()
+
+	result := r.db.WithContext(ctx).Model(&domain.ExportSchedule{}).
+		Where("id = ? AND last_run_at IS NOT DISTINCT FROM ?", id, previous).
+		Update("last_run_at", at)
+
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *ExportScheduleRepo) SaveRun(ctx context.Context, run *domain.ExportScheduleRun) error {
+	ctx, cancel := withTimeout(ctx, r.writeTimeout)
+	defer cancel()
+
+	return r.db.WithContext(ctx).Save(run).Error
+}
+
+func (r *ExportScheduleRepo) FinishJobRun(ctx context.Context, job *domain.ExportJob) error {
+	ctx, cancel := withTimeout(ctx, r.writeTimeout)
+	defer cancel()
+
+	return r.db.WithContext(ctx).Model(&domain.ExportScheduleRun{}).
+		Where("job_id = ?", job.ID).
+		Updates(map[string]any{
+			"status":      job.Status,
+			"rows":        job.RowsWritten,
+			"error":       job.Error,
+			"finished_at": job.FinishedAt,
+		}).Error
+}
+
+func (r *ExportScheduleRepo) FindRuns(ctx context.Context, scheduleID string, limit int) (result []*domain.ExportScheduleRun, err error) {
+	ctx, cancel := withTimeout(ctx, r.readTimeout)
+	defer cancel()
+
+	err = r.db.WithContext(ctx).
+		Where("schedule_id = ?", scheduleID).
+		Order("started_at DESC").
+		Limit(limit).
+		Find(&result).Error
+
+	return result, err
+}
@@ -1,9 +1,13 @@
 package gorm
 
 import (
+	"context"
+	"event-registration/internal/common"
 	"event-registration/internal/common/helper"
 	"event-registration/internal/common/request"
 	"event-registration/internal/core/domain"
+	"fmt"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -11,49 +15,162 @@ import (
 type ExporterRepo struct {
 	db          *gorm.DB
 	dbPlnMobile *gorm.DB
+	// queryTimeout bounds every query, statementTimeout is enforced by
+	// Postgres as well
+	queryTimeout     time.Duration
+	statementTimeout time.Duration
 }
 
 func NewExporterRepo(
 	db *gorm.DB, // `name:"DwhDB"`
 	dbPlnMobile *gorm.DB, // `name:"PLNMobileDB"`
+	cfg *common.Config,
 ) domain.ExporterRepository {
 	return &ExporterRepo{
-		db:          db,
-		dbPlnMobile: dbPlnMobile,
+		db:               db,
+		dbPlnMobile:      dbPlnMobile,
+		queryTimeout:     cfg.ExportQueryTimeout,
+		statementTimeout: cfg.ExportStatementTimeout,
 	}
 }
 
-func (r *ExporterRepo) GetAllUnit() (result []*domain.Regional, err error) {
-	err = r.db.Model(&domain.Regional{}).
-		// Preload("Induk").
-		Preload("Induk.Area").
-		// Preload("Induk.Area.Unit").
-		Find(&result).Error
+// exportQuery runs fn against db within EXPORT_QUERY_TIMEOUT. With an
+// EXPORT_STATEMENT_TIMEOUT, fn runs in a read transaction whose statements
+// Postgres cancels after it, even when the cancel request of the client
+// never arrives.
+func (r *ExporterRepo) exportQuery(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	ctx, cancel := withTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	if r.statementTimeout <= 0 {
+		return fn(db.WithContext(ctx))
+	}
+
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// SET does not take bind parameters
+		if err := tx.Exec(fmt.Sprintf("SET LOCAL statement_timeout = %d", r.statementTimeout.Milliseconds())).Error; err != nil {
+			return err
+		}
+
+		return fn(tx)
+	})
+}
+
+func (r *ExporterRepo) GetAllUnit(ctx context.Context) (result []*domain.Regional, err error) {
+	err = r.exportQuery(ctx, r.db, func(tx *gorm.DB) error {
+		return tx.Model(&domain.Regional{}).
+			Preload("Induk.Area.Unit").
+			Find(&result).Error
+	})
 
 	return result, err
 }
 
-func (r *ExporterRepo) FindTransaksi(req *request.RekapRequest) (result []*domain.Transaksi, err error) {
-	var query *gorm.DB
+func (r *ExporterRepo) UnitsVersion(ctx context.Context) (version string, err error) {
+	err = r.exportQuery(ctx, r.db, func(tx *gorm.DB) error {
+		return tx.Raw(`SELECT md5(string_agg(row, '|' ORDER BY row)) FROM (` +
+			`SELECT 'regional' || t :: text AS row FROM public.pln_unit_regional t ` +
+			`UNION ALL SELECT 'upi' || t :: text FROM public.pln_unit_upi t ` +
+			`UNION ALL SELECT 'ap' || t :: text FROM public.pln_unit_ap t ` +
+			`UNION ALL SELECT 'up' || t :: text FROM public.pln_unit_up t` +
+			`) units`).
+			Scan(&version).Error
+	})
+
+	return version, err
+}
+
+// transaksiTable returns the source table of the transactions, either the
+// DWH view or PLN Mobile's own table.
+func transaksiTable(req *request.RekapRequest) string {
+	if req.IsDBPlnMobile {
+		return "public.transaksi"
+	}
+
+	return "plnmobile.vw_transaksi"
+}
 
+// transaksiDB returns the database the transactions of req are read from.
+func (r *ExporterRepo) transaksiDB(req *request.RekapRequest) *gorm.DB {
 	if req.IsDBPlnMobile {
-		query = r.dbPlnMobile.Table("public.transaksi").Select("name, consumer_name, type, amount, status_code, meter_id as meter_number, title, payment_gateway, created_at, token, up.id_unit_up AS unit_up,	up.nama_unit_up, ap.nama_unit_ap, upi.nama_unit_upi, upi.id_unit_upi as unit_upi, ap.id_unit_ap as unit_ap").
+		return r.dbPlnMobile
+	}
+
+	return r.db
+}
+
+// transaksiQuery builds the joined and filtered transaksi query shared by
+// the find and count queries on tx, see transaksiDB.
+func transaksiQuery(tx *gorm.DB, req *request.RekapRequest) (query *gorm.DB, err error) {
+	if req.IsDBPlnMobile {
+		query = tx.Table("public.transaksi").
 			Joins("JOIN public.pln_unit_upi upi ON public.transaksi.unit_upi = upi.id_unit_upi :: text").
 			Joins("JOIN public.pln_unit_ap ap ON public.transaksi.unit_ap = ap.id_unit_ap").
 			Joins("JOIN public.pln_unit_up up ON public.transaksi.unit_up = up.id_unit_up")
 	} else {
-		query = r.db.Select("name, consumer_name, type, amount, status_code, meter_number, title, payment_gateway, created_at, token, up.id_unit_up AS unit_up,	up.nama_unit_up, ap.nama_unit_ap, upi.nama_unit_upi, upi.id_unit_upi as unit_upi, ap.id_unit_ap as unit_ap").
+		query = tx.Model(&domain.Transaksi{}).
 			Joins("JOIN public.pln_unit_upi upi ON plnmobile.vw_transaksi.unit_upi = upi.id_unit_upi :: text").
 			Joins("JOIN public.pln_unit_ap ap ON plnmobile.vw_transaksi.unit_ap = ap.id_unit_ap").
 			Joins("JOIN public.pln_unit_up up ON plnmobile.vw_transaksi.unit_up = up.id_unit_up")
 	}
 
+	query, err = applyUnitAndDateFilter(query, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return applyTransaksiFilter(query, req), nil
+}
+
+// applyTransaksiFilter adds the optional status, gateway, type, amount and
+// meter filters of req.
+func applyTransaksiFilter(query *gorm.DB, req *request.RekapRequest) *gorm.DB {
+	table := transaksiTable(req)
+
+	if len(req.StatusCode) > 0 {
+		query = query.Where(table+".status_code IN ?", req.StatusCode)
+	}
+
+	if len(req.PaymentGateway) > 0 {
+		query = query.Where(table+".payment_gateway = ?", req.PaymentGateway)
+	}
+
+	if len(req.Type) > 0 {
+		query = query.Where(table+".type = ?", req.Type)
+	}
+
+	// amount is stored as text, empty for transactions without one
+	amount := "CAST(NULLIF(" + table + ".amount, '') AS numeric)"
+	if req.AmountMin > 0 {
+		query = query.Where(amount+" >= ?", req.AmountMin)
+	}
+
+	if req.AmountMax > 0 {
+		query = query.Where(amount+" <= ?", req.AmountMax)
+	}
+
+	if len(req.MeterNumber) > 0 {
+		meterNumber := table + ".meter_number"
+		if req.IsDBPlnMobile {
+			meterNumber = table + ".meter_id"
+		}
+
+		query = query.Where(meterNumber+" = ?", req.MeterNumber)
+	}
+
+	return query
+}
+
+func applyUnitAndDateFilter(query *gorm.DB, req *request.RekapRequest) (*gorm.DB, error) {
 	if len(req.Induk) > 0 {
-		query.Where("unit_upi = ?", req.Induk)
+		query = query.Where("unit_upi = ?", req.Induk)
 	} else if len(req.Area) > 0 {
-		query.Where("unit_ap = ?", req.Area)
+		query = query.Where("unit_ap = ?", req.Area)
 	} else if len(req.UnitCode) > 0 {
-		query.Where("unit_up = ?", req.UnitCode)
+		query = query.Where("unit_up = ?", req.UnitCode)
+	} else if len(req.Pusat) > 0 {
+		// a regional covers every UPI it lists
+		query = query.Where("unit_upi IN (SELECT id_unit_upi :: text FROM public.pln_unit_upi WHERE id_reg_apkt = ?)", req.Pusat)
 	}
 
 	if len(req.DateStart) > 0 && len(req.DateEnd) > 0 {
@@ -67,135 +184,230 @@ func (r *ExporterRepo) FindTransaksi(req *request.RekapRequest) (result []*domai
 			return nil, err
 		}
 
-		query.Where("created_at BETWEEN ? AND ?", startDate, endDate)
-	}
-
-	if req.Limit > 0 {
-		query = query.Limit(req.Limit)
-	}
-	if req.Offset > 0 {
-		query = query.Offset(req.Offset)
+		query = query.Where("created_at BETWEEN ? AND ?", startDate, endDate)
 	}
 
-	err = query.Find(&result).Error
-
-	return result, err
+	return query, nil
 }
 
-func (r *ExporterRepo) CountTransaksi(req *request.RekapRequest) (result int64, err error) {
-	var query *gorm.DB
+func (r *ExporterRepo) transaksiSelect(req *request.RekapRequest) string {
+	table := transaksiTable(req)
 
+	meterNumber := "meter_number"
 	if req.IsDBPlnMobile {
-		query = r.dbPlnMobile.Table("transaksi").
-			Joins("JOIN public.pln_unit_upi upi ON public.transaksi.unit_upi = upi.id_unit_upi :: text").
-			Joins("JOIN public.pln_unit_ap ap ON public.transaksi.unit_ap = ap.id_unit_ap").
-			Joins("JOIN public.pln_unit_up up ON public.transaksi.unit_up = up.id_unit_up")
-	} else {
-		query = r.db.Model(&domain.Transaksi{}).
-			Joins("JOIN public.pln_unit_upi upi ON plnmobile.vw_transaksi.unit_upi = upi.id_unit_upi :: text").
-			Joins("JOIN public.pln_unit_ap ap ON plnmobile.vw_transaksi.unit_ap = ap.id_unit_ap").
-			Joins("JOIN public.pln_unit_up up ON plnmobile.vw_transaksi.unit_up = up.id_unit_up")
+		meterNumber = "meter_id as meter_number"
 	}
 
-	if len(req.Induk) > 0 {
-		query.Where("unit_upi = ?", req.Induk)
-	} else if len(req.Area) > 0 {
-		query.Where("unit_ap = ?", req.Area)
-	} else if len(req.UnitCode) > 0 {
-		query.Where("unit_up = ?", req.UnitCode)
-	}
+	return table + ".id, name, consumer_name, type, amount, status_code, " + meterNumber + ", title, payment_gateway, " + table + ".created_at, token, up.id_unit_up AS unit_up, up.nama_unit_up, ap.nama_unit_ap, upi.nama_unit_upi, upi.id_unit_upi as unit_upi, ap.id_unit_ap as unit_ap"
+}
 
-	if len(req.DateStart) > 0 && len(req.DateEnd) > 0 {
-		startDate, err := helper.StartDateParser(req.DateStart)
+// StreamTransaksi reads the next page ordered by (created_at, id), starting
+// right after the cursor, and hands every row to fn as it is scanned. Unlike
+// OFFSET, later pages do not re-scan earlier rows, and rows inserted meanwhile
+// cannot shift the page boundaries.
+func (r *ExporterRepo) StreamTransaksi(ctx context.Context, req *request.RekapRequest, after *domain.ExportCursor, limit int, fn func(*domain.Transaksi) error) error {
+	return r.exportQuery(ctx, r.transaksiDB(req), func(tx *gorm.DB) error {
+		query, err := transaksiQuery(tx, req)
 		if err != nil {
-			return result, err
+			return err
 		}
 
-		endDate, err := helper.EndDateParser(req.DateEnd)
+		table := transaksiTable(req)
+
+		query = query.Select(r.transaksiSelect(req))
+
+		if after != nil {
+			query = query.Where("("+table+".created_at, "+table+".id) > (?, ?)", after.CreatedAt, after.ID)
+		}
+
+		rows, err := query.
+			Order(table + ".created_at ASC").
+			Order(table + ".id ASC").
+			Limit(limit).
+			Rows()
 		if err != nil {
-			return result, err
+			return err
 		}
+		defer rows.Close()
 
-		query.Where("created_at BETWEEN ? AND ?", startDate, endDate)
-	}
+		for rows.Next() {
+			var row domain.Transaksi
+			if err := query.ScanRows(rows, &row); err != nil {
+				return err
+			}
 
-	err = query.Count(&result).Error
-	if err != nil {
-		return result, err
-	}
+			if err := fn(&row); err != nil {
+				return err
+			}
+		}
 
-	return result, err
+		return rows.Err()
+	})
 }
 
-func (r *ExporterRepo) FindPelanggan(req *request.RekapRequest) (result []*domain.Pelanggan, err error) {
+func (r *ExporterRepo) CountTransaksi(ctx context.Context, req *request.RekapRequest) (result int64, err error) {
+	err = r.exportQuery(ctx, r.transaksiDB(req), func(tx *gorm.DB) error {
+		query, err := transaksiQuery(tx, req)
+		if err != nil {
+			return err
+		}
 
-	query := r.dbPlnMobile.Select("id, idpel, name, consumer_name, energy_type, kwh, address, meter_no, meter_type, unit_upi, nama_unit_upi, unit_ap, nama_unit_ap, unit_up, nama_unit_up, created_at")
+		return query.Count(&result).Error
+	})
 
-	if len(req.Induk) > 0 {
-		query.Where("unit_upi = ?", req.Induk)
-	} else if len(req.Area) > 0 {
-		query.Where("unit_ap = ?", req.Area)
-	} else if len(req.UnitCode) > 0 {
-		query.Where("unit_up = ?", req.UnitCode)
-	}
+	return result, err
+}
 
-	if len(req.DateStart) > 0 && len(req.DateEnd) > 0 {
-		startDate, err := helper.StartDateParser(req.DateStart)
+// SummarizeTransaksi aggregates count and amount per unit, payment gateway,
+// type and status code in a single pass using grouping sets.
+func (r *ExporterRepo) SummarizeTransaksi(ctx context.Context, req *request.RekapRequest) (result []*domain.TransaksiSummary, err error) {
+	err = r.exportQuery(ctx, r.transaksiDB(req), func(tx *gorm.DB) error {
+		query, err := transaksiQuery(tx, req)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		endDate, err := helper.EndDateParser(req.DateEnd)
+		table := transaksiTable(req)
+
+		return query.
+			Select(`CASE
+					WHEN GROUPING(up.id_unit_up) = 0 THEN 'unit'
+					WHEN GROUPING(` + table + `.payment_gateway) = 0 THEN 'payment_gateway'
+					WHEN GROUPING(` + table + `.type) = 0 THEN 'type'
+					WHEN GROUPING(` + table + `.status_code) = 0 THEN 'status_code'
+					ELSE 'total'
+				END AS dimension,
+				upi.id_unit_upi AS unit_upi, upi.nama_unit_upi,
+				ap.id_unit_ap AS unit_ap, ap.nama_unit_ap,
+				up.id_unit_up AS unit_up, up.nama_unit_up,
+				` + table + `.payment_gateway, ` + table + `.type, ` + table + `.status_code,
+				COUNT(*) AS count,
+				COALESCE(SUM(CAST(NULLIF(` + table + `.amount, '') AS numeric)), 0) AS total_amount`).
+			Group(`GROUPING SETS (
+				(upi.id_unit_upi, upi.nama_unit_upi, ap.id_unit_ap, ap.nama_unit_ap, up.id_unit_up, up.nama_unit_up),
+				(` + table + `.payment_gateway),
+				(` + table + `.type),
+				(` + table + `.status_code),
+				()
+			)`).
+			Order("dimension, count DESC").
+			Scan(&result).Error
+	})
+
+	return result, err
+}
+
+// DailyTotalsTransaksi counts and sums the transactions per unit and per
+// day, the two sources are compared on these totals.
+func (r *ExporterRepo) DailyTotalsTransaksi(ctx context.Context, req *request.RekapRequest) (result []*domain.TransaksiDailyTotal, err error) {
+	err = r.exportQuery(ctx, r.transaksiDB(req), func(tx *gorm.DB) error {
+		query, err := transaksiQuery(tx, req)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		query.Where("created_at BETWEEN ? AND ?", startDate, endDate)
-	}
+		table := transaksiTable(req)
 
-	if req.Limit > 0 {
-		query = query.Limit(req.Limit)
-	}
+		return query.
+			Select(`up.id_unit_up AS unit_up, up.nama_unit_up,
+				to_char(` + table + `.created_at, 'YYYY-MM-DD') AS day,
+				COUNT(*) AS count,
+				COALESCE(SUM(CAST(NULLIF(` + table + `.amount, '') AS numeric)), 0) AS total_amount`).
+			Group("up.id_unit_up, up.nama_unit_up, day").
+			// byte order, the same in both databases
+			Order(`unit_up COLLATE "C", day`).
+			Scan(&result).Error
+	})
 
-	if req.Offset > 0 {
-		query = query.Offset(req.Offset)
-	}
+	return result, err
+}
 
-	err = query.Find(&result).Error
+func (r *ExporterRepo) TransaksiIDs(ctx context.Context, req *request.RekapRequest) (result []string, err error) {
+	err = r.exportQuery(ctx, r.transaksiDB(req), func(tx *gorm.DB) error {
+		query, err := transaksiQuery(tx, req)
+		if err != nil {
+			return err
+		}
+
+		table := transaksiTable(req)
+
+		return query.
+			Order(table+".id").
+			Pluck(table+".id", &result).Error
+	})
 
 	return result, err
 }
 
-func (r *ExporterRepo) CountPelanggan(req *request.RekapRequest) (result int64, err error) {
-	query := r.dbPlnMobile.Model(&domain.Pelanggan{})
+// pelangganQuery builds the filtered pelanggan query on tx, pelanggan are
+// only read from PLN Mobile.
+func pelangganQuery(tx *gorm.DB, req *request.RekapRequest) (*gorm.DB, error) {
+	query, err := applyUnitAndDateFilter(tx.Model(&domain.Pelanggan{}), req)
+	if err != nil {
+		return nil, err
+	}
 
-	if len(req.Induk) > 0 {
-		query.Where("unit_upi = ?", req.Induk)
-	} else if len(req.Area) > 0 {
-		query.Where("unit_ap = ?", req.Area)
-	} else if len(req.UnitCode) > 0 {
-		query.Where("unit_up = ?", req.UnitCode)
+	if len(req.EnergyType) > 0 {
+		query = query.Where("energy_type = ?", req.EnergyType)
 	}
 
-	if len(req.DateStart) > 0 && len(req.DateEnd) > 0 {
-		startDate, err := helper.StartDateParser(req.DateStart)
+	if len(req.MeterType) > 0 {
+		query = query.Where("meter_type = ?", req.MeterType)
+	}
+
+	return query, nil
+}
+
+const pelangganSelect = "id, idpel, name, consumer_name, energy_type, kwh, address, meter_no, meter_type, unit_upi, nama_unit_upi, unit_ap, nama_unit_ap, unit_up, nama_unit_up, created_at, last_update"
+
+// StreamPelanggan is the pelanggan counterpart of StreamTransaksi.
+func (r *ExporterRepo) StreamPelanggan(ctx context.Context, req *request.RekapRequest, after *domain.ExportCursor, limit int, fn func(*domain.Pelanggan) error) error {
+	return r.exportQuery(ctx, r.dbPlnMobile, func(tx *gorm.DB) error {
+		query, err := pelangganQuery(tx, req)
 		if err != nil {
-			return result, err
+			return err
 		}
 
-		endDate, err := helper.EndDateParser(req.DateEnd)
+		query = query.Select(pelangganSelect)
+
+		if after != nil {
+			query = query.Where("(created_at, id) > (?, ?)", after.CreatedAt, after.ID)
+		}
+
+		rows, err := query.
+			Order("created_at ASC").
+			Order("id ASC").
+			Limit(limit).
+			Rows()
 		if err != nil {
-			return result, err
+			return err
 		}
+		defer rows.Close()
 
-		query.Where("created_at BETWEEN ? AND ?", startDate, endDate)
-	}
+		for rows.Next() {
+			var row domain.Pelanggan
+			if err := query.ScanRows(rows, &row); err != nil {
+				return err
+			}
 
-	err = query.Count(&result).Error
-	if err != nil {
-		return result, err
-	}
+			if err := fn(&row); err != nil {
+				return err
+			}
+		}
 
-	return result, err
+		return rows.Err()
+	})
+}
 
+func (r *ExporterRepo) CountPelanggan(ctx context.Context, req *request.RekapRequest) (result int64, err error) {
+	err = r.exportQuery(ctx, r.dbPlnMobile, func(tx *gorm.DB) error {
+		query, err := pelangganQuery(tx, req)
+		if err != nil {
+			return err
+		}
+
+		return query.Count(&result).Error
+	})
+
+	return result, err
 }
@@ -0,0 +1,143 @@
+package gorm_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"event-registration/internal/common"
+	"event-registration/internal/common/request"
+
+	repo "event-registration/internal/repository/gorm"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountTransaksiFilters(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	r := repo.NewExporterRepo(db, db, &common.Config{})
+
+	req := &request.RekapRequest{
+		UnitCode:       "54110",
+		StatusCode:     []string{"05", "99"},
+		PaymentGateway: "BRI",
+		Type:           "prepaid",
+		AmountMin:      20000,
+		AmountMax:      500000,
+		MeterNumber:    "532100112233",
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`unit_up = $1 `+
+		`AND plnmobile.vw_transaksi.status_code IN ($2,$3) `+
+		`AND plnmobile.vw_transaksi.payment_gateway = $4 `+
+		`AND plnmobile.vw_transaksi.type = $5 `+
+		`AND CAST(NULLIF(plnmobile.vw_transaksi.amount, '') AS numeric) >= $6 `+
+		`AND CAST(NULLIF(plnmobile.vw_transaksi.amount, '') AS numeric) <= $7 `+
+		`AND plnmobile.vw_transaksi.meter_number = $8`)).
+		WithArgs("54110", "05", "99", "BRI", "prepaid", float64(20000), float64(500000), "532100112233").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	count, err := r.CountTransaksi(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), count)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCountPelangganFilters(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	r := repo.NewExporterRepo(db, db, &common.Config{})
+
+	req := &request.RekapRequest{Area: "54100", EnergyType: "prabayar", MeterType: "1 PHASE"}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`unit_ap = $1 AND energy_type = $2 AND meter_type = $3`)).
+		WithArgs("54100", "prabayar", "1 PHASE").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+
+	count, err := r.CountPelanggan(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), count)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCountTransaksiPusat(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	r := repo.NewExporterRepo(db, db, &common.Config{})
+
+	mock.ExpectQuery(regexp.QuoteMeta(`unit_upi IN (SELECT id_unit_upi :: text FROM public.pln_unit_upi WHERE id_reg_apkt = $1)`)).
+		WithArgs("REG2").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
+
+	count, err := r.CountTransaksi(context.Background(), &request.RekapRequest{Pusat: "REG2"})
+	require.NoError(t, err)
+	require.Equal(t, int64(12), count)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUnitsVersion(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	r := repo.NewExporterRepo(db, db, &common.Config{})
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT md5(string_agg(row, '|' ORDER BY row)) FROM (` +
+		`SELECT 'regional' || t :: text AS row FROM public.pln_unit_regional t ` +
+		`UNION ALL SELECT 'upi' || t :: text FROM public.pln_unit_upi t ` +
+		`UNION ALL SELECT 'ap' || t :: text FROM public.pln_unit_ap t ` +
+		`UNION ALL SELECT 'up' || t :: text FROM public.pln_unit_up t) units`)).
+		WillReturnRows(sqlmock.NewRows([]string{"md5"}).AddRow("5d41402abc4b2a76b9719d911017c592"))
+
+	version, err := r.UnitsVersion(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "5d41402abc4b2a76b9719d911017c592", version)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDailyTotalsTransaksi(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	r := repo.NewExporterRepo(db, db, &common.Config{})
+
+	mock.ExpectQuery(regexp.QuoteMeta(`to_char(public.transaksi.created_at, 'YYYY-MM-DD') AS day`) + `.*` +
+		regexp.QuoteMeta(`WHERE unit_up = $1 GROUP BY up.id_unit_up, up.nama_unit_up, day ORDER BY unit_up COLLATE "C", day`)).
+		WithArgs("54110").
+		WillReturnRows(sqlmock.NewRows([]string{"unit_up", "nama_unit_up", "day", "count", "total_amount"}).
+			AddRow("54110", "UP Menteng", "2026-02-01", 4, 120000).
+			AddRow("54110", "UP Menteng", "2026-02-02", 1, 20000))
+
+	totals, err := r.DailyTotalsTransaksi(context.Background(), &request.RekapRequest{UnitCode: "54110", IsDBPlnMobile: true})
+	require.NoError(t, err)
+	require.Len(t, totals, 2)
+	require.Equal(t, "2026-02-01", totals[0].Day)
+	require.Equal(t, int64(4), totals[0].Count)
+	require.Equal(t, float64(120000), totals[0].TotalAmount)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportStatementTimeout(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	r := repo.NewExporterRepo(db, db, &common.Config{ExportStatementTimeout: time.Second})
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`SET LOCAL statement_timeout = 1000`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`unit_up = $1`)).
+		WithArgs("54110").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectCommit()
+
+	count, err := r.CountTransaksi(context.Background(), &request.RekapRequest{UnitCode: "54110"})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), count)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
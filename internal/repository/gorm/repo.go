@@ -1,7 +1,9 @@
 package gorm
 
 import (
+	"context"
 	"errors"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -15,8 +17,26 @@ func handleGormError(err error) error {
 		return gorm.ErrRecordNotFound
 	}
 
+	// callers tell a timeout or a cancelled request from a failing query
+	if errors.Is(err, context.DeadlineExceeded) {
+		return context.DeadlineExceeded
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return context.Canceled
+	}
+
 	// Laporkan error ke Sentry
 	// sentry.CaptureException(err)
 
 	return errors.New("sql_error")
 }
+
+// withTimeout bounds ctx by timeout, a timeout of 0 only bounds it by ctx.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
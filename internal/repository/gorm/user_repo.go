@@ -1,31 +1,44 @@
 package gorm
 
 import (
+	"context"
 	"errors"
+	"event-registration/internal/common"
 	"event-registration/internal/common/constant"
 	"event-registration/internal/core/domain"
+	"time"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
 type UserRepo struct {
-	db     *gorm.DB
-	logger *zap.Logger
+	db           *gorm.DB
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	logger       *zap.Logger
 }
 
 func NewUserRepo(
 	db *gorm.DB, // `name:"VCCDB"`
 	logger *zap.Logger,
+	cfg *common.Config,
 ) domain.UserRepository {
-	return &UserRepo{db: db, logger: logger}
+	return &UserRepo{
+		db:           db,
+		readTimeout:  cfg.DBReadTimeout,
+		writeTimeout: cfg.DBWriteTimeout,
+		logger:       logger,
+	}
 }
 
-func (r *UserRepo) Search(key string) (user []*domain.UserVCC, err error) {
+func (r *UserRepo) Search(ctx context.Context, key string) (user []*domain.UserVCC, err error) {
+	ctx, cancel := withTimeout(ctx, r.readTimeout)
+	defer cancel()
 
 	key = "%" + key + "%"
 
-	err = r.db.Model(&domain.UserVCC{}).
+	err = r.db.WithContext(ctx).Model(&domain.UserVCC{}).
 		Where("email ILIKE ?", key).
 		Or("username ILIKE ?", key).
 		Or("email ILIKE ?", key).
@@ -44,9 +57,11 @@ func (r *UserRepo) Search(key string) (user []*domain.UserVCC, err error) {
 	return user, nil
 }
 
-func (r *UserRepo) Roles() (user []*domain.Role, err error) {
+func (r *UserRepo) Roles(ctx context.Context) (user []*domain.Role, err error) {
+	ctx, cancel := withTimeout(ctx, r.readTimeout)
+	defer cancel()
 
-	err = r.db.
+	err = r.db.WithContext(ctx).
 		Model(&domain.Role{}).
 		Scan(&user).Error
 
@@ -58,19 +73,22 @@ func (r *UserRepo) Roles() (user []*domain.Role, err error) {
 	return user, nil
 }
 
-func (r *UserRepo) Unit(level string) (units []*domain.UnitName, err error) {
+func (r *UserRepo) Unit(ctx context.Context, level string) (units []*domain.UnitName, err error) {
+	ctx, cancel := withTimeout(ctx, r.readTimeout)
+	defer cancel()
 
 	var query *gorm.DB
+	db := r.db.WithContext(ctx)
 
 	switch level {
 	case "1":
-		query = r.db.Table("public.pln_unit_upi").
+		query = db.Table("public.pln_unit_upi").
 			Select("id_unit_upi as code, nama_unit_upi || ' - ' || id_unit_upi as label")
 	case "2":
-		query = r.db.Table("public.pln_unit_ap").
+		query = db.Table("public.pln_unit_ap").
 			Select("id_unit_ap as code, nama_unit_ap || ' - ' || id_unit_ap as label")
 	case "3":
-		query = r.db.Table("public.pln_unit_up").
+		query = db.Table("public.pln_unit_up").
 			Select("id_unit_up as code, nama_unit_up || ' - ' || id_unit_up as label")
 	default:
 		return nil, errors.New("invalid level")
@@ -87,7 +105,10 @@ func (r *UserRepo) Unit(level string) (units []*domain.UnitName, err error) {
 	return units, nil
 }
 
-func (r *UserRepo) Update(user *domain.UserVCC) (err error) {
+func (r *UserRepo) Update(ctx context.Context, user *domain.UserVCC) (err error) {
+	ctx, cancel := withTimeout(ctx, r.writeTimeout)
+	defer cancel()
+
 	var updatableColumn []string = []string{
 		"Email",
 		"Username",
@@ -102,7 +123,7 @@ func (r *UserRepo) Update(user *domain.UserVCC) (err error) {
 
 	roles := []map[string]interface{}{}
 
-	tx := r.db.Begin()
+	tx := r.db.WithContext(ctx).Begin()
 
 	qry := tx.Select(updatableColumn).Updates(&user)
 
@@ -148,7 +169,7 @@ func (r *UserRepo) Update(user *domain.UserVCC) (err error) {
 		return handleGormError(err)
 	}
 
-	err = r.db.Model(&domain.UserVCC{}).
+	err = r.db.WithContext(ctx).Model(&domain.UserVCC{}).
 		Where("id = ?", user.ID).
 		Preload("Roles").
 		First(&user).Error
@@ -161,9 +182,10 @@ func (r *UserRepo) Update(user *domain.UserVCC) (err error) {
 	return nil
 }
 
-func (r *UserRepo) FindAll() (user []*domain.UserVCC, err error) {
-
-	err = r.db.Model(&domain.UserVCC{}).
+// FindAll loads every user to seed the search index, it is only bounded by
+// ctx.
+func (r *UserRepo) FindAll(ctx context.Context) (user []*domain.UserVCC, err error) {
+	err = r.db.WithContext(ctx).Model(&domain.UserVCC{}).
 		Preload("Roles").
 		Find(&user).Error
 
@@ -174,3 +196,24 @@ func (r *UserRepo) FindAll() (user []*domain.UserVCC, err error) {
 
 	return user, nil
 }
+
+// RoleNames returns the names of the roles assigned to the user with email.
+func (r *UserRepo) RoleNames(ctx context.Context, email string) (roles []string, err error) {
+	ctx, cancel := withTimeout(ctx, r.readTimeout)
+	defer cancel()
+
+	err = r.db.WithContext(ctx).
+		Table("dashboard.roles").
+		Joins("JOIN dashboard.role_users ON role_users.role_id = roles.id").
+		Joins("JOIN dashboard.users ON users.id = role_users.user_id").
+		Where("users.email = ?", email).
+		Order("roles.name ASC").
+		Pluck("roles.name", &roles).Error
+
+	if err != nil {
+		r.logger.Error(constant.SQL_ERROR, zap.Error(err))
+		return roles, handleGormError(err)
+	}
+
+	return roles, nil
+}
@@ -109,7 +109,7 @@ func (r *UserMeilisearchRepo) SetupIndex() (err error) {
 func (r *UserMeilisearchRepo) SeedIndex() error {
 	index := r.meilisearch.Index("users")
 
-	users, err := r.repo.FindAll()
+	users, err := r.repo.FindAll(context.Background())
 	if err != nil {
 		r.logger.Error(
 			"failed_to_connect_to_meilisearch",
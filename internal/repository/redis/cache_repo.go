@@ -34,8 +34,8 @@ func CloseRedisConnection(client *redis.Client) (err error) {
 	return err
 }
 
-func (r *CacheRepo) Get(key string) (*domain.Event, error) {
-	data, err := r.client.Get(context.Background(), key).Bytes()
+func (r *CacheRepo) Get(ctx context.Context, key string) (*domain.Event, error) {
+	data, err := r.client.Get(ctx, key).Bytes()
 	if err != nil {
 		return nil, err
 	}
@@ -45,10 +45,10 @@ func (r *CacheRepo) Get(key string) (*domain.Event, error) {
 	return &event, err
 }
 
-func (r *CacheRepo) Set(key string, event *domain.Event, expiration time.Duration) error {
+func (r *CacheRepo) Set(ctx context.Context, key string, event *domain.Event, expiration time.Duration) error {
 	data, err := json.Marshal(event)
 	if err != nil {
 		return err
 	}
-	return r.client.Set(context.Background(), key, data, expiration).Err()
+	return r.client.Set(ctx, key, data, expiration).Err()
 }
@@ -0,0 +1,101 @@
+package redis
+
+import (
+	"context"
+	"event-registration/internal/core/domain"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// exportSlotsKey is a sorted set of slot holders scored by lease expiry.
+	exportSlotsKey = "export_running_slots"
+	// exportSlotWaitersKey is a sorted set of the holders in line for a slot
+	// scored by priority, then queue time.
+	exportSlotWaitersKey = "export_slot_waiters"
+	// exportSlotWaitersExpiryKey scores the same holders by lease expiry.
+	exportSlotWaitersExpiryKey = "export_slot_waiters_expiry"
+
+	// exportSlotPriorityScale keeps the priority ahead of the queue time in
+	// milliseconds in a waiter's score.
+	exportSlotPriorityScale = 1e13
+)
+
+type ExportAdmissionRepo struct {
+	client *redis.Client
+}
+
+func NewExportAdmissionRepo(client *redis.Client) domain.ExportAdmissionRepository {
+	return &ExportAdmissionRepo{client: client}
+}
+
+func exportLockKey(key string) string {
+	return fmt.Sprintf("export_lock:%s", key)
+}
+
+// acquireSlot drops expired holders and waiters, then gives the holder a
+// slot if it already has one or is among the first waiters in line for the
+// free slots. Otherwise it keeps its place in line.
+var acquireSlot = redis.NewScript(`
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", ARGV[2])
+for _, waiter in ipairs(redis.call("ZRANGEBYSCORE", KEYS[3], "-inf", ARGV[2])) do
+	redis.call("ZREM", KEYS[2], waiter)
+end
+redis.call("ZREMRANGEBYSCORE", KEYS[3], "-inf", ARGV[2])
+
+if redis.call("ZSCORE", KEYS[1], ARGV[1]) then
+	redis.call("ZADD", KEYS[1], ARGV[4], ARGV[1])
+	return 1
+end
+
+redis.call("ZADD", KEYS[2], ARGV[5], ARGV[1])
+redis.call("ZADD", KEYS[3], ARGV[4], ARGV[1])
+
+local free = tonumber(ARGV[3]) - redis.call("ZCARD", KEYS[1])
+if free > 0 and redis.call("ZRANK", KEYS[2], ARGV[1]) < free then
+	redis.call("ZREM", KEYS[2], ARGV[1])
+	redis.call("ZREM", KEYS[3], ARGV[1])
+	redis.call("ZADD", KEYS[1], ARGV[4], ARGV[1])
+	return 1
+end
+return 0`)
+
+func (r *ExportAdmissionRepo) Lock(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, exportLockKey(key), owner, ttl).Result()
+}
+
+func (r *ExportAdmissionRepo) Unlock(ctx context.Context, key, owner string) error {
+	return releaseLease.Run(ctx, r.client, []string{exportLockKey(key)}, owner).Err()
+}
+
+func (r *ExportAdmissionRepo) AcquireSlot(ctx context.Context, holder string, priority int, queuedAt time.Time, limit int, lease time.Duration) (bool, error) {
+	now := time.Now()
+	rank := float64(priority)*exportSlotPriorityScale + float64(queuedAt.UnixMilli())
+
+	acquired, err := acquireSlot.Run(ctx, r.client, []string{exportSlotsKey, exportSlotWaitersKey, exportSlotWaitersExpiryKey},
+		holder, now.UnixMilli(), limit, now.Add(lease).UnixMilli(), rank).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return acquired == 1, nil
+}
+
+func (r *ExportAdmissionRepo) RenewSlot(ctx context.Context, holder string, lease time.Duration) error {
+	return r.client.ZAddXX(ctx, exportSlotsKey, redis.Z{
+		Score:  float64(time.Now().Add(lease).UnixMilli()),
+		Member: holder,
+	}).Err()
+}
+
+func (r *ExportAdmissionRepo) ReleaseSlot(ctx context.Context, holder string) error {
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, exportSlotsKey, holder)
+		pipe.ZRem(ctx, exportSlotWaitersKey, holder)
+		pipe.ZRem(ctx, exportSlotWaitersExpiryKey, holder)
+		return nil
+	})
+	return err
+}
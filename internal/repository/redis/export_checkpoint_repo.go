@@ -0,0 +1,56 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"event-registration/internal/common"
+	"event-registration/internal/core/domain"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type ExportCheckpointRepo struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewExportCheckpointRepo(client *redis.Client, cfg *common.Config) domain.ExportCheckpointRepository {
+	return &ExportCheckpointRepo{
+		client: client,
+		ttl:    cfg.ExportJobTTL,
+	}
+}
+
+func exportCheckpointKey(hash string) string {
+	return fmt.Sprintf("export_checkpoint:%s", hash)
+}
+
+func (r *ExportCheckpointRepo) Find(ctx context.Context, hash string) (*domain.ExportCheckpoint, error) {
+	data, err := r.client.Get(ctx, exportCheckpointKey(hash)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, domain.ErrExportCheckpointNotFound
+		}
+		return nil, err
+	}
+
+	var checkpoint domain.ExportCheckpoint
+	err = json.Unmarshal(data, &checkpoint)
+	return &checkpoint, err
+}
+
+func (r *ExportCheckpointRepo) Save(ctx context.Context, checkpoint *domain.ExportCheckpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	return r.client.Set(ctx, exportCheckpointKey(checkpoint.Hash), data, r.ttl).Err()
+}
+
+func (r *ExportCheckpointRepo) Delete(ctx context.Context, hash string) error {
+	return r.client.Del(ctx, exportCheckpointKey(hash)).Err()
+}
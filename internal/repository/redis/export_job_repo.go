@@ -0,0 +1,206 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"event-registration/internal/common"
+	"event-registration/internal/core/domain"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type ExportJobRepo struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewExportJobRepo(client *redis.Client, cfg *common.Config) domain.ExportJobRepository {
+	return &ExportJobRepo{
+		client: client,
+		ttl:    cfg.ExportJobTTL,
+	}
+}
+
+func exportJobKey(id string) string {
+	return fmt.Sprintf("export_job:%s", id)
+}
+
+func exportJobLeaseKey(id string) string {
+	return fmt.Sprintf("export_job_lease:%s", id)
+}
+
+func userExportJobsKey(userID string) string {
+	return fmt.Sprintf("user_export_jobs:%s", userID)
+}
+
+const (
+	// activeExportJobsKey indexes the jobs that are queued or running.
+	activeExportJobsKey = "active_export_jobs"
+	pinnedExportJobsKey = "pinned_export_jobs"
+)
+
+func (r *ExportJobRepo) Save(ctx context.Context, job *domain.ExportJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	// pinned jobs never expire
+	ttl := r.ttl
+	if job.Pinned {
+		ttl = 0
+	}
+
+	if err := r.client.Set(ctx, exportJobKey(job.ID), data, ttl).Err(); err != nil {
+		return err
+	}
+
+	if job.Pinned {
+		err = r.client.SAdd(ctx, pinnedExportJobsKey, job.ID).Err()
+	} else {
+		err = r.client.SRem(ctx, pinnedExportJobsKey, job.ID).Err()
+	}
+	if err != nil {
+		return err
+	}
+
+	if job.Status == domain.EXPORT_JOB_QUEUED || job.Status == domain.EXPORT_JOB_RUNNING {
+		err = r.client.SAdd(ctx, activeExportJobsKey, job.ID).Err()
+	} else {
+		err = r.client.SRem(ctx, activeExportJobsKey, job.ID).Err()
+	}
+	if err != nil {
+		return err
+	}
+
+	if job.RequestedBy == "" {
+		return nil
+	}
+
+	userKey := userExportJobsKey(job.RequestedBy)
+	if err := r.client.SAdd(ctx, userKey, job.ID).Err(); err != nil {
+		return err
+	}
+
+	return r.client.Expire(ctx, userKey, r.ttl).Err()
+}
+
+func (r *ExportJobRepo) FindByID(ctx context.Context, id string) (*domain.ExportJob, error) {
+	data, err := r.client.Get(ctx, exportJobKey(id)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, domain.ErrExportJobNotFound
+		}
+		return nil, err
+	}
+
+	var job domain.ExportJob
+	err = json.Unmarshal(data, &job)
+	return &job, err
+}
+
+func (r *ExportJobRepo) FindByUser(ctx context.Context, userID string) ([]*domain.ExportJob, error) {
+	jobs, err := r.findIndexed(ctx, userExportJobsKey(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	// pinned jobs outlive the user index
+	pinned, err := r.FindPinned(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for _, job := range jobs {
+		seen[job.ID] = true
+	}
+
+	for _, job := range pinned {
+		if job.RequestedBy == userID && !seen[job.ID] {
+			jobs = append(jobs, job)
+		}
+	}
+
+	return jobs, nil
+}
+
+func (r *ExportJobRepo) FindActive(ctx context.Context) ([]*domain.ExportJob, error) {
+	return r.findIndexed(ctx, activeExportJobsKey)
+}
+
+func (r *ExportJobRepo) FindPinned(ctx context.Context) ([]*domain.ExportJob, error) {
+	return r.findIndexed(ctx, pinnedExportJobsKey)
+}
+
+// renewLease and releaseLease only touch a lease still held by the owner.
+var (
+	renewLease = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+	releaseLease = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+func (r *ExportJobRepo) Claim(ctx context.Context, id, owner string, lease time.Duration) error {
+	ok, err := r.client.SetNX(ctx, exportJobLeaseKey(id), owner, lease).Result()
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return domain.ErrExportJobClaimed
+	}
+
+	return nil
+}
+
+func (r *ExportJobRepo) Renew(ctx context.Context, id, owner string, lease time.Duration) error {
+	renewed, err := renewLease.Run(ctx, r.client, []string{exportJobLeaseKey(id)}, owner, lease.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+
+	if renewed == 0 {
+		return domain.ErrExportJobClaimed
+	}
+
+	return nil
+}
+
+func (r *ExportJobRepo) Release(ctx context.Context, id, owner string) error {
+	return releaseLease.Run(ctx, r.client, []string{exportJobLeaseKey(id)}, owner).Err()
+}
+
+// findIndexed loads every job in the set at key.
+func (r *ExportJobRepo) findIndexed(ctx context.Context, key string) ([]*domain.ExportJob, error) {
+	ids, err := r.client.SMembers(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*domain.ExportJob, 0, len(ids))
+	for _, id := range ids {
+		job, err := r.FindByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, domain.ErrExportJobNotFound) {
+				// job expired, drop it from the index
+				r.client.SRem(ctx, key, id)
+				continue
+			}
+			return nil, err
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
@@ -0,0 +1,44 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"event-registration/internal/core/domain"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const unitTreeKey = "unit_tree"
+
+type UnitTreeRepo struct {
+	client *redis.Client
+}
+
+func NewUnitTreeRepo(client *redis.Client) domain.UnitTreeCache {
+	return &UnitTreeRepo{client: client}
+}
+
+func (r *UnitTreeRepo) Get(ctx context.Context) (*domain.UnitTree, error) {
+	data, err := r.client.Get(ctx, unitTreeKey).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, domain.ErrUnitTreeNotCached
+		}
+		return nil, err
+	}
+
+	var tree domain.UnitTree
+	err = json.Unmarshal(data, &tree)
+	return &tree, err
+}
+
+func (r *UnitTreeRepo) Set(ctx context.Context, tree *domain.UnitTree, expiration time.Duration) error {
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+
+	return r.client.Set(ctx, unitTreeKey, data, expiration).Err()
+}